@@ -50,7 +50,7 @@ app.get('/internal/v1/watchers/routes', async (request, reply) => {
 
   const rows = await query(
     `
-    select transfer_id as "transferId", token, deposit_address as "depositAddress"
+    select transfer_id as "transferId", token, deposit_address as "depositAddress", created_at as "createdAt"
     from deposit_routes
     where chain = $1
       and status = 'active'
@@ -199,6 +199,37 @@ app.post('/internal/v1/watchers/dedupe/mark/:watcherName', async (request, reply
   return reply.status(204).send();
 });
 
+app.post('/internal/v1/watchers/dedupe/clear/:watcherName', async (request, reply) => {
+  try {
+    const claims = toAuthClaims(request);
+    assertScope(claims, 'watchers:internal');
+  } catch (error) {
+    return deny({
+      request,
+      reply,
+      code: 'FORBIDDEN',
+      message: (error as Error).message,
+      status: 403
+    });
+  }
+
+  const parsed = watcherDedupeSchema.safeParse(request.body);
+  if (!parsed.success) {
+    return deny({
+      request,
+      reply,
+      code: 'INVALID_PAYLOAD',
+      message: parsed.error.issues[0]?.message ?? 'Invalid payload.',
+      status: 400,
+      details: parsed.error.issues
+    });
+  }
+
+  await query('delete from watcher_event_dedupe where event_key = $1', [parsed.data.eventKey]);
+
+  return reply.status(204).send();
+});
+
 app.post('/internal/v1/watchers/resolve-route', async (request, reply) => {
   try {
     const claims = toAuthClaims(request);
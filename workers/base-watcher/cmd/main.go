@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"log/slog"
 	"net/http"
@@ -23,6 +24,21 @@ func envOrDefault(name string, fallback string) string {
 	return value
 }
 
+// defaultEvmMethodCosts are compute units per call on Alchemy-style plans;
+// BASE_RPC_METHOD_COSTS overrides individual methods.
+var defaultEvmMethodCosts = map[string]float64{
+	"eth_blockNumber":           10,
+	"eth_getBlockByNumber":      16,
+	"eth_getBlockByHash":        16,
+	"eth_getLogs":               75,
+	"eth_getBlockReceipts":      500,
+	"eth_getTransactionByHash":  17,
+	"eth_getTransactionReceipt": 15,
+	"eth_call":                  26,
+	"trace_block":               24,
+	"debug_traceBlockByNumber":  309,
+}
+
 func envIntOrDefault(name string, fallback int) int {
 	value := os.Getenv(name)
 	if value == "" {
@@ -51,6 +67,75 @@ func envBoolOrDefault(name string, fallback bool) bool {
 	}
 }
 
+func envFloatOrDefault(name string, fallback float64) float64 {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return fallback
+	}
+	return parsed
+}
+
+func envListOrDefault(name string, fallback []string) []string {
+	value := strings.TrimSpace(os.Getenv(name))
+	if value == "" {
+		return fallback
+	}
+	return envListValue(value)
+}
+
+func envListValue(value string) []string {
+	items := make([]string, 0)
+	for _, item := range strings.Split(value, ",") {
+		if trimmed := strings.TrimSpace(item); trimmed != "" {
+			items = append(items, trimmed)
+		}
+	}
+	return items
+}
+
+// parseTokenList reads extra tokens as comma-separated
+// SYMBOL:contract[:decimals[:display name]] entries. Decimals left empty are
+// read from the contract at startup.
+func parseTokenList(value string) ([]internal.TokenInfo, error) {
+	tokens := make([]internal.TokenInfo, 0)
+	for _, entry := range envListValue(value) {
+		parts := strings.SplitN(entry, ":", 4)
+		if len(parts) < 2 {
+			return nil, fmt.Errorf("invalid token entry %q (expected SYMBOL:contract[:decimals[:name]])", entry)
+		}
+		token := internal.TokenInfo{Symbol: parts[0], Contract: parts[1]}
+		if len(parts) > 2 && strings.TrimSpace(parts[2]) != "" {
+			decimals, err := strconv.Atoi(strings.TrimSpace(parts[2]))
+			if err != nil {
+				return nil, fmt.Errorf("invalid decimals in token entry %q", entry)
+			}
+			token.Decimals = decimals
+		}
+		if len(parts) > 3 {
+			token.Name = strings.TrimSpace(parts[3])
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, nil
+}
+
+// parseMinAmounts parses SYMBOL:amount entries, e.g. "USDC:0.5,ETH:0.0001".
+func parseMinAmounts(value string) (map[string]string, error) {
+	amounts := make(map[string]string)
+	for _, entry := range envListValue(value) {
+		symbol, amount, ok := strings.Cut(entry, ":")
+		if !ok || strings.TrimSpace(symbol) == "" || strings.TrimSpace(amount) == "" {
+			return nil, fmt.Errorf("invalid minimum amount entry %q (expected SYMBOL:amount)", entry)
+		}
+		amounts[strings.TrimSpace(symbol)] = strings.TrimSpace(amount)
+	}
+	return amounts, nil
+}
+
 func main() {
 	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo})))
 
@@ -58,9 +143,9 @@ func main() {
 	defer cancel()
 
 	coreAPIURL := envOrDefault("CORE_API_URL", "http://localhost:3001")
-	rpcURL := strings.TrimSpace(os.Getenv("BASE_RPC_URL"))
-	if rpcURL == "" {
-		log.Fatalf("missing required BASE_RPC_URL for base-watcher")
+	rpcURLs := envListOrDefault("BASE_RPC_URLS", envListOrDefault("BASE_RPC_URL", nil))
+	if len(rpcURLs) == 0 {
+		log.Fatalf("missing required BASE_RPC_URL (or BASE_RPC_URLS) for base-watcher")
 	}
 	usdcContract := strings.TrimSpace(os.Getenv("BASE_USDC_CONTRACT"))
 	if usdcContract == "" {
@@ -70,22 +155,97 @@ func main() {
 	if usdtContract == "" {
 		log.Fatalf("missing required BASE_USDT_CONTRACT for base-watcher")
 	}
+	extraTokens, err := parseTokenList(os.Getenv("BASE_EXTRA_TOKENS"))
+	if err != nil {
+		log.Fatalf("invalid BASE_EXTRA_TOKENS: %v", err)
+	}
+	tokens, err := internal.NewTokenRegistry(append([]internal.TokenInfo{
+		{Symbol: "USDC", Contract: usdcContract, Decimals: envIntOrDefault("BASE_USDC_DECIMALS", 6), Name: "USD Coin"},
+		{Symbol: "USDT", Contract: usdtContract, Decimals: envIntOrDefault("BASE_USDT_DECIMALS", 6), Name: "Tether USD"},
+	}, extraTokens...))
+	if err != nil {
+		log.Fatalf("invalid base token configuration: %v", err)
+	}
 	callbackURL := envOrDefault("CORE_API_FUNDING_CALLBACK_URL", "http://localhost:3001/internal/v1/funding-confirmed")
+	revertedCallbackURL := strings.TrimSpace(os.Getenv("CORE_API_FUNDING_REVERTED_CALLBACK_URL"))
 	callbackSecret := envOrDefault("WATCHER_CALLBACK_SECRET", "dev-callback-secret-change-me")
 	minConfirmations := envIntOrDefault("BASE_MIN_CONFIRMATIONS", 3)
+	finalityMode := internal.FinalityMode(strings.ToLower(envOrDefault("BASE_FINALITY_MODE", string(internal.FinalityConfirmations))))
+	switch finalityMode {
+	case internal.FinalityConfirmations, internal.FinalitySafe, internal.FinalityFinalized:
+	default:
+		log.Fatalf("invalid BASE_FINALITY_MODE %q (expected confirmations, safe or finalized)", finalityMode)
+	}
+	scanMode := internal.ScanMode(strings.ToLower(envOrDefault("BASE_SCAN_MODE", string(internal.ScanLogs))))
+	switch scanMode {
+	case internal.ScanLogs, internal.ScanBlockReceipts:
+	default:
+		log.Fatalf("invalid BASE_SCAN_MODE %q (expected logs or block_receipts)", scanMode)
+	}
+	nativeScan := envBoolOrDefault("BASE_NATIVE_SCAN", false)
+	nativeTraceMethod := internal.TraceMethod(envOrDefault("BASE_NATIVE_TRACE_METHOD", string(internal.TraceNone)))
+	switch nativeTraceMethod {
+	case internal.TraceNone, internal.TraceParity, internal.TraceDebug:
+	default:
+		log.Fatalf("invalid BASE_NATIVE_TRACE_METHOD %q (expected none, trace_block or debug_traceBlockByNumber)", nativeTraceMethod)
+	}
+	var prices internal.PriceSource
+	if priceURL := os.Getenv("BASE_ETH_PRICE_URL"); priceURL != "" {
+		prices = internal.NewHTTPPriceSource(priceURL, envOrDefault("BASE_ETH_PRICE_FIELD", "price"))
+	} else if price := envFloatOrDefault("BASE_ETH_PRICE_USD", 0); price > 0 {
+		prices = internal.StaticPriceSource{"ETH": price}
+	}
+	if nativeScan && prices == nil {
+		log.Fatalf("BASE_NATIVE_SCAN requires BASE_ETH_PRICE_URL or BASE_ETH_PRICE_USD")
+	}
 	pollIntervalMs := envIntOrDefault("BASE_POLL_INTERVAL_MS", 5000)
 	maxBlockSpan := envIntOrDefault("BASE_LOG_QUERY_BLOCK_SPAN", 250)
+	maxTopicAddresses := envIntOrDefault("BASE_LOG_QUERY_ADDRESS_CHUNK", 100)
+	rpcBatchSize := envIntOrDefault("BASE_RPC_BATCH_SIZE", 50)
+	receiptsBatchSize := envIntOrDefault("BASE_RECEIPTS_BATCH_SIZE", 10)
 	factoryLevelScan := envBoolOrDefault("BASE_FACTORY_LEVEL_SCAN", false)
+	reorgWindow := envIntOrDefault("BASE_REORG_WINDOW_BLOCKS", 64)
+	var create2 *internal.Create2Deriver
+	if factoryAddress := os.Getenv("BASE_DEPOSIT_FACTORY_ADDRESS"); factoryLevelScan && factoryAddress != "" {
+		sharedInitCodeHash := os.Getenv("BASE_DEPOSIT_PROXY_INIT_CODE_HASH")
+		deriver, err := internal.NewCreate2Deriver(
+			factoryAddress,
+			map[string]string{
+				"USDC": envOrDefault("BASE_USDC_PROXY_INIT_CODE_HASH", sharedInitCodeHash),
+				"USDT": envOrDefault("BASE_USDT_PROXY_INIT_CODE_HASH", sharedInitCodeHash),
+			},
+			internal.SaltScheme(envOrDefault("BASE_CREATE2_SALT_SCHEME", string(internal.SaltTransferID))),
+			int64(envIntOrDefault("BASE_CREATE2_SALT_INDEX_FROM", 0)),
+			int64(envIntOrDefault("BASE_CREATE2_SALT_INDEX_TO", 0)),
+		)
+		if err != nil {
+			log.Fatalf("invalid CREATE2 deposit config: %v", err)
+		}
+		create2 = deriver
+	}
+	catchUpThreshold := envIntOrDefault("BASE_CATCHUP_THRESHOLD_BLOCKS", 1000)
+	catchUpWorkers := envIntOrDefault("BASE_CATCHUP_WORKERS", 4)
 
 	slog.Info("base-watcher effective config",
 		"coreApiUrl", coreAPIURL,
-		"rpcUrl", rpcURL,
+		"rpcProviderCount", len(rpcURLs),
 		"usdcContract", usdcContract,
 		"usdtContract", usdtContract,
 		"minConfirmations", minConfirmations,
+		"finalityMode", finalityMode,
+		"scanMode", scanMode,
 		"pollIntervalMs", pollIntervalMs,
 		"maxBlockSpan", maxBlockSpan,
+		"maxTopicAddresses", maxTopicAddresses,
+		"rpcBatchSize", rpcBatchSize,
 		"factoryLevelScan", factoryLevelScan,
+		"create2Filter", create2 != nil,
+		"nativeScan", nativeScan,
+		"nativeTraceMethod", nativeTraceMethod,
+		"reorgWindow", reorgWindow,
+		"catchUpThreshold", catchUpThreshold,
+		"catchUpWorkers", catchUpWorkers,
+		"revertedCallbackConfigured", revertedCallbackURL != "",
 	)
 
 	client := internal.CoreAPIClient{
@@ -102,41 +262,153 @@ func main() {
 	checkpointStore := internal.CoreAPICheckpointStore{Client: &client, WatcherName: "base-watcher", Chain: "base"}
 	dedupeStore := internal.CoreAPIDedupeStore{Client: &client, WatcherName: "base-watcher"}
 
+	rpcPool := internal.NewRPCPool("base-rpc", rpcURLs, slog.Default())
+	rpcPool.Cooldown = time.Duration(envIntOrDefault("BASE_RPC_PROVIDER_COOLDOWN_MS", 15000)) * time.Millisecond
+
+	methodCosts, err := internal.ParseMethodCosts(os.Getenv("BASE_RPC_METHOD_COSTS"), defaultEvmMethodCosts)
+	if err != nil {
+		log.Fatalf("invalid BASE_RPC_METHOD_COSTS: %v", err)
+	}
+	rpcBudget := &internal.RPCBudget{
+		Name:           "base-rpc",
+		Logger:         slog.Default(),
+		UnitsPerSecond: envFloatOrDefault("BASE_RPC_UNITS_PER_SECOND", 0),
+		Burst:          envFloatOrDefault("BASE_RPC_BURST_UNITS", 0),
+		MethodCosts:    methodCosts,
+		PeriodLimit:    envFloatOrDefault("BASE_RPC_MONTHLY_UNITS", 0),
+		MaxSlowdown:    envFloatOrDefault("BASE_RPC_MAX_SLOWDOWN", 8),
+	}
+	go rpcBudget.LogReport(ctx, time.Duration(envIntOrDefault("BASE_RPC_USAGE_REPORT_INTERVAL_MS", 300000))*time.Millisecond)
+
 	source := internal.EvmRpcSource{
-		RPCURL:                 rpcURL,
+		RPCPool:                rpcPool,
+		Budget:                 rpcBudget,
 		HTTPClient:             &http.Client{Timeout: 30 * time.Second},
 		RouteStore:             routeStore,
 		Chain:                  "base",
 		FactoryLevelScan:       factoryLevelScan,
+		Create2:                create2,
+		FinalityMode:           finalityMode,
 		FinalizedConfirmations: minConfirmations,
 		MaxBlockSpan:           int64(maxBlockSpan),
-		TokenContracts: map[string]string{
-			"USDC": usdcContract,
-			"USDT": usdtContract,
-		},
+		MaxTopicAddresses:      maxTopicAddresses,
+		MaxBatchSize:           rpcBatchSize,
+		ScanMode:               scanMode,
+		ReceiptsBatchSize:      receiptsBatchSize,
+		ReorgWindow:            int64(reorgWindow),
+		Tokens:                 tokens,
+		NativeScan:             nativeScan,
+		NativeTraceMethod:      nativeTraceMethod,
+		Prices:                 prices,
+		RateLimitRetries:       envIntOrDefault("BASE_RPC_RATE_LIMIT_RETRIES", 4),
+		RateLimitBackoff:       time.Duration(envIntOrDefault("BASE_RPC_RATE_LIMIT_BACKOFF_MS", 500)) * time.Millisecond,
+	}
+	if err := source.VerifyTokenDecimals(ctx); err != nil {
+		log.Fatalf("verify base token decimals: %v", err)
+	}
+	if envBoolOrDefault("BASE_TRANSFER_FILTER", true) {
+		// Built after decimals are verified, since minimums are in whole units.
+		minAmounts, err := parseMinAmounts(os.Getenv("BASE_MIN_DEPOSIT_AMOUNTS"))
+		if err != nil {
+			log.Fatalf("invalid BASE_MIN_DEPOSIT_AMOUNTS: %v", err)
+		}
+		filter, err := internal.NewTransferFilter(minAmounts, tokens)
+		if err != nil {
+			log.Fatalf("invalid BASE_MIN_DEPOSIT_AMOUNTS: %v", err)
+		}
+		filter.LookalikeChars = envIntOrDefault("BASE_LOOKALIKE_SENDER_CHARS", 4)
+		source.Filter = filter
+	}
+
+	verifiers := internal.VerifierChain{}
+	if envBoolOrDefault("BASE_RECEIPT_VERIFICATION", true) {
+		verifiers = append(verifiers, internal.ReceiptVerifier{Source: source, Logger: slog.Default()})
+	}
+	if proofMinUSD := envFloatOrDefault("BASE_RECEIPT_PROOF_MIN_USD", 0); proofMinUSD > 0 {
+		verifiers = append(verifiers, internal.ReceiptProofVerifier{
+			Source:       source,
+			MinAmountUSD: proofMinUSD,
+			Logger:       slog.Default(),
+		})
+		slog.Info("base-watcher receipts root proofs enabled", "minAmountUSD", proofMinUSD)
+	}
+	if quorumURLs := envListOrDefault("BASE_QUORUM_RPC_URLS", nil); len(quorumURLs) > 0 {
+		verifiers = append(verifiers, internal.QuorumVerifier{
+			Endpoints:  quorumURLs,
+			Quorum:     envIntOrDefault("BASE_QUORUM_MIN_AGREEMENT", len(quorumURLs)),
+			Tokens:     source.Tokens,
+			HTTPClient: &http.Client{Timeout: 15 * time.Second},
+			Logger:     slog.Default(),
+		})
+		slog.Info("base-watcher quorum verification enabled",
+			"providerCount", len(quorumURLs),
+			"quorum", envIntOrDefault("BASE_QUORUM_MIN_AGREEMENT", len(quorumURLs)),
+		)
+	}
+
+	var verifier internal.CandidateVerifier
+	if len(verifiers) > 0 {
+		verifier = verifiers
+	}
+
+	// Transfers from our own wallets into deposit addresses are not funding.
+	internalAddresses := make(map[string]bool)
+	for _, address := range append(envListOrDefault("BASE_INTERNAL_ADDRESSES", nil), os.Getenv("BASE_TREASURY_ADDRESS")) {
+		if address = strings.ToLower(strings.TrimSpace(address)); address != "" {
+			internalAddresses[address] = true
+		}
 	}
 
 	watcher := internal.Watcher{
-		Chain:            "base",
-		MinConfirmations: minConfirmations,
-		Resolver:         routeResolver,
+		Chain:             "base",
+		MinConfirmations:  minConfirmations,
+		Resolver:          routeResolver,
+		Verifier:          verifier,
+		InternalAddresses: internalAddresses,
+		// core-api only holds deposit routes for these tokens.
+		RoutableTokens: map[string]bool{"USDC": true, "USDT": true},
 		Publisher: internal.CallbackPublisher{
-			Endpoint:  callbackURL,
-			Secret:    callbackSecret,
-			APIClient: &client,
-			Client:    &http.Client{Timeout: 15 * time.Second},
-			Now:       time.Now,
+			Endpoint:         callbackURL,
+			RevertedEndpoint: revertedCallbackURL,
+			Secret:           callbackSecret,
+			APIClient:        &client,
+			Client:           &http.Client{Timeout: 15 * time.Second},
+			Now:              time.Now,
 		},
 	}
 
+	var candidateSource internal.CandidateSource = source
+	if wsURL := os.Getenv("BASE_WS_URL"); wsURL != "" {
+		wsSource := internal.NewEvmWebsocketSource(wsURL, source, slog.Default())
+		wsSource.Start(ctx)
+		candidateSource = wsSource
+		slog.Info("base-watcher websocket push mode enabled")
+	}
+
+	var startAt time.Time
+	if raw := os.Getenv("BASE_START_FROM"); raw != "" {
+		startAt, err = internal.ParseStartTime(raw)
+		if err != nil {
+			log.Fatalf("invalid BASE_START_FROM: %v", err)
+		}
+	}
+
 	runner := internal.Runner{
 		Name:            "base-watcher",
 		PollInterval:    time.Duration(pollIntervalMs) * time.Millisecond,
-		Source:          source,
+		Source:          candidateSource,
 		Watcher:         watcher,
 		CheckpointStore: checkpointStore,
 		DedupeStore:     dedupeStore,
 		Logger:          slog.Default(),
+
+		CatchUpThreshold: int64(catchUpThreshold),
+		CatchUpWorkers:   catchUpWorkers,
+		Budget:           rpcBudget,
+		StartAt:          startAt,
+		StartMarker:      internal.CoreAPICheckpointStore{Client: &client, WatcherName: "base-watcher-start", Chain: "base"},
+		MaxHeld:          envIntOrDefault("BASE_MAX_HELD_CANDIDATES", 1000),
+		HoldAlertAfter:   time.Duration(envIntOrDefault("BASE_HOLD_ALERT_AFTER_MS", 24*60*60*1000)) * time.Millisecond,
 	}
 
 	if err := runner.Run(ctx); err != nil {
@@ -7,6 +7,7 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -14,24 +15,45 @@ import (
 )
 
 type callbackPayload struct {
-	EventID        string         `json:"eventId"`
-	Chain          string         `json:"chain"`
-	Token          string         `json:"token"`
-	TxHash         string         `json:"txHash"`
-	LogIndex       int            `json:"logIndex"`
-	TransferID     string         `json:"transferId,omitempty"`
-	DepositAddress string         `json:"depositAddress"`
-	AmountUSD      float64        `json:"amountUsd"`
-	ConfirmedAt    string         `json:"confirmedAt"`
-	Metadata       map[string]any `json:"metadata,omitempty"`
+	EventID         string         `json:"eventId"`
+	Chain           string         `json:"chain"`
+	Token           string         `json:"token"`
+	TxHash          string         `json:"txHash"`
+	LogIndex        int            `json:"logIndex"`
+	TransferID      string         `json:"transferId,omitempty"`
+	DepositAddress  string         `json:"depositAddress"`
+	AmountBaseUnits string         `json:"amountBaseUnits,omitempty"`
+	TokenDecimals   int            `json:"tokenDecimals"`
+	Amount          string         `json:"amount,omitempty"`
+	AmountUSD       float64        `json:"amountUsd"` // Deprecated: use amountBaseUnits and tokenDecimals
+	ConfirmedAt     string         `json:"confirmedAt"`
+	Metadata        map[string]any `json:"metadata,omitempty"`
+}
+
+type revertedCallbackPayload struct {
+	EventID         string         `json:"eventId"`
+	Chain           string         `json:"chain"`
+	Token           string         `json:"token"`
+	TxHash          string         `json:"txHash"`
+	LogIndex        int            `json:"logIndex"`
+	TransferID      string         `json:"transferId,omitempty"`
+	DepositAddress  string         `json:"depositAddress"`
+	AmountBaseUnits string         `json:"amountBaseUnits,omitempty"`
+	TokenDecimals   int            `json:"tokenDecimals"`
+	Amount          string         `json:"amount,omitempty"`
+	AmountUSD       float64        `json:"amountUsd"` // Deprecated: use amountBaseUnits and tokenDecimals
+	Reason          string         `json:"reason"`
+	RevertedAt      string         `json:"revertedAt"`
+	Metadata        map[string]any `json:"metadata,omitempty"`
 }
 
 type CallbackPublisher struct {
-	Endpoint  string
-	Secret    string
-	APIClient *CoreAPIClient
-	Client    *http.Client
-	Now       func() time.Time
+	Endpoint         string
+	RevertedEndpoint string
+	Secret           string
+	APIClient        *CoreAPIClient
+	Client           *http.Client
+	Now              func() time.Time
 }
 
 func (p CallbackPublisher) PublishFundingConfirmed(ctx context.Context, event FundingConfirmedEvent) error {
@@ -42,31 +64,72 @@ func (p CallbackPublisher) PublishFundingConfirmed(ctx context.Context, event Fu
 		return fmt.Errorf("callback secret is required")
 	}
 
-	client := p.Client
-	if client == nil {
-		client = &http.Client{Timeout: 5 * time.Second}
+	if event.ConfirmedAt.IsZero() {
+		return fmt.Errorf("confirmedAt is required")
 	}
 
-	now := p.Now
-	if now == nil {
-		now = time.Now
+	payload := callbackPayload{
+		EventID:         event.EventID,
+		Chain:           event.Chain,
+		Token:           event.Token,
+		TxHash:          event.TxHash,
+		LogIndex:        event.LogIndex,
+		TransferID:      event.TransferID,
+		DepositAddress:  event.DepositAddress,
+		AmountBaseUnits: event.AmountBaseUnits,
+		TokenDecimals:   event.TokenDecimals,
+		Amount:          event.Amount,
+		AmountUSD:       event.AmountUSD,
+		ConfirmedAt:     event.ConfirmedAt.UTC().Format(time.RFC3339Nano),
+		Metadata:        event.Metadata,
 	}
 
-	if event.ConfirmedAt.IsZero() {
-		return fmt.Errorf("confirmedAt is required")
+	return p.send(ctx, p.Endpoint, event.EventID, payload)
+}
+
+// ErrRevertNotDelivered is returned by PublishFundingReverted when no reverted
+// endpoint is configured, so the caller knows core-api still holds the credit.
+var ErrRevertNotDelivered = errors.New("funding reverted callback endpoint not configured")
+
+func (p CallbackPublisher) PublishFundingReverted(ctx context.Context, event FundingRevertedEvent) error {
+	if p.RevertedEndpoint == "" {
+		return ErrRevertNotDelivered
+	}
+	if p.Secret == "" {
+		return fmt.Errorf("callback secret is required")
 	}
 
-	payload := callbackPayload{
-		EventID:        event.EventID,
-		Chain:          event.Chain,
-		Token:          event.Token,
-		TxHash:         event.TxHash,
-		LogIndex:       event.LogIndex,
-		TransferID:     event.TransferID,
-		DepositAddress: event.DepositAddress,
-		AmountUSD:      event.AmountUSD,
-		ConfirmedAt:    event.ConfirmedAt.UTC().Format(time.RFC3339Nano),
-		Metadata:       event.Metadata,
+	payload := revertedCallbackPayload{
+		EventID:         event.EventID,
+		Chain:           event.Chain,
+		Token:           event.Token,
+		TxHash:          event.TxHash,
+		LogIndex:        event.LogIndex,
+		TransferID:      event.TransferID,
+		DepositAddress:  event.DepositAddress,
+		AmountBaseUnits: event.AmountBaseUnits,
+		TokenDecimals:   event.TokenDecimals,
+		Amount:          event.Amount,
+		AmountUSD:       event.AmountUSD,
+		Reason:          event.Reason,
+		RevertedAt:      p.now().UTC().Format(time.RFC3339Nano),
+		Metadata:        event.Metadata,
+	}
+
+	return p.send(ctx, p.RevertedEndpoint, "reverted:"+event.EventID, payload)
+}
+
+func (p CallbackPublisher) now() time.Time {
+	if p.Now == nil {
+		return time.Now()
+	}
+	return p.Now()
+}
+
+func (p CallbackPublisher) send(ctx context.Context, endpoint string, idempotencyKey string, payload any) error {
+	client := p.Client
+	if client == nil {
+		client = &http.Client{Timeout: 5 * time.Second}
 	}
 
 	body, err := json.Marshal(payload)
@@ -74,10 +137,10 @@ func (p CallbackPublisher) PublishFundingConfirmed(ctx context.Context, event Fu
 		return fmt.Errorf("marshal payload: %w", err)
 	}
 
-	timestampMs := strconv.FormatInt(now().UnixMilli(), 10)
+	timestampMs := strconv.FormatInt(p.now().UnixMilli(), 10)
 	sig := signPayload(timestampMs, body, p.Secret)
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.Endpoint, bytes.NewReader(body))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
 	if err != nil {
 		return fmt.Errorf("build request: %w", err)
 	}
@@ -85,7 +148,7 @@ func (p CallbackPublisher) PublishFundingConfirmed(ctx context.Context, event Fu
 	req.Header.Set("content-type", "application/json")
 	req.Header.Set("x-callback-timestamp", timestampMs)
 	req.Header.Set("x-callback-signature", sig)
-	req.Header.Set("idempotency-key", event.EventID)
+	req.Header.Set("idempotency-key", idempotencyKey)
 
 	if p.APIClient != nil {
 		if token, err := p.APIClient.CreateToken(); err == nil {
@@ -4,6 +4,7 @@ import (
 	"context"
 	"io"
 	"net/http"
+	"strings"
 	"testing"
 	"time"
 )
@@ -49,14 +50,17 @@ func TestCallbackPublisherPublishesSignedPayload(t *testing.T) {
 	}
 
 	event := FundingConfirmedEvent{
-		EventID:        "evt_123",
-		Chain:          "base",
-		Token:          "USDC",
-		TxHash:         "0xabc",
-		LogIndex:       1,
-		DepositAddress: "0xdep",
-		AmountUSD:      100,
-		ConfirmedAt:    time.Date(2026, 2, 13, 11, 59, 0, 0, time.UTC),
+		EventID:         "evt_123",
+		Chain:           "base",
+		Token:           "USDC",
+		TxHash:          "0xabc",
+		LogIndex:        1,
+		DepositAddress:  "0xdep",
+		AmountBaseUnits: "100000001",
+		TokenDecimals:   6,
+		Amount:          "100.000001",
+		AmountUSD:       100.000001,
+		ConfirmedAt:     time.Date(2026, 2, 13, 11, 59, 0, 0, time.UTC),
 	}
 
 	if err := pub.PublishFundingConfirmed(context.Background(), event); err != nil {
@@ -76,6 +80,9 @@ func TestCallbackPublisherPublishesSignedPayload(t *testing.T) {
 	if gotIdempotencyKey != event.EventID {
 		t.Fatalf("unexpected idempotency key: %s", gotIdempotencyKey)
 	}
+	if !strings.Contains(string(gotBody), `"amountBaseUnits":"100000001","tokenDecimals":6,"amount":"100.000001"`) {
+		t.Fatalf("expected exact amount fields in payload: %s", gotBody)
+	}
 }
 
 func TestCallbackPublisherFailsOnNonSuccess(t *testing.T) {
@@ -0,0 +1,123 @@
+package internal
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// SaltScheme selects how the deposit factory derives CREATE2 salts.
+type SaltScheme string
+
+const (
+	SaltTransferID SaltScheme = "transfer_id" // keccak256 of the transfer id, as core-api derives route addresses
+	SaltIndex      SaltScheme = "index"       // a uint256 counter, big-endian
+)
+
+// Create2Deriver derives deposit addresses of the deposit factory locally:
+//
+//	address = keccak256(0xff ++ factory ++ salt ++ initCodeHash)[12:]
+//
+// Each token has its own proxy init-code hash. Under the transfer-id scheme
+// addresses are derived from the transfer ids of active routes on every scan;
+// under the index scheme every index in [indexFrom, indexTo) is derived once at
+// construction.
+type Create2Deriver struct {
+	factory        []byte
+	initCodeHashes map[string][]byte // token -> proxy init-code hash
+	scheme         SaltScheme
+	indexed        map[string]map[string]bool // token -> derived addresses of the index range
+}
+
+func NewCreate2Deriver(factory string, initCodeHashes map[string]string, scheme SaltScheme, indexFrom int64, indexTo int64) (*Create2Deriver, error) {
+	factoryBytes, err := decodeHexBytes(factory)
+	if err != nil || len(factoryBytes) != 20 {
+		return nil, fmt.Errorf("invalid factory address %q", factory)
+	}
+	switch scheme {
+	case SaltTransferID, SaltIndex:
+	default:
+		return nil, fmt.Errorf("invalid salt scheme %q (expected transfer_id or index)", scheme)
+	}
+
+	d := &Create2Deriver{
+		factory:        factoryBytes,
+		initCodeHashes: make(map[string][]byte, len(initCodeHashes)),
+		scheme:         scheme,
+		indexed:        make(map[string]map[string]bool),
+	}
+	for token, initCodeHash := range initCodeHashes {
+		if strings.TrimSpace(initCodeHash) == "" {
+			continue
+		}
+		hash, err := decodeHexBytes(initCodeHash)
+		if err != nil || len(hash) != 32 {
+			return nil, fmt.Errorf("invalid %s init-code hash %q", token, initCodeHash)
+		}
+		d.initCodeHashes[strings.ToUpper(token)] = hash
+	}
+	if len(d.initCodeHashes) == 0 {
+		return nil, fmt.Errorf("at least one init-code hash is required")
+	}
+
+	if scheme != SaltIndex {
+		return d, nil
+	}
+	if indexTo <= indexFrom {
+		return nil, fmt.Errorf("empty salt index range [%d, %d)", indexFrom, indexTo)
+	}
+	for token := range d.initCodeHashes {
+		addresses := make(map[string]bool, indexTo-indexFrom)
+		for index := indexFrom; index < indexTo; index++ {
+			addresses[d.address(token, indexSalt(index))] = true
+		}
+		d.indexed[token] = addresses
+	}
+	return d, nil
+}
+
+// address returns the lower-cased CREATE2 address of token's proxy for salt.
+func (d *Create2Deriver) address(token string, salt []byte) string {
+	payload := make([]byte, 0, 85)
+	payload = append(payload, 0xff)
+	payload = append(payload, d.factory...)
+	payload = append(payload, salt...)
+	payload = append(payload, d.initCodeHashes[token]...)
+	return "0x" + hex.EncodeToString(keccak256(payload)[12:])
+}
+
+// derivableAddresses returns the lower-cased addresses a Transfer of token may
+// fund. Under the transfer-id scheme each route's transfer id is derived for
+// every token, so a token sent to another token's address is seen too. A nil
+// deriver derives nothing.
+func (d *Create2Deriver) derivableAddresses(token string, routes []ActiveRoute) map[string]bool {
+	if d == nil {
+		return nil
+	}
+	token = strings.ToUpper(token)
+	if _, ok := d.initCodeHashes[token]; !ok {
+		return map[string]bool{}
+	}
+
+	addresses := make(map[string]bool, len(d.indexed[token])+len(routes))
+	for address := range d.indexed[token] {
+		addresses[address] = true
+	}
+	if d.scheme == SaltTransferID {
+		for _, route := range routes {
+			if route.TransferID != "" {
+				addresses[d.address(token, transferIDSalt(route.TransferID))] = true
+			}
+		}
+	}
+	return addresses
+}
+
+func transferIDSalt(transferID string) []byte {
+	return keccak256([]byte(transferID))
+}
+
+func indexSalt(index int64) []byte {
+	return new(big.Int).SetInt64(index).FillBytes(make([]byte, 32))
+}
@@ -0,0 +1,69 @@
+package internal
+
+import (
+	"context"
+	"encoding/hex"
+	"testing"
+)
+
+func TestCreate2Deriver_MatchesEIP1014Vectors(t *testing.T) {
+	// EIP-1014 examples 0 and 1: salt 0, init code 0x00.
+	initCodeHash := "0x" + hex.EncodeToString(keccak256([]byte{0x00}))
+	cases := []struct {
+		factory string
+		want    string
+	}{
+		{"0x0000000000000000000000000000000000000000", "0x4d1a2e2bb4f88f0250f26ffff098b0b30b26bf38"},
+		{"0xdeadbeef00000000000000000000000000000000", "0xb928f69bb1d91cd65274e3c79d8986362984fda3"},
+	}
+	for _, tc := range cases {
+		deriver, err := NewCreate2Deriver(tc.factory, map[string]string{"USDC": initCodeHash}, SaltIndex, 0, 1)
+		if err != nil {
+			t.Fatalf("unexpected deriver error: %v", err)
+		}
+		if got := deriver.address("USDC", indexSalt(0)); got != tc.want {
+			t.Fatalf("factory %s: got %s, want %s", tc.factory, got, tc.want)
+		}
+		if !deriver.derivableAddresses("usdc", nil)[tc.want] {
+			t.Fatalf("factory %s: expected index 0 in the derivable set", tc.factory)
+		}
+	}
+}
+
+func TestEvmRpcSource_FactoryScanKeepsOnlyDerivableRecipients(t *testing.T) {
+	const factory = "0x5555555555555555555555555555555555555555"
+	initCodeHash := "0x" + hex.EncodeToString(keccak256([]byte("deposit-proxy")))
+	deriver, err := NewCreate2Deriver(factory, map[string]string{"USDC": initCodeHash, "USDT": initCodeHash}, SaltTransferID, 0, 0)
+	if err != nil {
+		t.Fatalf("unexpected deriver error: %v", err)
+	}
+	// The USDC address of a transfer that was opened for USDT.
+	derived := deriver.address("USDC", transferIDSalt("tr_2"))
+
+	node := newFakeEvmNode(110)
+	node.addTransfer(103, "0xroute", 0, testDepositAddr, 2_000_000)
+	node.addTransfer(104, "0xderived", 0, derived, 5_000_000)
+	node.addTransfer(105, "0xstranger", 0, "0x3333333333333333333333333333333333333333", 7_000_000)
+
+	for _, mode := range []ScanMode{ScanLogs, ScanBlockReceipts} {
+		source := newTestEvmSource(node.serve(t).URL)
+		source.RouteStore = routeStoreStub{routes: []ActiveRoute{
+			{TransferID: "tr_1", Token: "USDC", DepositAddress: testDepositAddr},
+			{TransferID: "tr_2", Token: "USDT", DepositAddress: "0x6666666666666666666666666666666666666666"},
+		}}
+		source.ScanMode = mode
+		source.FactoryLevelScan = true
+		source.Create2 = deriver
+
+		candidates, _, err := source.Poll(context.Background(), "100")
+		if err != nil {
+			t.Fatalf("%s: unexpected poll error: %v", mode, err)
+		}
+		if len(candidates) != 2 || candidates[0].TxHash != "0xroute" || candidates[1].TxHash != "0xderived" {
+			t.Fatalf("%s: expected the routed and the derivable transfer, got %+v", mode, candidates)
+		}
+		if candidates[1].TransferID != "" || candidates[1].DepositAddress != derived {
+			t.Fatalf("%s: expected the derivable transfer to be resolved by route, got %+v", mode, candidates[1])
+		}
+	}
+}
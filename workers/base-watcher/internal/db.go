@@ -129,3 +129,11 @@ func (s CoreAPIDedupeStore) Mark(ctx context.Context, key string) error {
 
 	return s.Client.Do(ctx, "POST", "/internal/v1/watchers/dedupe/mark/"+s.WatcherName, map[string]any{"eventKey": key}, nil)
 }
+
+func (s CoreAPIDedupeStore) Clear(ctx context.Context, key string) error {
+	if s.Client == nil {
+		return fmt.Errorf("core api client is required")
+	}
+
+	return s.Client.Do(ctx, "POST", "/internal/v1/watchers/dedupe/clear/"+s.WatcherName, map[string]any{"eventKey": key}, nil)
+}
@@ -0,0 +1,269 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// evmCursor is the checkpoint persisted by EvmRpcSource. Besides the last
+// scanned block it keeps the hashes of the most recent scanned blocks and the
+// candidates found in them, so a later poll can detect that those blocks were
+// reorged out and report which candidates disappeared.
+//
+// A cursor without hashes is encoded as a bare block number, which keeps
+// checkpoints written by older watcher versions readable.
+type evmCursor struct {
+	Block      int64              `json:"block"`
+	Hashes     []blockHashRef     `json:"hashes,omitempty"`
+	Candidates []trackedCandidate `json:"candidates,omitempty"`
+}
+
+type blockHashRef struct {
+	Number int64  `json:"number"`
+	Hash   string `json:"hash"`
+}
+
+type trackedCandidate struct {
+	BlockNumber    int64   `json:"blockNumber"`
+	Token          string  `json:"token"`
+	TxHash         string  `json:"txHash"`
+	LogIndex       int     `json:"logIndex"`
+	TransferID     string  `json:"transferId,omitempty"`
+	DepositAddress string  `json:"depositAddress"`
+	AmountUSD      float64 `json:"amountUsd"`
+
+	AmountBaseUnits string `json:"amountBaseUnits,omitempty"`
+	TokenDecimals   int    `json:"tokenDecimals,omitempty"`
+}
+
+func parseEvmCursor(raw string) evmCursor {
+	trimmed := strings.TrimSpace(raw)
+	if block, err := strconv.ParseInt(trimmed, 10, 64); err == nil {
+		return evmCursor{Block: block}
+	}
+
+	var cursor evmCursor
+	if err := json.Unmarshal([]byte(trimmed), &cursor); err != nil {
+		return evmCursor{}
+	}
+	return cursor
+}
+
+func (c evmCursor) String() string {
+	if len(c.Hashes) == 0 && len(c.Candidates) == 0 {
+		return strconv.FormatInt(c.Block, 10)
+	}
+
+	encoded, err := json.Marshal(c)
+	if err != nil {
+		return strconv.FormatInt(c.Block, 10)
+	}
+	return string(encoded)
+}
+
+// hashAt returns the recorded hash for a block number, if it is still tracked.
+func (c evmCursor) hashAt(blockNumber int64) string {
+	for _, ref := range c.Hashes {
+		if ref.Number == blockNumber {
+			return ref.Hash
+		}
+	}
+	return ""
+}
+
+// rewind drops every hash and candidate recorded above the given block and
+// returns the candidates that were dropped.
+func (c evmCursor) rewind(ancestor int64) (evmCursor, []trackedCandidate) {
+	out := evmCursor{Block: ancestor}
+	dropped := make([]trackedCandidate, 0)
+
+	for _, ref := range c.Hashes {
+		if ref.Number <= ancestor {
+			out.Hashes = append(out.Hashes, ref)
+		}
+	}
+	for _, candidate := range c.Candidates {
+		if candidate.BlockNumber <= ancestor {
+			out.Candidates = append(out.Candidates, candidate)
+			continue
+		}
+		dropped = append(dropped, candidate)
+	}
+
+	return out, dropped
+}
+
+// advance records newly scanned block hashes and candidates and trims both to
+// the most recent window blocks.
+func (c evmCursor) advance(toBlock int64, headers []blockHashRef, candidates []FundingCandidate, window int64) evmCursor {
+	out := evmCursor{Block: toBlock}
+
+	hashes := append(append([]blockHashRef{}, c.Hashes...), headers...)
+	sort.Slice(hashes, func(i, j int) bool { return hashes[i].Number < hashes[j].Number })
+
+	oldest := toBlock - window + 1
+	for _, ref := range hashes {
+		if ref.Number >= oldest && ref.Number <= toBlock {
+			out.Hashes = append(out.Hashes, ref)
+		}
+	}
+
+	for _, candidate := range c.Candidates {
+		if candidate.BlockNumber >= oldest {
+			out.Candidates = append(out.Candidates, candidate)
+		}
+	}
+	for _, candidate := range candidates {
+		blockNumber, ok := candidate.Metadata["blockNumber"].(int64)
+		// Filtered transfers are never published, so a reorg has nothing to revert.
+		if !ok || blockNumber < oldest || candidate.FilterReason != "" {
+			continue
+		}
+		out.Candidates = append(out.Candidates, trackedCandidate{
+			BlockNumber:    blockNumber,
+			Token:          candidate.Token,
+			TxHash:         candidate.TxHash,
+			LogIndex:       candidate.LogIndex,
+			TransferID:     candidate.TransferID,
+			DepositAddress: candidate.DepositAddress,
+			AmountUSD:      candidate.AmountUSD,
+
+			AmountBaseUnits: candidate.AmountBaseUnits,
+			TokenDecimals:   candidate.TokenDecimals,
+		})
+	}
+
+	return out
+}
+
+// CursorBefore steps cursor back to the block before the candidate's, so the
+// candidate is scanned again from the returned cursor.
+func (s EvmRpcSource) CursorBefore(cursor string, candidate FundingCandidate) (string, bool) {
+	blockNumber, ok := candidate.Metadata["blockNumber"].(int64)
+	current := parseEvmCursor(cursor)
+	if !ok || blockNumber > current.Block {
+		return cursor, false
+	}
+	stepped, _ := current.rewind(blockNumber - 1)
+	return stepped.String(), true
+}
+
+func (s EvmRpcSource) effectiveReorgWindow() int64 {
+	if s.ReorgWindow > 0 {
+		return s.ReorgWindow
+	}
+	return 64
+}
+
+// findCommonAncestor checks whether the tracked tip of the cursor is still
+// canonical. It returns the highest tracked block whose hash still matches the
+// chain and whether a reorg was detected.
+func (s EvmRpcSource) findCommonAncestor(ctx context.Context, cursor evmCursor) (int64, bool, error) {
+	if len(cursor.Hashes) == 0 {
+		return cursor.Block, false, nil
+	}
+
+	tip := cursor.Hashes[len(cursor.Hashes)-1]
+
+	// Fast path: the child of the tracked tip must point back at it.
+	next, err := s.ethGetBlockHeader(ctx, tip.Number+1)
+	if err == nil {
+		if strings.EqualFold(next.ParentHash, tip.Hash) {
+			return cursor.Block, false, nil
+		}
+	} else if !isBlockNotFoundError(err) {
+		return 0, false, err
+	}
+
+	numbers := make([]int64, len(cursor.Hashes))
+	for i, ref := range cursor.Hashes {
+		numbers[i] = ref.Number
+	}
+	headers, headerErrs, err := s.ethGetBlockHeaders(ctx, numbers)
+	if err != nil {
+		return 0, false, err
+	}
+
+	for i := len(cursor.Hashes) - 1; i >= 0; i-- {
+		if headerErrs[i] != nil {
+			if isBlockNotFoundError(headerErrs[i]) {
+				continue
+			}
+			return 0, false, headerErrs[i]
+		}
+		ref := cursor.Hashes[i]
+		if strings.EqualFold(headers[i].Hash, ref.Hash) {
+			return ref.Number, ref.Number != tip.Number, nil
+		}
+	}
+
+	oldest := cursor.Hashes[0].Number - 1
+	slog.Warn("base-rpc: reorg deeper than tracked window, rewinding to oldest tracked block",
+		"trackedFrom", cursor.Hashes[0].Number,
+		"trackedTo", tip.Number,
+		"rewindTo", oldest,
+	)
+	return oldest, true, nil
+}
+
+// revertedCandidates turns tracked candidates from orphaned blocks into
+// reverted FundingCandidates, skipping any that were found again on the new
+// canonical chain.
+func (s EvmRpcSource) revertedCandidates(orphaned []trackedCandidate, previous evmCursor, ancestor int64, rescanned []FundingCandidate) []FundingCandidate {
+	stillPresent := make(map[string]bool, len(rescanned))
+	for _, candidate := range rescanned {
+		stillPresent[candidate.TxHash+":"+strconv.Itoa(candidate.LogIndex)] = true
+	}
+
+	out := make([]FundingCandidate, 0, len(orphaned))
+	for _, tracked := range orphaned {
+		if stillPresent[tracked.TxHash+":"+strconv.Itoa(tracked.LogIndex)] {
+			continue
+		}
+
+		metadata := map[string]any{
+			"blockNumber":        tracked.BlockNumber,
+			"reorgAncestorBlock": ancestor,
+		}
+		if hash := previous.hashAt(tracked.BlockNumber); hash != "" {
+			metadata["blockHash"] = hash
+		}
+
+		reverted := FundingCandidate{
+			Chain:          s.Chain,
+			Token:          tracked.Token,
+			TxHash:         tracked.TxHash,
+			LogIndex:       tracked.LogIndex,
+			TransferID:     tracked.TransferID,
+			DepositAddress: tracked.DepositAddress,
+			AmountUSD:      tracked.AmountUSD,
+			Reverted:       true,
+			Metadata:       metadata,
+		}
+		if baseUnits, ok := new(big.Int).SetString(tracked.AmountBaseUnits, 10); ok {
+			reverted.setAmount(TokenInfo{Decimals: tracked.TokenDecimals}, baseUnits)
+		}
+		out = append(out, reverted)
+	}
+
+	return out
+}
+
+// checkHeaderChain verifies that freshly fetched headers link to each other and
+// to the last block that is still trusted.
+func checkHeaderChain(anchorHash string, headers []evmBlock) error {
+	previous := anchorHash
+	for _, header := range headers {
+		if previous != "" && !strings.EqualFold(header.ParentHash, previous) {
+			return fmt.Errorf("chain changed during poll at block %s", header.Number)
+		}
+		previous = header.Hash
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package internal
+
+import (
+	"fmt"
+)
+
+// evmHeader is a block header with every field that goes into its hash, so
+// the hash can be recomputed instead of trusting the one a provider reports.
+// Fields added by later forks are empty on blocks that predate them.
+type evmHeader struct {
+	Hash             string `json:"hash"`
+	ParentHash       string `json:"parentHash"`
+	Sha3Uncles       string `json:"sha3Uncles"`
+	Miner            string `json:"miner"`
+	StateRoot        string `json:"stateRoot"`
+	TransactionsRoot string `json:"transactionsRoot"`
+	ReceiptsRoot     string `json:"receiptsRoot"`
+	LogsBloom        string `json:"logsBloom"`
+	Difficulty       string `json:"difficulty"`
+	Number           string `json:"number"`
+	GasLimit         string `json:"gasLimit"`
+	GasUsed          string `json:"gasUsed"`
+	Timestamp        string `json:"timestamp"`
+	ExtraData        string `json:"extraData"`
+	MixHash          string `json:"mixHash"`
+	Nonce            string `json:"nonce"`
+
+	BaseFeePerGas         string `json:"baseFeePerGas,omitempty"`         // London
+	WithdrawalsRoot       string `json:"withdrawalsRoot,omitempty"`       // Shanghai
+	BlobGasUsed           string `json:"blobGasUsed,omitempty"`           // Cancun
+	ExcessBlobGas         string `json:"excessBlobGas,omitempty"`         // Cancun
+	ParentBeaconBlockRoot string `json:"parentBeaconBlockRoot,omitempty"` // Cancun
+	RequestsHash          string `json:"requestsHash,omitempty"`          // Prague
+}
+
+// headerHash returns the keccak256 of the header's RLP encoding, which is the
+// block hash.
+func headerHash(h evmHeader) ([]byte, error) {
+	type field struct {
+		value    string
+		quantity bool
+	}
+	fields := []field{
+		{h.ParentHash, false},
+		{h.Sha3Uncles, false},
+		{h.Miner, false},
+		{h.StateRoot, false},
+		{h.TransactionsRoot, false},
+		{h.ReceiptsRoot, false},
+		{h.LogsBloom, false},
+		{h.Difficulty, true},
+		{h.Number, true},
+		{h.GasLimit, true},
+		{h.GasUsed, true},
+		{h.Timestamp, true},
+		{h.ExtraData, false},
+		{h.MixHash, false},
+		{h.Nonce, false},
+	}
+	optional := []field{
+		{h.BaseFeePerGas, true},
+		{h.WithdrawalsRoot, false},
+		{h.BlobGasUsed, true},
+		{h.ExcessBlobGas, true},
+		{h.ParentBeaconBlockRoot, false},
+		{h.RequestsHash, false},
+	}
+	// Fork fields are appended in order; the first missing one ends the list.
+	for _, f := range optional {
+		if f.value == "" {
+			break
+		}
+		fields = append(fields, f)
+	}
+
+	items := make([][]byte, len(fields))
+	for i, f := range fields {
+		decode := decodeHexBytes
+		if f.quantity {
+			decode = decodeHexQuantity
+		}
+		value, err := decode(f.value)
+		if err != nil {
+			return nil, fmt.Errorf("header field %d: %w", i, err)
+		}
+		items[i] = rlpBytes(value)
+	}
+	return keccak256(rlpList(items...)), nil
+}
@@ -0,0 +1,138 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PriceSource returns the USD price of one whole unit of an asset.
+type PriceSource interface {
+	PriceUSD(ctx context.Context, symbol string) (float64, error)
+}
+
+// StaticPriceSource serves fixed prices, keyed by upper-case symbol.
+type StaticPriceSource map[string]float64
+
+func (p StaticPriceSource) PriceUSD(_ context.Context, symbol string) (float64, error) {
+	price, ok := p[strings.ToUpper(symbol)]
+	if !ok || price <= 0 {
+		return 0, fmt.Errorf("no static price for %s", symbol)
+	}
+	return price, nil
+}
+
+// HTTPPriceSource fetches a price from a JSON endpoint and caches it for
+// CacheTTL. URL may contain "{symbol}", and Field is a dotted path to the price
+// in the response, e.g. "data.amount" for Coinbase's spot price API. The price
+// may be a JSON number or a numeric string.
+type HTTPPriceSource struct {
+	URL        string
+	Field      string        // default: "price"
+	CacheTTL   time.Duration // default: 1 minute
+	HTTPClient *http.Client
+
+	mu    sync.Mutex
+	cache map[string]cachedPrice
+}
+
+type cachedPrice struct {
+	price     float64
+	fetchedAt time.Time
+}
+
+func NewHTTPPriceSource(url string, field string) *HTTPPriceSource {
+	return &HTTPPriceSource{URL: url, Field: field}
+}
+
+func (p *HTTPPriceSource) PriceUSD(ctx context.Context, symbol string) (float64, error) {
+	symbol = strings.ToUpper(symbol)
+
+	p.mu.Lock()
+	cached, ok := p.cache[symbol]
+	p.mu.Unlock()
+	if ok && time.Since(cached.fetchedAt) < p.cacheTTL() {
+		return cached.price, nil
+	}
+
+	price, err := p.fetch(ctx, symbol)
+	if err != nil {
+		return 0, err
+	}
+
+	p.mu.Lock()
+	if p.cache == nil {
+		p.cache = make(map[string]cachedPrice)
+	}
+	p.cache[symbol] = cachedPrice{price: price, fetchedAt: time.Now()}
+	p.mu.Unlock()
+	return price, nil
+}
+
+func (p *HTTPPriceSource) cacheTTL() time.Duration {
+	if p.CacheTTL > 0 {
+		return p.CacheTTL
+	}
+	return time.Minute
+}
+
+func (p *HTTPPriceSource) fetch(ctx context.Context, symbol string) (float64, error) {
+	client := p.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	url := strings.ReplaceAll(p.URL, "{symbol}", symbol)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("fetch %s price: %w", symbol, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return 0, fmt.Errorf("fetch %s price: status %d", symbol, resp.StatusCode)
+	}
+
+	var body any
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return 0, fmt.Errorf("decode %s price: %w", symbol, err)
+	}
+
+	field := p.Field
+	if field == "" {
+		field = "price"
+	}
+	value := body
+	for _, key := range strings.Split(field, ".") {
+		object, ok := value.(map[string]any)
+		if !ok {
+			return 0, fmt.Errorf("%s price response has no field %q", symbol, field)
+		}
+		value = object[key]
+	}
+
+	var price float64
+	switch v := value.(type) {
+	case float64:
+		price = v
+	case string:
+		price, err = strconv.ParseFloat(v, 64)
+		if err != nil {
+			return 0, fmt.Errorf("%s price %q is not a number", symbol, v)
+		}
+	default:
+		return 0, fmt.Errorf("%s price response has no field %q", symbol, field)
+	}
+	if price <= 0 {
+		return 0, fmt.Errorf("%s price %v is not positive", symbol, price)
+	}
+	return price, nil
+}
@@ -0,0 +1,212 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// Reason codes reported by ReceiptProofVerifier, in addition to the
+// ReceiptVerifier codes it shares.
+const (
+	ReceiptProofReasonHeaderMismatch = "header_hash_mismatch"
+	ReceiptProofReasonRootMismatch   = "receipts_root_mismatch"
+	ReceiptProofReasonNotInBlock     = "receipt_not_in_block"
+	ReceiptProofReasonEncoding       = "receipt_encoding_failed"
+	ReceiptProofReasonUnprovenNative = "unproven_native"
+)
+
+// ReceiptProofVerifier checks high-value candidates without trusting any
+// provider's eth_getLogs answer: it fetches every receipt of the candidate's
+// block, rebuilds the receipts trie locally and requires its root to equal the
+// header's receiptsRoot before looking for the Transfer log in the proven
+// receipt. The header itself must hash to the block hash the scan recorded, so
+// a provider cannot vouch for its own receipts with a made-up receiptsRoot.
+//
+// Candidates below MinAmountUSD pass through untouched. A root mismatch or a
+// log missing from the block, or a header that does not hash to its block, is
+// held and retried; a proven receipt that
+// failed, or a proven log that differs from the candidate, is rejected.
+//
+// Native transfers are held as unproven_native: they have no log, and the
+// receipts trie commits to neither the transaction hash, value nor recipient,
+// so a proven receipt says nothing about them.
+type ReceiptProofVerifier struct {
+	Source       EvmRpcSource
+	MinAmountUSD float64
+	Logger       *slog.Logger
+}
+
+type evmBlockTxHashes struct {
+	Hash         string   `json:"hash"`
+	Transactions []string `json:"transactions"`
+}
+
+func (v ReceiptProofVerifier) logger() *slog.Logger {
+	if v.Logger != nil {
+		return v.Logger
+	}
+	return slog.Default()
+}
+
+func (v ReceiptProofVerifier) VerifyCandidate(ctx context.Context, c FundingCandidate) (CandidateVerification, error) {
+	if c.AmountUSD < v.MinAmountUSD {
+		return CandidateVerification{Verified: true}, nil
+	}
+	if isNativeCandidate(c) {
+		return v.fail(c, ReceiptProofReasonUnprovenNative, false, nil), nil
+	}
+
+	header, err := v.candidateBlock(ctx, c)
+	if err != nil {
+		if isBlockNotFoundError(err) {
+			return v.fail(c, ReceiptReasonBlockNotCanonical, false, nil), nil
+		}
+		return CandidateVerification{}, err
+	}
+
+	wantHash, _ := c.Metadata["blockHash"].(string)
+	if wantHash == "" {
+		wantHash = header.Hash
+	}
+	computedHash, err := headerHash(header)
+	if err != nil || !strings.EqualFold("0x"+hex.EncodeToString(computedHash), wantHash) {
+		return v.fail(c, ReceiptProofReasonHeaderMismatch, false, map[string]any{
+			"receiptProofBlockHash": wantHash,
+			"headerHashComputed":    "0x" + hex.EncodeToString(computedHash),
+		}), nil
+	}
+
+	receipts, err := v.blockReceipts(ctx, header.Hash)
+	if err != nil {
+		return CandidateVerification{}, err
+	}
+
+	root, err := receiptsRoot(receipts)
+	if err != nil {
+		v.logger().Warn("base-rpc: receipt proof encoding failed", "blockHash", header.Hash, "error", err)
+		return v.fail(c, ReceiptProofReasonEncoding, false, nil), nil
+	}
+
+	metadata := map[string]any{
+		"receiptsRoot":          header.ReceiptsRoot,
+		"receiptProofBlockHash": header.Hash,
+	}
+	expected, err := decodeHexBytes(header.ReceiptsRoot)
+	if err != nil || !bytes.Equal(root, expected) {
+		metadata["receiptsRootComputed"] = "0x" + hex.EncodeToString(root)
+		return v.fail(c, ReceiptProofReasonRootMismatch, false, metadata), nil
+	}
+
+	// Log indexes and tx hashes are not part of the receipt encoding, so the
+	// log is located by its position in the proven receipts rather than by the
+	// indexes the provider reported.
+	proven, eventLog := provenLogAt(receipts, c.LogIndex)
+	if proven == nil || !strings.EqualFold(proven.TransactionHash, c.TxHash) {
+		return v.fail(c, ReceiptProofReasonNotInBlock, false, metadata), nil
+	}
+	if proven.Status != "0x1" {
+		return v.fail(c, ReceiptReasonTxFailed, true, metadata), nil
+	}
+	if compareTransferLog(eventLog, c, v.Source.Tokens) != "" {
+		return v.fail(c, ReceiptReasonLogMismatch, true, metadata), nil
+	}
+
+	metadata["receiptProof"] = "verified"
+	return CandidateVerification{Verified: true, Metadata: metadata}, nil
+}
+
+// provenLogAt returns the receipt and log at a block-wide log index.
+func provenLogAt(receipts []evmReceipt, logIndex int) (*evmReceipt, evmLog) {
+	if logIndex < 0 {
+		return nil, evmLog{}
+	}
+	for i := range receipts {
+		if logIndex < len(receipts[i].Logs) {
+			return &receipts[i], receipts[i].Logs[logIndex]
+		}
+		logIndex -= len(receipts[i].Logs)
+	}
+	return nil, evmLog{}
+}
+
+func (v ReceiptProofVerifier) fail(c FundingCandidate, reason string, rejected bool, metadata map[string]any) CandidateVerification {
+	if metadata == nil {
+		metadata = map[string]any{}
+	}
+	metadata["receiptProof"] = reason
+
+	v.logger().Warn("base-rpc: receipt proof failed",
+		"txHash", c.TxHash,
+		"logIndex", c.LogIndex,
+		"depositAddress", c.DepositAddress,
+		"amountUSD", c.AmountUSD,
+		"reason", reason,
+		"rejected", rejected,
+	)
+	return CandidateVerification{Rejected: rejected, Reason: reason, Metadata: metadata}
+}
+
+// candidateBlock returns the full header of the block the candidate's log was
+// reported in, by hash when the source recorded one.
+func (v ReceiptProofVerifier) candidateBlock(ctx context.Context, c FundingCandidate) (evmHeader, error) {
+	method := "eth_getBlockByHash"
+	ref, _ := c.Metadata["blockHash"].(string)
+	if ref == "" {
+		blockNumber, ok := c.Metadata["blockNumber"].(int64)
+		if !ok {
+			return evmHeader{}, fmt.Errorf("candidate %s has no block reference", c.TxHash)
+		}
+		method, ref = "eth_getBlockByNumber", fmt.Sprintf("0x%x", blockNumber)
+	}
+
+	var header *evmHeader
+	if err := v.Source.rpcCall(ctx, method, []interface{}{ref, false}, &header); err != nil {
+		return evmHeader{}, fmt.Errorf("%s: %w", method, err)
+	}
+	if header == nil || header.Hash == "" {
+		return evmHeader{}, fmt.Errorf("%s %s: %w", method, ref, errBlockNotFound)
+	}
+	return *header, nil
+}
+
+// blockReceipts fetches every receipt of a block, ordered by transaction
+// index. It prefers eth_getBlockReceipts and falls back to one batched
+// eth_getTransactionReceipt per transaction for providers without it.
+func (v ReceiptProofVerifier) blockReceipts(ctx context.Context, blockHash string) ([]evmReceipt, error) {
+	var receipts []evmReceipt
+	err := v.Source.rpcCall(ctx, "eth_getBlockReceipts", []interface{}{blockHash}, &receipts)
+	if err == nil && receipts != nil {
+		return receipts, nil
+	}
+
+	var block *evmBlockTxHashes
+	if err := v.Source.rpcCall(ctx, "eth_getBlockByHash", []interface{}{blockHash, false}, &block); err != nil {
+		return nil, fmt.Errorf("eth_getBlockByHash: %w", err)
+	}
+	if block == nil || block.Hash == "" {
+		return nil, fmt.Errorf("eth_getBlockByHash %s: %w", blockHash, errBlockNotFound)
+	}
+
+	calls := make([]*rpcBatchCall, len(block.Transactions))
+	receipts = make([]evmReceipt, len(block.Transactions))
+	for i, txHash := range block.Transactions {
+		calls[i] = &rpcBatchCall{
+			Method: "eth_getTransactionReceipt",
+			Params: []interface{}{txHash},
+			Out:    &receipts[i],
+		}
+	}
+	if err := v.Source.rpc().batch(ctx, calls); err != nil {
+		return nil, fmt.Errorf("eth_getTransactionReceipt batch: %w", err)
+	}
+	for i, call := range calls {
+		if call.Err != nil {
+			return nil, fmt.Errorf("eth_getTransactionReceipt %s: %w", block.Transactions[i], call.Err)
+		}
+	}
+	return receipts, nil
+}
@@ -0,0 +1,118 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// Reason codes reported by ReceiptVerifier.
+const (
+	ReceiptReasonNotFound          = "receipt_not_found"
+	ReceiptReasonTxFailed          = "tx_failed"
+	ReceiptReasonLogNotInReceipt   = "log_not_in_receipt"
+	ReceiptReasonLogMismatch       = "log_mismatch"
+	ReceiptReasonBlockHashMismatch = "block_hash_mismatch"
+	ReceiptReasonBlockNotCanonical = "block_not_canonical"
+)
+
+// ReceiptVerifier checks a candidate against eth_getTransactionReceipt: the tx
+// must have succeeded, the Transfer log must sit in the receipt at the same
+// index with the same contents, and the receipt's block must be canonical.
+//
+// Native ETH candidates have no log; for those the tx status and block are
+// checked.
+//
+// A failed tx or a log whose contents differ is rejected outright. The other
+// failures usually come from a lagging node or a reorg in progress, so those
+// candidates are held and retried.
+type ReceiptVerifier struct {
+	Source EvmRpcSource
+	Logger *slog.Logger
+}
+
+func (v ReceiptVerifier) logger() *slog.Logger {
+	if v.Logger != nil {
+		return v.Logger
+	}
+	return slog.Default()
+}
+
+func (v ReceiptVerifier) VerifyCandidate(ctx context.Context, c FundingCandidate) (CandidateVerification, error) {
+	var receipt *evmReceipt
+	if err := v.Source.rpcCall(ctx, "eth_getTransactionReceipt", []interface{}{c.TxHash}, &receipt); err != nil {
+		return CandidateVerification{}, fmt.Errorf("eth_getTransactionReceipt: %w", err)
+	}
+
+	reason, rejected, err := v.check(ctx, c, receipt)
+	if err != nil {
+		return CandidateVerification{}, err
+	}
+	if reason != "" {
+		v.logger().Warn("base-rpc: receipt verification failed",
+			"txHash", c.TxHash,
+			"logIndex", c.LogIndex,
+			"depositAddress", c.DepositAddress,
+			"reason", reason,
+			"rejected", rejected,
+		)
+		return CandidateVerification{
+			Rejected: rejected,
+			Reason:   reason,
+			Metadata: map[string]any{"receiptVerification": reason},
+		}, nil
+	}
+
+	return CandidateVerification{
+		Verified: true,
+		Metadata: map[string]any{"receiptVerification": "verified"},
+	}, nil
+}
+
+func (v ReceiptVerifier) check(ctx context.Context, c FundingCandidate, receipt *evmReceipt) (string, bool, error) {
+	if receipt == nil {
+		return ReceiptReasonNotFound, false, nil
+	}
+	if receipt.Status != "0x1" {
+		return ReceiptReasonTxFailed, true, nil
+	}
+
+	if !isNativeCandidate(c) {
+		var matched *evmLog
+		for i := range receipt.Logs {
+			logIndex, err := parseHexInt64(receipt.Logs[i].LogIndex)
+			if err == nil && int(logIndex) == c.LogIndex {
+				matched = &receipt.Logs[i]
+				break
+			}
+		}
+		if matched == nil {
+			return ReceiptReasonLogNotInReceipt, false, nil
+		}
+		if compareTransferLog(*matched, c, v.Source.Tokens) != "" {
+			return ReceiptReasonLogMismatch, true, nil
+		}
+	}
+
+	if candidateHash, _ := c.Metadata["blockHash"].(string); candidateHash != "" && !strings.EqualFold(candidateHash, receipt.BlockHash) {
+		return ReceiptReasonBlockHashMismatch, false, nil
+	}
+
+	blockNumber, err := parseHexInt64(receipt.BlockNumber)
+	if err != nil {
+		return "", false, err
+	}
+	header, err := v.Source.ethGetBlockHeader(ctx, blockNumber)
+	if err != nil {
+		if isBlockNotFoundError(err) {
+			return ReceiptReasonBlockNotCanonical, false, nil
+		}
+		return "", false, err
+	}
+	if !strings.EqualFold(header.Hash, receipt.BlockHash) {
+		return ReceiptReasonBlockNotCanonical, false, nil
+	}
+
+	return "", false, nil
+}
@@ -0,0 +1,262 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// This file rebuilds a block's receipts trie so a Transfer log can be checked
+// against the header's receiptsRoot instead of trusting a provider's answer.
+// It covers the subset of RLP and the Merkle Patricia trie that receipts need.
+
+// rlpBytes encodes a byte string.
+func rlpBytes(b []byte) []byte {
+	if len(b) == 1 && b[0] < 0x80 {
+		return []byte{b[0]}
+	}
+	return append(rlpLength(len(b), 0x80), b...)
+}
+
+// rlpList encodes a list whose items are already RLP encoded.
+func rlpList(items ...[]byte) []byte {
+	payload := bytes.Join(items, nil)
+	return append(rlpLength(len(payload), 0xc0), payload...)
+}
+
+func rlpLength(length int, offset byte) []byte {
+	if length < 56 {
+		return []byte{offset + byte(length)}
+	}
+	encoded := make([]byte, 0, 8)
+	for n := length; n > 0; n >>= 8 {
+		encoded = append([]byte{byte(n)}, encoded...)
+	}
+	return append([]byte{offset + 55 + byte(len(encoded))}, encoded...)
+}
+
+func rlpUint(n uint64) []byte {
+	encoded := make([]byte, 0, 8)
+	for ; n > 0; n >>= 8 {
+		encoded = append([]byte{byte(n)}, encoded...)
+	}
+	return rlpBytes(encoded)
+}
+
+// decodeHexBytes decodes 0x-prefixed hex data, tolerating an odd length.
+func decodeHexBytes(raw string) ([]byte, error) {
+	trimmed := strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(raw), "0x"), "0X")
+	if len(trimmed)%2 == 1 {
+		trimmed = "0" + trimmed
+	}
+	return hex.DecodeString(trimmed)
+}
+
+// decodeHexQuantity decodes a hex quantity to its minimal big-endian bytes.
+func decodeHexQuantity(raw string) ([]byte, error) {
+	decoded, err := decodeHexBytes(raw)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.TrimLeft(decoded, "\x00"), nil
+}
+
+// encodeReceipt returns the consensus encoding of a receipt: the RLP list for
+// legacy receipts, or the type byte followed by the list for typed receipts.
+// OP Stack deposit receipts (type 0x7e) carry depositNonce and
+// depositReceiptVersion when the node reports them.
+func encodeReceipt(receipt evmReceipt) ([]byte, error) {
+	txType := uint64(0)
+	if receipt.Type != "" {
+		parsed, err := parseHexInt64(receipt.Type)
+		if err != nil {
+			return nil, fmt.Errorf("receipt type: %w", err)
+		}
+		txType = uint64(parsed)
+	}
+
+	var status []byte
+	switch receipt.Status {
+	case "0x1":
+		status = rlpBytes([]byte{1})
+	case "0x0":
+		status = rlpBytes(nil)
+	default:
+		return nil, fmt.Errorf("unsupported receipt status %q", receipt.Status)
+	}
+
+	cumulativeGas, err := decodeHexQuantity(receipt.CumulativeGasUsed)
+	if err != nil {
+		return nil, fmt.Errorf("receipt cumulativeGasUsed: %w", err)
+	}
+	bloom, err := decodeHexBytes(receipt.LogsBloom)
+	if err != nil {
+		return nil, fmt.Errorf("receipt logsBloom: %w", err)
+	}
+
+	logs := make([][]byte, 0, len(receipt.Logs))
+	for _, eventLog := range receipt.Logs {
+		encoded, err := encodeReceiptLog(eventLog)
+		if err != nil {
+			return nil, err
+		}
+		logs = append(logs, encoded)
+	}
+
+	fields := [][]byte{status, rlpBytes(cumulativeGas), rlpBytes(bloom), rlpList(logs...)}
+	if txType == 0x7e {
+		for _, optional := range []*string{receipt.DepositNonce, receipt.DepositReceiptVersion} {
+			if optional == nil {
+				break
+			}
+			value, err := decodeHexQuantity(*optional)
+			if err != nil {
+				return nil, fmt.Errorf("deposit receipt field: %w", err)
+			}
+			fields = append(fields, rlpBytes(value))
+		}
+	}
+
+	encoded := rlpList(fields...)
+	if txType == 0 {
+		return encoded, nil
+	}
+	return append([]byte{byte(txType)}, encoded...), nil
+}
+
+func encodeReceiptLog(eventLog evmLog) ([]byte, error) {
+	address, err := decodeHexBytes(eventLog.Address)
+	if err != nil {
+		return nil, fmt.Errorf("log address: %w", err)
+	}
+	topics := make([][]byte, 0, len(eventLog.Topics))
+	for _, topic := range eventLog.Topics {
+		decoded, err := decodeHexBytes(topic)
+		if err != nil {
+			return nil, fmt.Errorf("log topic: %w", err)
+		}
+		topics = append(topics, rlpBytes(decoded))
+	}
+	data, err := decodeHexBytes(eventLog.Data)
+	if err != nil {
+		return nil, fmt.Errorf("log data: %w", err)
+	}
+	return rlpList(rlpBytes(address), rlpList(topics...), rlpBytes(data)), nil
+}
+
+// receiptsRoot computes the receipts trie root for receipts ordered by
+// transaction index.
+func receiptsRoot(receipts []evmReceipt) ([]byte, error) {
+	entries := make([]trieEntry, 0, len(receipts))
+	for i, receipt := range receipts {
+		encoded, err := encodeReceipt(receipt)
+		if err != nil {
+			return nil, fmt.Errorf("encode receipt %s: %w", receipt.TransactionHash, err)
+		}
+		entries = append(entries, trieEntry{key: rlpUint(uint64(i)), value: encoded})
+	}
+	return trieRoot(entries), nil
+}
+
+type trieEntry struct {
+	key   []byte
+	value []byte
+}
+
+type trieNibbleEntry struct {
+	nibbles []byte
+	value   []byte
+}
+
+// trieRoot returns the root hash of a Merkle Patricia trie holding entries.
+func trieRoot(entries []trieEntry) []byte {
+	nibbleEntries := make([]trieNibbleEntry, len(entries))
+	for i, entry := range entries {
+		nibbles := make([]byte, 0, len(entry.key)*2)
+		for _, b := range entry.key {
+			nibbles = append(nibbles, b>>4, b&0x0f)
+		}
+		nibbleEntries[i] = trieNibbleEntry{nibbles: nibbles, value: entry.value}
+	}
+	sort.Slice(nibbleEntries, func(i, j int) bool {
+		return bytes.Compare(nibbleEntries[i].nibbles, nibbleEntries[j].nibbles) < 0
+	})
+
+	return keccak256(trieNode(nibbleEntries, 0))
+}
+
+// trieNode encodes the node holding entries, which share their first depth
+// nibbles and are sorted.
+func trieNode(entries []trieNibbleEntry, depth int) []byte {
+	switch len(entries) {
+	case 0:
+		return rlpBytes(nil)
+	case 1:
+		return rlpList(rlpBytes(hexPrefix(entries[0].nibbles[depth:], true)), rlpBytes(entries[0].value))
+	}
+
+	shared := commonNibblePrefix(entries, depth)
+	if shared > 0 {
+		path := entries[0].nibbles[depth : depth+shared]
+		return rlpList(rlpBytes(hexPrefix(path, false)), trieChildRef(trieNode(entries, depth+shared)))
+	}
+
+	children := make([][]byte, 17)
+	for i := range children {
+		children[i] = rlpBytes(nil)
+	}
+	start := 0
+	if len(entries[0].nibbles) == depth {
+		children[16] = rlpBytes(entries[0].value)
+		start = 1
+	}
+	for start < len(entries) {
+		nibble := entries[start].nibbles[depth]
+		end := start + 1
+		for end < len(entries) && entries[end].nibbles[depth] == nibble {
+			end++
+		}
+		children[nibble] = trieChildRef(trieNode(entries[start:end], depth+1))
+		start = end
+	}
+	return rlpList(children...)
+}
+
+// trieChildRef embeds nodes shorter than 32 bytes and hashes the rest.
+func trieChildRef(encoded []byte) []byte {
+	if len(encoded) < 32 {
+		return encoded
+	}
+	return rlpBytes(keccak256(encoded))
+}
+
+func commonNibblePrefix(entries []trieNibbleEntry, depth int) int {
+	first, last := entries[0].nibbles[depth:], entries[len(entries)-1].nibbles[depth:]
+	shared := 0
+	for shared < len(first) && shared < len(last) && first[shared] == last[shared] {
+		shared++
+	}
+	return shared
+}
+
+// hexPrefix applies the compact nibble encoding used by leaf and extension
+// nodes.
+func hexPrefix(nibbles []byte, leaf bool) []byte {
+	flag := byte(0)
+	if leaf {
+		flag = 2
+	}
+	out := make([]byte, 0, len(nibbles)/2+1)
+	if len(nibbles)%2 == 1 {
+		out = append(out, (flag+1)<<4|nibbles[0])
+		nibbles = nibbles[1:]
+	} else {
+		out = append(out, flag<<4)
+	}
+	for i := 0; i < len(nibbles); i += 2 {
+		out = append(out, nibbles[i]<<4|nibbles[i+1])
+	}
+	return out
+}
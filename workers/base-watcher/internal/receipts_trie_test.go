@@ -0,0 +1,83 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestKeccak256_KnownVectors(t *testing.T) {
+	cases := map[string]string{
+		"":    "c5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470",
+		"abc": "4e03657aea45a94fc7d47ba826c8d667c0d1e6e33a64a036ec44f58fa12d6c45",
+	}
+	for input, want := range cases {
+		if got := hex.EncodeToString(keccak256([]byte(input))); got != want {
+			t.Fatalf("keccak256(%q) = %s, want %s", input, got, want)
+		}
+	}
+
+	if got := "0x" + hex.EncodeToString(keccak256([]byte("Transfer(address,address,uint256)"))); got != transferTopic {
+		t.Fatalf("transfer topic = %s, want %s", got, transferTopic)
+	}
+
+	// Inputs around and past the 136-byte rate exercise padding in its own
+	// block and absorbing more than one block.
+	multiBlock := map[int]string{
+		135: "34367dc248bbd832f4e3e69dfaac2f92638bd0bbd18f2912ba4ef454919cf446",
+		136: "a6c4d403279fe3e0af03729caada8374b5ca54d8065329a3ebcaeb4b60aa386e",
+		137: "d869f639c7046b4929fc92a4d988a8b22c55fbadb802c0c66ebcd484f1915f39",
+		272: "cf7fcd4f705ee749930d19ca84561a9bf62516bd90a471545fa2f49fdc7e63c8",
+		300: "5b7e0e47a96f32a88b4f14ca177982790807c40e1a105742ba0fc1babe1ef826",
+	}
+	for length, want := range multiBlock {
+		if got := hex.EncodeToString(keccak256(bytes.Repeat([]byte("a"), length))); got != want {
+			t.Fatalf("keccak256(%d x \"a\") = %s, want %s", length, got, want)
+		}
+	}
+}
+
+func TestHeaderHash_MatchesMainnetGenesis(t *testing.T) {
+	zeroHash := "0x" + strings.Repeat("00", 32)
+	genesis := evmHeader{
+		ParentHash:       zeroHash,
+		Sha3Uncles:       "0x1dcc4de8dec75d7aab85b567b6ccd41ad312451b948a7413f0a142fd40d49347",
+		Miner:            "0x0000000000000000000000000000000000000000",
+		StateRoot:        "0xd7f8974fb5ac78d9ac099b9ad5018bedc2ce0a72dad1827a1709da30580f0544",
+		TransactionsRoot: "0x56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421",
+		ReceiptsRoot:     "0x56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421",
+		LogsBloom:        "0x" + strings.Repeat("00", 256),
+		Difficulty:       "0x400000000",
+		Number:           "0x0",
+		GasLimit:         "0x1388",
+		GasUsed:          "0x0",
+		Timestamp:        "0x0",
+		ExtraData:        "0x11bbe8db4e347b4e8c937c1c8370e4b5ed33adb3db69cbdb7a38e1e50b1b82fa",
+		MixHash:          zeroHash,
+		Nonce:            "0x0000000000000042",
+	}
+	hash, err := headerHash(genesis)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := hex.EncodeToString(hash); got != "d4e56740f876aef8c010b86a40d5f56745a118d0906a34e69aec8c0db1cb8fa3" {
+		t.Fatalf("unexpected genesis hash %s", got)
+	}
+}
+
+func TestTrieRoot_MatchesReferenceTries(t *testing.T) {
+	if got := hex.EncodeToString(trieRoot(nil)); got != "56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421" {
+		t.Fatalf("unexpected empty trie root %s", got)
+	}
+
+	root := trieRoot([]trieEntry{
+		{key: []byte("doe"), value: []byte("reindeer")},
+		{key: []byte("dog"), value: []byte("puppy")},
+		{key: []byte("dogglesworth"), value: []byte("cat")},
+	})
+	if got := hex.EncodeToString(root); got != "8aad789dff2f538bca5d8ea56e8abe10f4c7ba3a5dea95fea4cd6e7c3a1168d3" {
+		t.Fatalf("unexpected trie root %s", got)
+	}
+
+}
@@ -0,0 +1,102 @@
+package internal
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func newTestBudget(now *time.Time, slept *[]time.Duration) *RPCBudget {
+	return &RPCBudget{
+		Name:           "test-rpc",
+		UnitsPerSecond: 100,
+		MethodCosts:    map[string]float64{"eth_blockNumber": 10, "eth_getLogs": 75},
+		now:            func() time.Time { return *now },
+		sleep: func(_ context.Context, d time.Duration) error {
+			*slept = append(*slept, d)
+			*now = now.Add(d)
+			return nil
+		},
+	}
+}
+
+func TestRPCBudget_PacesByMethodCost(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	var slept []time.Duration
+	budget := newTestBudget(&now, &slept)
+
+	// The bucket starts full with 100 units: the first call is free, the
+	// second leaves it 50 units in debt.
+	for i := 0; i < 2; i++ {
+		if err := budget.acquire(context.Background(), "eth_getLogs"); err != nil {
+			t.Fatalf("unexpected acquire error: %v", err)
+		}
+	}
+	if len(slept) != 1 || slept[0] != 500*time.Millisecond {
+		t.Fatalf("expected one 500ms wait, got %v", slept)
+	}
+
+	if err := budget.acquire(context.Background(), "eth_blockNumber", "eth_blockNumber", "eth_unknown"); err != nil {
+		t.Fatalf("unexpected acquire error: %v", err)
+	}
+	report := budget.Report()
+	if len(report) != 3 || report[0].Method != "eth_getLogs" || report[0].Calls != 2 || report[0].Units != 150 {
+		t.Fatalf("unexpected usage report: %+v", report)
+	}
+	if report[1].Method != "eth_blockNumber" || report[1].Units != 20 || report[2].Units != 1 {
+		t.Fatalf("unexpected usage report: %+v", report)
+	}
+}
+
+func TestRPCBudget_SlowsDownWhenAheadOfPlan(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	var slept []time.Duration
+	budget := newTestBudget(&now, &slept)
+	budget.UnitsPerSecond = 0
+	budget.PeriodLimit = 1000
+	budget.Period = 10 * time.Hour
+
+	_ = budget.acquire(context.Background(), "eth_getLogs")
+	if slowdown := budget.Slowdown(); slowdown != 1 {
+		t.Fatalf("expected no slowdown on plan, got %v", slowdown)
+	}
+
+	// 300 units (30%) after 5 hours (50%) is on plan; after 1 hour it is 3x.
+	_ = budget.acquire(context.Background(), "eth_getLogs", "eth_getLogs", "eth_getLogs")
+	now = now.Add(time.Hour)
+	if slowdown := budget.Slowdown(); slowdown < 2.99 || slowdown > 3.01 {
+		t.Fatalf("expected a 3x slowdown, got %v", slowdown)
+	}
+	if size := budget.scaleBatch(50); size != 16 {
+		t.Fatalf("expected batches to shrink to 16, got %d", size)
+	}
+	now = now.Add(4 * time.Hour)
+	if slowdown := budget.Slowdown(); slowdown != 1 {
+		t.Fatalf("expected no slowdown back on plan, got %v", slowdown)
+	}
+
+	for i := 0; i < 10; i++ {
+		_ = budget.acquire(context.Background(), "eth_getLogs")
+	}
+	if slowdown := budget.Slowdown(); slowdown != 8 {
+		t.Fatalf("expected the maximum slowdown past the limit, got %v", slowdown)
+	}
+
+	now = now.Add(5 * time.Hour)
+	if slowdown := budget.Slowdown(); slowdown != 1 {
+		t.Fatalf("expected a new period to reset the slowdown, got %v", slowdown)
+	}
+}
+
+func TestParseMethodCosts(t *testing.T) {
+	costs, err := ParseMethodCosts(" eth_getLogs:20, trace_block:0 ", map[string]float64{"eth_getLogs": 75, "eth_call": 26})
+	if err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+	if costs["eth_getLogs"] != 20 || costs["trace_block"] != 0 || costs["eth_call"] != 26 {
+		t.Fatalf("unexpected costs: %v", costs)
+	}
+	if _, err := ParseMethodCosts("eth_getLogs", nil); err == nil {
+		t.Fatalf("expected an error for an entry without units")
+	}
+}
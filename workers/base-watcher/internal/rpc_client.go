@@ -0,0 +1,198 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// rpcClient is a JSON-RPC 2.0 client over HTTP that can send single calls or
+// batches. Batched responses are matched back to their request by ID, so a
+// provider may answer out of order or fail individual items.
+type rpcClient struct {
+	URL            string
+	Pool           *RPCPool
+	HTTPClient     *http.Client
+	DefaultTimeout time.Duration
+	MaxBatchSize   int
+	Budget         *RPCBudget // when set, calls wait for compute units and batches shrink under pressure
+}
+
+// rpcBatchCall is one item of a batch. After rpcClient.batch returns, Err holds
+// the per-item failure, if any, and Out has been decoded on success.
+type rpcBatchCall struct {
+	Method string
+	Params interface{}
+	Out    interface{}
+	Err    error
+}
+
+func (c rpcClient) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return &http.Client{Timeout: c.DefaultTimeout}
+}
+
+func (c rpcClient) effectiveMaxBatchSize() int {
+	size := 50
+	if c.MaxBatchSize > 0 {
+		size = c.MaxBatchSize
+	}
+	if c.Budget != nil {
+		size = c.Budget.scaleBatch(size)
+	}
+	return size
+}
+
+func (c rpcClient) call(ctx context.Context, method string, params interface{}, out interface{}) error {
+	reqBody, err := json.Marshal(rpcRequest{
+		JSONRPC: "2.0",
+		ID:      1,
+		Method:  method,
+		Params:  params,
+	})
+	if err != nil {
+		return err
+	}
+	if err := c.Budget.acquire(ctx, method); err != nil {
+		return err
+	}
+
+	raw, err := c.post(ctx, reqBody)
+	if err != nil {
+		return err
+	}
+
+	var rpcResp rpcResponse
+	if err := json.Unmarshal(raw, &rpcResp); err != nil {
+		return err
+	}
+	return decodeRPCResponse(rpcResp, out)
+}
+
+// batch sends calls in chunks of MaxBatchSize. It only returns an error when a
+// whole chunk could not be sent; item failures are reported on each call.
+func (c rpcClient) batch(ctx context.Context, calls []*rpcBatchCall) error {
+	chunkSize := c.effectiveMaxBatchSize()
+	for start := 0; start < len(calls); start += chunkSize {
+		end := start + chunkSize
+		if end > len(calls) {
+			end = len(calls)
+		}
+		if err := c.batchChunk(ctx, calls[start:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c rpcClient) batchChunk(ctx context.Context, calls []*rpcBatchCall) error {
+	if len(calls) == 0 {
+		return nil
+	}
+
+	requests := make([]rpcRequest, len(calls))
+	methods := make([]string, len(calls))
+	for i, call := range calls {
+		methods[i] = call.Method
+		requests[i] = rpcRequest{
+			JSONRPC: "2.0",
+			ID:      i + 1,
+			Method:  call.Method,
+			Params:  call.Params,
+		}
+	}
+
+	reqBody, err := json.Marshal(requests)
+	if err != nil {
+		return err
+	}
+	if err := c.Budget.acquire(ctx, methods...); err != nil {
+		return err
+	}
+
+	raw, err := c.post(ctx, reqBody)
+	if err != nil {
+		return err
+	}
+
+	var responses []rpcResponse
+	if err := json.Unmarshal(raw, &responses); err != nil {
+		// Some providers answer a rejected batch with a single error object.
+		var single rpcResponse
+		if singleErr := json.Unmarshal(raw, &single); singleErr == nil && single.Error != nil {
+			return fmt.Errorf("rpc batch rejected: %w", &rpcCallError{Code: single.Error.Code, Message: single.Error.Message})
+		}
+		return fmt.Errorf("decode rpc batch response: %w", err)
+	}
+
+	byID := make(map[int]rpcResponse, len(responses))
+	for _, resp := range responses {
+		byID[resp.ID] = resp
+	}
+
+	for i, call := range calls {
+		resp, ok := byID[i+1]
+		if !ok {
+			call.Err = fmt.Errorf("rpc batch response missing id %d (%s)", i+1, call.Method)
+			continue
+		}
+		call.Err = decodeRPCResponse(resp, call.Out)
+	}
+
+	return nil
+}
+
+func (c rpcClient) post(ctx context.Context, reqBody []byte) ([]byte, error) {
+	if c.Pool != nil {
+		return c.Pool.post(ctx, c.httpClient(), reqBody)
+	}
+	return postRPC(ctx, c.httpClient(), c.URL, reqBody)
+}
+
+// rpcStatusError is returned when a provider answers with a non-2xx status.
+type rpcStatusError struct {
+	StatusCode int
+}
+
+func (e *rpcStatusError) Error() string {
+	return fmt.Sprintf("rpc status %d", e.StatusCode)
+}
+
+func postRPC(ctx context.Context, client *http.Client, url string, reqBody []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("content-type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, &rpcStatusError{StatusCode: resp.StatusCode}
+	}
+
+	var raw json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return nil, err
+	}
+	return raw, nil
+}
+
+func decodeRPCResponse(resp rpcResponse, out interface{}) error {
+	if resp.Error != nil {
+		return &rpcCallError{Code: resp.Error.Code, Message: resp.Error.Message}
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(resp.Result, out)
+}
@@ -0,0 +1,138 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// rpcCallError is a JSON-RPC error object returned by a provider.
+type rpcCallError struct {
+	Code    int
+	Message string
+}
+
+func (e *rpcCallError) Error() string {
+	return fmt.Sprintf("rpc error %d: %s", e.Code, e.Message)
+}
+
+// rpcErrorKind says how a failed call should be handled by the caller.
+type rpcErrorKind int
+
+const (
+	rpcErrorTransient         rpcErrorKind = iota // network failures, 5xx and unknown errors: retry later
+	rpcErrorTooLarge                              // range or response too large: split the request
+	rpcErrorRateLimited                           // back off and retry the same request
+	rpcErrorMethodUnsupported                     // the provider does not offer the method
+	rpcErrorPermanent                             // the request itself is wrong: retrying cannot help
+)
+
+func (k rpcErrorKind) String() string {
+	switch k {
+	case rpcErrorTooLarge:
+		return "too_large"
+	case rpcErrorRateLimited:
+		return "rate_limited"
+	case rpcErrorMethodUnsupported:
+		return "method_unsupported"
+	case rpcErrorPermanent:
+		return "permanent"
+	default:
+		return "transient"
+	}
+}
+
+// Providers disagree on codes, so "too large" and rate limits are also told
+// apart by message. Infura, for one, uses -32005 for both.
+var (
+	tooLargeMessages = []string{
+		"query returned more than",
+		"block range",
+		"range too large",
+		"range is too large",
+		"too many results",
+		"response size",
+		"response is too big",
+		"exceed max results",
+		"log response size exceeded",
+	}
+	rateLimitMessages = []string{
+		"rate limit",
+		"too many requests",
+		"request limit",
+		"exceeded its compute units",
+		"capacity exceeded",
+	}
+	unsupportedMessages = []string{
+		"method not found",
+		"does not exist",
+		"not supported",
+	}
+)
+
+// classifyRPCError sorts an error from rpcClient by how it should be handled.
+func classifyRPCError(err error) rpcErrorKind {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return rpcErrorPermanent
+	}
+
+	var statusErr *rpcStatusError
+	if errors.As(err, &statusErr) {
+		switch code := statusErr.StatusCode; {
+		case code == http.StatusRequestEntityTooLarge:
+			return rpcErrorTooLarge
+		case code == http.StatusTooManyRequests:
+			return rpcErrorRateLimited
+		case code == http.StatusRequestTimeout, code >= 500:
+			return rpcErrorTransient
+		default:
+			return rpcErrorPermanent
+		}
+	}
+
+	var callErr *rpcCallError
+	if !errors.As(err, &callErr) {
+		return rpcErrorTransient
+	}
+	message := strings.ToLower(callErr.Message)
+	switch {
+	case callErr.Code == -32029 || containsAny(message, rateLimitMessages):
+		return rpcErrorRateLimited
+	case containsAny(message, tooLargeMessages):
+		return rpcErrorTooLarge
+	case callErr.Code == -32005:
+		// "limit exceeded" without a hint is a result cap on most providers.
+		return rpcErrorTooLarge
+	case callErr.Code == -32601 || containsAny(message, unsupportedMessages):
+		return rpcErrorMethodUnsupported
+	case callErr.Code == -32600, callErr.Code == -32602, callErr.Code == 3:
+		return rpcErrorPermanent
+	default:
+		return rpcErrorTransient
+	}
+}
+
+func containsAny(message string, needles []string) bool {
+	for _, needle := range needles {
+		if strings.Contains(message, needle) {
+			return true
+		}
+	}
+	return false
+}
+
+func isPayloadTooLargeError(err error) bool {
+	return err != nil && classifyRPCError(err) == rpcErrorTooLarge
+}
+
+func isRateLimitedError(err error) bool {
+	return err != nil && classifyRPCError(err) == rpcErrorRateLimited
+}
+
+// isMethodUnsupportedError reports whether a provider rejected a call because
+// it does not offer the method.
+func isMethodUnsupportedError(err error) bool {
+	return err != nil && classifyRPCError(err) == rpcErrorMethodUnsupported
+}
@@ -0,0 +1,96 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestClassifyRPCError(t *testing.T) {
+	cases := []struct {
+		err  error
+		want rpcErrorKind
+	}{
+		{&rpcStatusError{StatusCode: http.StatusRequestEntityTooLarge}, rpcErrorTooLarge},
+		{&rpcCallError{Code: -32005, Message: "query returned more than 10000 results"}, rpcErrorTooLarge},
+		{&rpcCallError{Code: -32602, Message: "eth_getLogs block range too large, range: 5000, max: 2000"}, rpcErrorTooLarge},
+		{&rpcCallError{Code: -32000, Message: "Log response size exceeded. You can make eth_getLogs requests with up to a 2K block range"}, rpcErrorTooLarge},
+		{&rpcStatusError{StatusCode: http.StatusTooManyRequests}, rpcErrorRateLimited},
+		{&rpcCallError{Code: -32029, Message: "request rate exceeded"}, rpcErrorRateLimited},
+		{&rpcCallError{Code: -32005, Message: "daily request count exceeded, request rate limited"}, rpcErrorRateLimited},
+		{&rpcCallError{Code: -32601, Message: "the method trace_block does not exist/is not available"}, rpcErrorMethodUnsupported},
+		{&rpcCallError{Code: -32602, Message: "invalid argument 0: hex string has odd length"}, rpcErrorPermanent},
+		{&rpcStatusError{StatusCode: http.StatusUnauthorized}, rpcErrorPermanent},
+		{&rpcStatusError{StatusCode: http.StatusBadGateway}, rpcErrorTransient},
+		{fmt.Errorf("eth_getLogs: %w", context.Canceled), rpcErrorPermanent},
+		{fmt.Errorf("dial tcp: connection refused"), rpcErrorTransient},
+	}
+	for _, tc := range cases {
+		wrapped := fmt.Errorf("eth_getLogs: %w", tc.err)
+		if got := classifyRPCError(wrapped); got != tc.want {
+			t.Errorf("%v: got %s, want %s", tc.err, got, tc.want)
+		}
+	}
+}
+
+func TestEvmRpcSource_GetLogsSplitsOnResultCap(t *testing.T) {
+	node := newFakeEvmNode(110)
+	node.addTransfer(102, "0xtx1", 0, testDepositAddr, 1_000_000)
+	node.addTransfer(108, "0xtx2", 0, testDepositAddr, 2_000_000)
+	node.logsErr = func(from int64, to int64) *rpcError {
+		if to-from >= 4 {
+			return &rpcError{Code: -32005, Message: "query returned more than 10000 results"}
+		}
+		return nil
+	}
+	source := newTestEvmSource(node.serve(t).URL)
+
+	candidates, _, err := source.Poll(context.Background(), "100")
+	if err != nil {
+		t.Fatalf("unexpected poll error: %v", err)
+	}
+	if len(candidates) != 2 || candidates[0].TxHash != "0xtx1" || candidates[1].TxHash != "0xtx2" {
+		t.Fatalf("expected both transfers after splitting, got %+v", candidates)
+	}
+}
+
+func TestEvmRpcSource_GetLogsBacksOffOnRateLimit(t *testing.T) {
+	node := newFakeEvmNode(110)
+	node.addTransfer(105, "0xtx1", 0, testDepositAddr, 5_000_000)
+	limited := 2
+	node.logsErr = func(int64, int64) *rpcError {
+		if limited > 0 {
+			limited--
+			return &rpcError{Code: -32029, Message: "rate limit exceeded"}
+		}
+		return nil
+	}
+	source := newTestEvmSource(node.serve(t).URL)
+	source.RateLimitBackoff = time.Millisecond
+
+	candidates, _, err := source.Poll(context.Background(), "100")
+	if err != nil {
+		t.Fatalf("unexpected poll error: %v", err)
+	}
+	if len(candidates) != 1 || node.calls["eth_getLogs"] != 3 {
+		t.Fatalf("expected the call to succeed on the third attempt, got %+v after %d calls", candidates, node.calls["eth_getLogs"])
+	}
+}
+
+func TestEvmRpcSource_GetLogsFailsFastOnPermanentError(t *testing.T) {
+	node := newFakeEvmNode(110)
+	node.logsErr = func(int64, int64) *rpcError {
+		return &rpcError{Code: -32602, Message: "invalid argument 0: hex string has odd length"}
+	}
+	source := newTestEvmSource(node.serve(t).URL)
+	source.RateLimitBackoff = time.Millisecond
+
+	if _, _, err := source.Poll(context.Background(), "100"); err == nil {
+		t.Fatalf("expected a permanent error to fail the poll")
+	}
+	if node.calls["eth_getLogs"] != 1 {
+		t.Fatalf("expected no retries or splits, got %d eth_getLogs calls", node.calls["eth_getLogs"])
+	}
+}
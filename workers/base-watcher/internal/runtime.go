@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log/slog"
 	"strconv"
+	"sync"
 	"time"
 )
 
@@ -12,6 +13,53 @@ type CandidateSource interface {
 	Poll(ctx context.Context, cursor string) ([]FundingCandidate, string, error)
 }
 
+// WakingSource is implemented by push-mode sources. The runner polls as soon
+// as Wake fires instead of waiting for the next tick.
+type WakingSource interface {
+	Wake() <-chan struct{}
+}
+
+// ScanRange is a contiguous slice of a source's backlog, in the source's own
+// units (blocks for EVM).
+type ScanRange struct {
+	From int64
+	To   int64
+
+	// After is the committed cursor the range continues from. Only the first
+	// planned range carries it, so that range can check and extend what the
+	// cursor tracks.
+	After string
+}
+
+// BacklogSource is implemented by sources that can report how far a cursor
+// trails the chain and scan explicit ranges, which lets the runner catch up
+// back-to-back and with parallel range workers.
+type BacklogSource interface {
+	Backlog(ctx context.Context, cursor string) (int64, error)
+	PlanBacklog(ctx context.Context, cursor string, maxRanges int) ([]ScanRange, error)
+	PollRange(ctx context.Context, r ScanRange) ([]FundingCandidate, string, error)
+}
+
+// RangeCursorMerger is implemented by backlog sources whose range cursors only
+// track their own range. The runner folds the committed cursor into each later
+// range's cursor before saving it.
+type RangeCursorMerger interface {
+	MergeRangeCursor(committed string, next string) string
+}
+
+// TimestampSource is implemented by sources that can find the cursor at which
+// scanning reaches a point in time, which lets the runner start from a date.
+type TimestampSource interface {
+	CursorAt(ctx context.Context, at time.Time) (string, error)
+}
+
+// HoldingSource is implemented by sources that can step a cursor back to just
+// before a candidate, which keeps the saved checkpoint from passing candidates
+// still held for verification.
+type HoldingSource interface {
+	CursorBefore(cursor string, candidate FundingCandidate) (string, bool)
+}
+
 type CheckpointStore interface {
 	GetCursor(ctx context.Context) (string, error)
 	SaveCursor(ctx context.Context, cursor string) error
@@ -20,6 +68,9 @@ type CheckpointStore interface {
 type DedupeStore interface {
 	Seen(ctx context.Context, key string) (bool, error)
 	Mark(ctx context.Context, key string) error
+	// Clear forgets a key, so a reverted deposit that is mined again is
+	// published again.
+	Clear(ctx context.Context, key string) error
 }
 
 type Runner struct {
@@ -30,6 +81,89 @@ type Runner struct {
 	CheckpointStore CheckpointStore
 	DedupeStore     DedupeStore
 	Logger          *slog.Logger
+
+	// CatchUpThreshold is the backlog above which ranges are fetched by
+	// CatchUpWorkers concurrent workers. Any smaller backlog is still polled
+	// back-to-back without waiting for the ticker. Zero disables the workers.
+	CatchUpThreshold int64
+	CatchUpWorkers   int // concurrent range workers in catch-up mode (default: 4)
+
+	// Budget, when set, stretches PollInterval by its Slowdown and pauses
+	// back-to-back catch-up while RPC spending runs ahead of plan.
+	Budget *RPCBudget
+
+	// StartAt, when set, replaces the checkpoint on start with the source's
+	// cursor for that time, to backfill from a date. StartMarker records the
+	// StartAt last applied, so the checkpoint is only replaced once per value
+	// and restarts continue from where the watcher got to.
+	StartAt     time.Time
+	StartMarker CheckpointStore
+
+	// MaxHeld caps the held candidates retried for verification (default:
+	// 1000). Beyond it, new ones are parked: not retried until a slot frees
+	// up, but the checkpoint still stays before them. A candidate
+	// still unverified after HoldAlertAfter stays held, but every failed retry
+	// is logged as an error (default: 24h).
+	MaxHeld        int
+	HoldAlertAfter time.Duration
+}
+
+// heldCandidate is a candidate that failed verification. It is retried with a
+// growing delay until it verifies or disappears; the checkpoint never moves
+// past it.
+type heldCandidate struct {
+	candidate FundingCandidate
+	since     time.Time
+	attempts  int
+	retryAt   time.Time
+	parked    bool // held beyond MaxHeld, so not retried yet
+}
+
+const maxHeldRetryDelay = 5 * time.Minute
+
+func (r Runner) effectiveMaxHeld() int {
+	if r.MaxHeld > 0 {
+		return r.MaxHeld
+	}
+	return 1000
+}
+
+func (r Runner) effectiveHoldAlertAfter() time.Duration {
+	if r.HoldAlertAfter > 0 {
+		return r.HoldAlertAfter
+	}
+	return 24 * time.Hour
+}
+
+// heldCheckpoint keeps the cursor the runner has reached in memory and saves
+// it stepped back before every held candidate, so a restart scans those
+// candidates again instead of losing them.
+type heldCheckpoint struct {
+	CheckpointStore
+	source  HoldingSource
+	held    map[string]heldCandidate
+	reached string
+}
+
+func (c *heldCheckpoint) GetCursor(ctx context.Context) (string, error) {
+	if c.reached != "" {
+		return c.reached, nil
+	}
+	return c.CheckpointStore.GetCursor(ctx)
+}
+
+func (c *heldCheckpoint) SaveCursor(ctx context.Context, cursor string) error {
+	saved := cursor
+	for _, entry := range c.held {
+		if stepped, ok := c.source.CursorBefore(saved, entry.candidate); ok {
+			saved = stepped
+		}
+	}
+	if err := c.CheckpointStore.SaveCursor(ctx, saved); err != nil {
+		return err
+	}
+	c.reached = cursor
+	return nil
 }
 
 func (r Runner) Run(ctx context.Context) error {
@@ -43,6 +177,12 @@ func (r Runner) Run(ctx context.Context) error {
 		r.PollInterval = 5 * time.Second
 	}
 
+	if !r.StartAt.IsZero() {
+		if err := r.rewindToStart(ctx); err != nil {
+			return err
+		}
+	}
+
 	cursor, err := r.CheckpointStore.GetCursor(ctx)
 	if err != nil {
 		return fmt.Errorf("load cursor: %w", err)
@@ -54,63 +194,304 @@ func (r Runner) Run(ctx context.Context) error {
 		"pollInterval", r.PollInterval.String(),
 	)
 
-	if err := r.runOnce(ctx, cursor); err != nil {
+	// Candidates that failed verification are retried until they verify,
+	// instead of being dropped when the cursor moves on.
+	held := make(map[string]heldCandidate)
+	if source, ok := r.Source.(HoldingSource); ok {
+		r.CheckpointStore = &heldCheckpoint{CheckpointStore: r.CheckpointStore, source: source, held: held}
+	}
+
+	if err := r.runOnce(ctx, cursor, held); err != nil {
 		r.Logger.Error("initial poll failed",
 			"watcher", r.Name,
 			"error", err,
 		)
+	} else if nextCursor, err := r.CheckpointStore.GetCursor(ctx); err == nil {
+		cursor = r.catchUp(ctx, nextCursor, held)
 	}
 
-	ticker := time.NewTicker(r.PollInterval)
+	interval := r.pollInterval()
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
+	var wake <-chan struct{}
+	if waking, ok := r.Source.(WakingSource); ok {
+		wake = waking.Wake()
+	}
+
 	for {
 		select {
 		case <-ctx.Done():
 			r.Logger.Info("watcher shutting down", "watcher", r.Name)
 			return nil
 		case <-ticker.C:
-			if err := r.runOnce(ctx, cursor); err != nil {
-				r.Logger.Error("poll failed",
-					"watcher", r.Name,
-					"cursor", cursor,
-					"error", err,
-				)
-				continue
-			}
+		case <-wake:
+		}
 
-			nextCursor, err := r.CheckpointStore.GetCursor(ctx)
-			if err == nil {
-				cursor = nextCursor
-			}
+		if err := r.runOnce(ctx, cursor, held); err != nil {
+			r.Logger.Error("poll failed",
+				"watcher", r.Name,
+				"cursor", cursor,
+				"error", err,
+			)
+			continue
+		}
+
+		nextCursor, err := r.CheckpointStore.GetCursor(ctx)
+		if err == nil {
+			cursor = r.catchUp(ctx, nextCursor, held)
+		}
+
+		if next := r.pollInterval(); next != interval {
+			r.Logger.Info("poll interval changed by rpc budget",
+				"watcher", r.Name,
+				"pollInterval", next.String(),
+			)
+			interval = next
+			ticker.Reset(interval)
 		}
 	}
 }
 
-func (r Runner) runOnce(ctx context.Context, currentCursor string) error {
-	candidates, nextCursor, err := r.Source.Poll(ctx, currentCursor)
+// pollInterval is PollInterval stretched by the RPC budget's slowdown.
+func (r Runner) pollInterval() time.Duration {
+	return time.Duration(float64(r.PollInterval) * r.Budget.Slowdown())
+}
+
+// rewindToStart saves the source's cursor for StartAt as the checkpoint,
+// unless StartMarker shows this StartAt was already applied.
+func (r Runner) rewindToStart(ctx context.Context) error {
+	source, ok := r.Source.(TimestampSource)
+	if !ok {
+		return fmt.Errorf("source cannot start from a timestamp")
+	}
+	if r.StartMarker == nil {
+		return fmt.Errorf("start time needs a start marker store")
+	}
+	startAt := r.StartAt.UTC().Format(time.RFC3339)
+	applied, err := r.StartMarker.GetCursor(ctx)
+	if err != nil {
+		return fmt.Errorf("load start marker: %w", err)
+	}
+	if applied == startAt {
+		r.Logger.Info("start time already applied, keeping checkpoint",
+			"watcher", r.Name,
+			"startAt", startAt,
+		)
+		return nil
+	}
+
+	cursor, err := source.CursorAt(ctx, r.StartAt)
+	if err != nil {
+		return fmt.Errorf("find cursor for %s: %w", r.StartAt.Format(time.RFC3339), err)
+	}
+	if err := r.CheckpointStore.SaveCursor(ctx, cursor); err != nil {
+		return fmt.Errorf("save start cursor: %w", err)
+	}
+	if err := r.StartMarker.SaveCursor(ctx, startAt); err != nil {
+		return fmt.Errorf("save start marker: %w", err)
+	}
+	r.Logger.Warn("checkpoint rewound to start time",
+		"watcher", r.Name,
+		"startAt", r.StartAt.Format(time.RFC3339),
+		"cursor", cursor,
+	)
+	return nil
+}
+
+func (r Runner) effectiveCatchUpWorkers() int {
+	if r.CatchUpWorkers > 0 {
+		return r.CatchUpWorkers
+	}
+	return 4
+}
+
+// catchUp keeps polling while the source reports a backlog, so a watcher
+// that was down does not advance only one range per tick. It returns the last
+// committed cursor.
+func (r Runner) catchUp(ctx context.Context, cursor string, held map[string]heldCandidate) string {
+	source, ok := r.Source.(BacklogSource)
+	if !ok {
+		return cursor
+	}
+
+	for ctx.Err() == nil {
+		backlog, err := source.Backlog(ctx, cursor)
+		if err != nil {
+			r.Logger.Warn("backlog check failed", "watcher", r.Name, "error", err)
+			return cursor
+		}
+		if backlog <= 0 {
+			return cursor
+		}
+		if slowdown := r.Budget.Slowdown(); slowdown > 1 {
+			r.Logger.Warn("rpc budget running low, leaving backlog to the ticker",
+				"watcher", r.Name,
+				"backlog", backlog,
+				"slowdown", slowdown,
+			)
+			return cursor
+		}
+
+		parallel := r.CatchUpThreshold > 0 && backlog >= r.CatchUpThreshold
+		r.Logger.Info("catching up",
+			"watcher", r.Name,
+			"cursor", cursor,
+			"backlog", backlog,
+			"parallel", parallel,
+		)
+
+		if parallel {
+			err = r.runRanges(ctx, source, cursor, held)
+		} else {
+			err = r.runOnce(ctx, cursor, held)
+		}
+		if err != nil {
+			r.Logger.Error("catch-up poll failed",
+				"watcher", r.Name,
+				"cursor", cursor,
+				"error", err,
+			)
+		}
+
+		nextCursor, loadErr := r.CheckpointStore.GetCursor(ctx)
+		if loadErr != nil {
+			return cursor
+		}
+		if err != nil || nextCursor == cursor {
+			// No progress; leave the rest to the next tick.
+			return nextCursor
+		}
+		cursor = nextCursor
+	}
+	return cursor
+}
+
+type rangeResult struct {
+	candidates []FundingCandidate
+	nextCursor string
+	err        error
+}
+
+// runRanges fetches the next backlog ranges concurrently, then processes and
+// checkpoints them strictly in order. A failed range stops the batch, so the
+// checkpoint never moves past a range that was not fully processed.
+func (r Runner) runRanges(ctx context.Context, source BacklogSource, cursor string, held map[string]heldCandidate) error {
+	ranges, err := source.PlanBacklog(ctx, cursor, r.effectiveCatchUpWorkers())
+	if err != nil {
+		return fmt.Errorf("plan backlog: %w", err)
+	}
+
+	results := make([]rangeResult, len(ranges))
+	var wg sync.WaitGroup
+	for i, scanRange := range ranges {
+		wg.Add(1)
+		go func(i int, scanRange ScanRange) {
+			defer wg.Done()
+			candidates, nextCursor, err := source.PollRange(ctx, scanRange)
+			results[i] = rangeResult{candidates: candidates, nextCursor: nextCursor, err: err}
+		}(i, scanRange)
+	}
+	wg.Wait()
+
+	merger, _ := source.(RangeCursorMerger)
+	current := cursor
+	for i, result := range results {
+		if result.err != nil {
+			return fmt.Errorf("poll range %d-%d: %w", ranges[i].From, ranges[i].To, result.err)
+		}
+		nextCursor := result.nextCursor
+		if merger != nil && i > 0 {
+			nextCursor = merger.MergeRangeCursor(current, nextCursor)
+		}
+		if err := r.processPolled(ctx, current, result.candidates, nextCursor, held); err != nil {
+			return err
+		}
+		current = nextCursor
+	}
+	return nil
+}
+
+func (r Runner) runOnce(ctx context.Context, currentCursor string, held map[string]heldCandidate) error {
+	polled, nextCursor, err := r.Source.Poll(ctx, currentCursor)
 	if err != nil {
 		return fmt.Errorf("poll source: %w", err)
 	}
+	return r.processPolled(ctx, currentCursor, polled, nextCursor, held)
+}
+
+// processPolled hands polled and held candidates to the watcher and saves
+// nextCursor once all of them are handled.
+func (r Runner) processPolled(ctx context.Context, currentCursor string, polled []FundingCandidate, nextCursor string, held map[string]heldCandidate) error {
+	now := time.Now()
+	active := activeHeld(held)
+	for eventKey, entry := range held {
+		if entry.parked && active < r.effectiveMaxHeld() {
+			entry.parked, entry.retryAt = false, time.Time{}
+			held[eventKey] = entry
+			active++
+		}
+	}
+
+	candidates := make([]FundingCandidate, 0, len(held)+len(polled))
+	for _, entry := range held {
+		if entry.parked || now.Before(entry.retryAt) {
+			continue
+		}
+		candidates = append(candidates, entry.candidate)
+	}
+	for _, candidate := range polled {
+		if _, ok := held[buildEventKey(candidate)]; ok {
+			continue
+		}
+		candidates = append(candidates, candidate)
+	}
 
 	r.Logger.Info("poll complete",
 		"watcher", r.Name,
 		"cursor", currentCursor,
 		"nextCursor", nextCursor,
-		"candidateCount", len(candidates),
+		"candidateCount", len(polled),
+		"heldCount", len(held),
 	)
 
 	confirmedCount := 0
 	skippedCount := 0
+	revertedCount := 0
+	unverifiedCount := 0
+	rejectedCount := 0
+	filteredCount := 0
+	internalCount := 0
+	unroutableCount := 0
 
 	for _, candidate := range candidates {
 		eventKey := buildEventKey(candidate)
 
-		seen, err := r.DedupeStore.Seen(ctx, eventKey)
+		// A revert is deduplicated on the key of the deposit it reverts: it is
+		// only published while that deposit is marked as confirmed.
+		dedupeKey := eventKey
+		if candidate.Reverted {
+			// A held deposit that was reorged out no longer needs verifying.
+			original := candidate
+			original.Reverted = false
+			dedupeKey = buildEventKey(original)
+			delete(held, dedupeKey)
+		}
+
+		seen, err := r.DedupeStore.Seen(ctx, dedupeKey)
 		if err != nil {
 			return fmt.Errorf("check dedupe: %w", err)
 		}
-		if seen {
+		if candidate.Reverted && !seen {
+			skippedCount++
+			r.Logger.Debug("reverted candidate was never confirmed, skipping",
+				"eventKey", eventKey,
+				"txHash", candidate.TxHash,
+			)
+			continue
+		}
+		if !candidate.Reverted && seen {
+			delete(held, eventKey)
 			skippedCount++
 			r.Logger.Debug("candidate already seen, skipping",
 				"eventKey", eventKey,
@@ -139,7 +520,8 @@ func (r Runner) runOnce(ctx context.Context, currentCursor string) error {
 			"txHash", candidate.TxHash,
 			"depositAddress", candidate.DepositAddress,
 			"resolvedDepositAddress", resolvedDepositAddress,
-			"amountUSD", candidate.AmountUSD,
+			"amount", candidate.Amount,
+			"amountBaseUnits", candidate.AmountBaseUnits,
 			"confirmations", candidate.Confirmations,
 			"result", string(result),
 		)
@@ -168,12 +550,138 @@ func (r Runner) runOnce(ctx context.Context, currentCursor string) error {
 			)
 		}
 
+		if result == ProcessReverted {
+			r.Logger.Warn("route resolution outcome",
+				"watcher", r.Name,
+				"chain", candidate.Chain,
+				"transferId", resolvedTransferID,
+				"txHash", candidate.TxHash,
+				"depositAddress", candidate.DepositAddress,
+				"resolvedDepositAddress", resolvedDepositAddress,
+				"outcome", "reverted",
+			)
+		}
+
+		if result == ProcessRevertUndelivered {
+			r.Logger.Error("route resolution outcome",
+				"watcher", r.Name,
+				"chain", candidate.Chain,
+				"transferId", resolvedTransferID,
+				"txHash", candidate.TxHash,
+				"depositAddress", candidate.DepositAddress,
+				"resolvedDepositAddress", resolvedDepositAddress,
+				"outcome", "revert_undelivered",
+			)
+		}
+
+		if result == ProcessUnverified {
+			unverifiedCount++
+			entry, ok := held[eventKey]
+			if !ok {
+				entry.since = now
+				entry.parked = activeHeld(held) >= r.effectiveMaxHeld()
+			}
+			entry.candidate = candidate
+			entry.attempts++
+			entry.retryAt = now.Add(heldRetryDelay(r.PollInterval, entry.attempts))
+			held[eventKey] = entry
+			level, outcome := slog.LevelWarn, "unverified"
+			if now.Sub(entry.since) > r.effectiveHoldAlertAfter() {
+				// Still held, so the deposit is not lost, but it needs a look.
+				level, outcome = slog.LevelError, "unverified_overdue"
+			}
+			r.Logger.Log(ctx, level, "route resolution outcome",
+				"watcher", r.Name,
+				"chain", candidate.Chain,
+				"transferId", resolvedTransferID,
+				"txHash", candidate.TxHash,
+				"depositAddress", candidate.DepositAddress,
+				"resolvedDepositAddress", resolvedDepositAddress,
+				"amount", candidate.Amount,
+				"heldSince", entry.since.Format(time.RFC3339),
+				"parked", entry.parked,
+				"outcome", outcome,
+			)
+		} else {
+			delete(held, eventKey)
+		}
+
+		if result == ProcessRejected {
+			r.Logger.Warn("route resolution outcome",
+				"watcher", r.Name,
+				"chain", candidate.Chain,
+				"transferId", resolvedTransferID,
+				"txHash", candidate.TxHash,
+				"depositAddress", candidate.DepositAddress,
+				"resolvedDepositAddress", resolvedDepositAddress,
+				"outcome", "rejected",
+			)
+		}
+
+		if result == ProcessFiltered {
+			filteredCount++
+			r.Logger.Info("route resolution outcome",
+				"watcher", r.Name,
+				"chain", candidate.Chain,
+				"txHash", candidate.TxHash,
+				"depositAddress", candidate.DepositAddress,
+				"payerAddress", candidate.Metadata["payerAddress"],
+				"amount", candidate.Amount,
+				"outcome", "filtered",
+				"filterReason", candidate.FilterReason,
+			)
+		}
+
+		if result == ProcessInternal {
+			internalCount++
+			r.Logger.Info("route resolution outcome",
+				"watcher", r.Name,
+				"chain", candidate.Chain,
+				"txHash", candidate.TxHash,
+				"depositAddress", candidate.DepositAddress,
+				"payerAddress", candidate.Metadata["payerAddress"],
+				"amount", candidate.Amount,
+				"outcome", "internal_movement",
+			)
+		}
+
+		if result == ProcessUnroutable {
+			unroutableCount++
+			r.Logger.Warn("route resolution outcome",
+				"watcher", r.Name,
+				"chain", candidate.Chain,
+				"token", candidate.Token,
+				"txHash", candidate.TxHash,
+				"depositAddress", candidate.DepositAddress,
+				"payerAddress", candidate.Metadata["payerAddress"],
+				"amount", candidate.Amount,
+				"outcome", "unroutable",
+			)
+		}
+
+		if result == ProcessReverted {
+			revertedCount++
+		}
+
+		if result == ProcessRejected {
+			rejectedCount++
+		}
+
 		if result == ProcessConfirmed {
 			confirmedCount++
+		}
+
+		if result == ProcessConfirmed {
 			if err := r.DedupeStore.Mark(ctx, eventKey); err != nil {
 				return fmt.Errorf("mark dedupe: %w", err)
 			}
 		}
+		// An undelivered revert leaves the deposit credited, so its key stays.
+		if result == ProcessReverted {
+			if err := r.DedupeStore.Clear(ctx, dedupeKey); err != nil {
+				return fmt.Errorf("clear dedupe: %w", err)
+			}
+		}
 	}
 
 	if len(candidates) > 0 {
@@ -182,7 +690,13 @@ func (r Runner) runOnce(ctx context.Context, currentCursor string) error {
 			"total", len(candidates),
 			"confirmed", confirmedCount,
 			"skipped", skippedCount,
-			"unresolved", len(candidates)-confirmedCount-skippedCount,
+			"reverted", revertedCount,
+			"unverified", unverifiedCount,
+			"rejected", rejectedCount,
+			"filtered", filteredCount,
+			"internal", internalCount,
+			"unroutable", unroutableCount,
+			"unresolved", len(candidates)-confirmedCount-skippedCount-revertedCount-unverifiedCount-rejectedCount-filteredCount-internalCount-unroutableCount,
 		)
 	}
 
@@ -193,6 +707,33 @@ func (r Runner) runOnce(ctx context.Context, currentCursor string) error {
 	return nil
 }
 
+// activeHeld counts the held candidates that are retried, not parked.
+func activeHeld(held map[string]heldCandidate) int {
+	active := 0
+	for _, entry := range held {
+		if !entry.parked {
+			active++
+		}
+	}
+	return active
+}
+
+// heldRetryDelay doubles the poll interval with every failed attempt, up to
+// maxHeldRetryDelay, so held candidates do not query every provider each cycle.
+func heldRetryDelay(pollInterval time.Duration, attempts int) time.Duration {
+	delay := pollInterval
+	for i := 1; i < attempts && delay < maxHeldRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxHeldRetryDelay {
+		delay = maxHeldRetryDelay
+	}
+	return delay
+}
+
 func buildEventKey(candidate FundingCandidate) string {
+	if candidate.Reverted {
+		return "reverted:" + candidate.Chain + ":" + candidate.TxHash + ":" + strconv.Itoa(candidate.LogIndex)
+	}
 	return candidate.Chain + ":" + candidate.TxHash + ":" + strconv.Itoa(candidate.LogIndex)
 }
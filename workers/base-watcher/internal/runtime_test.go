@@ -3,8 +3,12 @@ package internal
 import (
 	"context"
 	"errors"
+	"fmt"
 	"io"
 	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -51,6 +55,11 @@ func (d *dedupeStub) Mark(_ context.Context, key string) error {
 	return nil
 }
 
+func (d *dedupeStub) Clear(_ context.Context, key string) error {
+	delete(d.seen, key)
+	return nil
+}
+
 func TestRunner_ProcessesAndMarksDedupe(t *testing.T) {
 	pub := &publisherStub{}
 	checkpoint := &checkpointStub{cursor: "10"}
@@ -125,3 +134,413 @@ func TestRunner_PropagatesSourceError(t *testing.T) {
 		t.Fatalf("runner should continue until context cancel, got %v", err)
 	}
 }
+
+type flakyVerifier struct {
+	calls int
+}
+
+func (v *flakyVerifier) VerifyCandidate(_ context.Context, _ FundingCandidate) (CandidateVerification, error) {
+	v.calls++
+	return CandidateVerification{Verified: v.calls > 1}, nil
+}
+
+func TestRunner_RetriesHeldCandidatesAfterCursorMoves(t *testing.T) {
+	pub := &publisherStub{}
+	checkpoint := &checkpointStub{cursor: "10"}
+	dedupe := &dedupeStub{seen: map[string]bool{}}
+	verifier := &flakyVerifier{}
+	held := map[string]heldCandidate{}
+
+	runner := Runner{
+		Name: "base-watcher-test",
+		Source: sourceStub{
+			candidates: []FundingCandidate{{
+				Chain: "base", Token: "USDC", TxHash: "0xheld", LogIndex: 2, TransferID: "tr_held",
+				DepositAddress: "dep_1", AmountUSD: 10, Confirmations: 10,
+				ConfirmedAt: time.Date(2026, 2, 13, 12, 0, 0, 0, time.UTC),
+			}},
+			nextCursor: "20",
+		},
+		Watcher:         Watcher{Chain: "base", MinConfirmations: 1, Publisher: pub, Verifier: verifier},
+		CheckpointStore: checkpoint,
+		DedupeStore:     dedupe,
+		Logger:          slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+
+	if err := runner.runOnce(context.Background(), "10", held); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(held) != 1 || len(pub.calledWith) != 0 || checkpoint.cursor != "20" {
+		t.Fatalf("expected candidate held while cursor advances, held=%d published=%d", len(held), len(pub.calledWith))
+	}
+
+	runner.Source = sourceStub{nextCursor: "30"}
+	if err := runner.runOnce(context.Background(), "20", held); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(held) != 0 || len(pub.calledWith) != 1 {
+		t.Fatalf("expected held candidate to be published on retry, held=%d published=%d", len(held), len(pub.calledWith))
+	}
+	if !dedupe.seen["base:0xheld:2"] {
+		t.Fatalf("expected dedupe mark after verification")
+	}
+}
+
+func TestRunner_RevertsOnlyConfirmedDepositsAndForgetsThem(t *testing.T) {
+	pub := &publisherStub{}
+	dedupe := &dedupeStub{seen: map[string]bool{}}
+	deposit := FundingCandidate{
+		Chain: "base", Token: "USDC", TxHash: "0xdep", LogIndex: 1, TransferID: "tr_1",
+		DepositAddress: "dep_1", Confirmations: 10, ConfirmedAt: time.Date(2026, 2, 13, 12, 0, 0, 0, time.UTC),
+	}
+	reverted := deposit
+	reverted.Reverted = true
+
+	runner := Runner{
+		Name:            "base-watcher-test",
+		Watcher:         Watcher{Chain: "base", MinConfirmations: 1, Publisher: pub},
+		CheckpointStore: &checkpointStub{},
+		DedupeStore:     dedupe,
+		Logger:          slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	poll := func(candidates ...FundingCandidate) {
+		t.Helper()
+		runner.Source = sourceStub{candidates: candidates, nextCursor: "1"}
+		if err := runner.runOnce(context.Background(), "0", map[string]heldCandidate{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	// A deposit reorged out before it was confirmed has nothing to revert.
+	poll(reverted)
+	if len(pub.revertedWith) != 0 {
+		t.Fatalf("expected no revert for an unconfirmed deposit, got %+v", pub.revertedWith)
+	}
+
+	poll(deposit)
+	poll(reverted)
+	if len(pub.revertedWith) != 1 || dedupe.seen["base:0xdep:1"] {
+		t.Fatalf("expected one revert and a cleared key, got %d reverts, seen=%v", len(pub.revertedWith), dedupe.seen)
+	}
+
+	// Mined again, the deposit is published again.
+	poll(deposit)
+	if len(pub.calledWith) != 2 || !dedupe.seen["base:0xdep:1"] {
+		t.Fatalf("expected the re-mined deposit to be published, got %d", len(pub.calledWith))
+	}
+
+	// Without a reverted endpoint the credit stands, so the key is kept.
+	runner.Watcher.Publisher = CallbackPublisher{Endpoint: "http://core-api.invalid"}
+	poll(reverted)
+	if !dedupe.seen["base:0xdep:1"] {
+		t.Fatalf("expected an undelivered revert to keep the confirmed key")
+	}
+}
+
+// holdingSourceStub uses block numbers as cursors.
+type holdingSourceStub struct {
+	sourceStub
+}
+
+func (s holdingSourceStub) CursorBefore(cursor string, candidate FundingCandidate) (string, bool) {
+	block, _ := strconv.ParseInt(cursor, 10, 64)
+	held, ok := candidate.Metadata["blockNumber"].(int64)
+	if !ok || held > block {
+		return cursor, false
+	}
+	return strconv.FormatInt(held-1, 10), true
+}
+
+func TestRunner_SavesCheckpointBeforeHeldCandidatesAndBoundsThem(t *testing.T) {
+	pub := &publisherStub{}
+	checkpoint := &checkpointStub{cursor: "10"}
+	held := map[string]heldCandidate{}
+	candidate := func(txHash string, block int64) FundingCandidate {
+		return FundingCandidate{
+			Chain: "base", Token: "USDC", TxHash: txHash, TransferID: "tr_held", DepositAddress: "dep_1",
+			Confirmations: 10, ConfirmedAt: time.Date(2026, 2, 13, 12, 0, 0, 0, time.UTC),
+			Metadata: map[string]any{"blockNumber": block},
+		}
+	}
+	verifier := &flakyVerifier{}
+
+	runner := Runner{
+		Name:         "base-watcher-test",
+		PollInterval: time.Hour,
+		Source:       holdingSourceStub{sourceStub{candidates: []FundingCandidate{candidate("0xheld", 15)}, nextCursor: "20"}},
+		Watcher:      Watcher{Chain: "base", MinConfirmations: 1, Publisher: pub, Verifier: verifier},
+		DedupeStore:  &dedupeStub{seen: map[string]bool{}},
+		Logger:       slog.New(slog.NewTextHandler(io.Discard, nil)),
+		MaxHeld:      1,
+	}
+	store := &heldCheckpoint{CheckpointStore: checkpoint, source: runner.Source.(HoldingSource), held: held}
+	runner.CheckpointStore = store
+
+	if err := runner.runOnce(context.Background(), "10", held); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if reached, _ := store.GetCursor(context.Background()); checkpoint.cursor != "14" || reached != "20" {
+		t.Fatalf("expected checkpoint 14 before the held block and reached 20, got %s and %s", checkpoint.cursor, reached)
+	}
+
+	// The held candidate waits for its retry delay instead of being verified
+	// again on the next cycle.
+	runner.Source = holdingSourceStub{sourceStub{nextCursor: "30"}}
+	if err := runner.runOnce(context.Background(), "20", held); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if verifier.calls != 1 || len(held) != 1 || checkpoint.cursor != "14" {
+		t.Fatalf("expected no retry before the delay, got %d verifications and checkpoint %s", verifier.calls, checkpoint.cursor)
+	}
+
+	// Beyond the limit a new unverified candidate is parked: not retried, but
+	// the checkpoint stays before it while later deposits are still published.
+	runner.Watcher.Verifier = &flakyVerifier{calls: -1}
+	later := candidate("0xlater", 38)
+	runner.Source = holdingSourceStub{sourceStub{candidates: []FundingCandidate{candidate("0xmore", 35)}, nextCursor: "40"}}
+	if err := runner.runOnce(context.Background(), "30", held); err != nil {
+		t.Fatalf("expected the held limit not to fail the poll, got %v", err)
+	}
+	if len(held) != 2 || !held[buildEventKey(candidate("0xmore", 35))].parked || checkpoint.cursor != "14" {
+		t.Fatalf("expected the new candidate parked behind the checkpoint, got %+v and %s", held, checkpoint.cursor)
+	}
+	runner.Watcher.Verifier = verifiesOnly{txHash: "0xlater"}
+	runner.Source = holdingSourceStub{sourceStub{candidates: []FundingCandidate{later}, nextCursor: "50"}}
+	if err := runner.runOnce(context.Background(), "40", held); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if reached, _ := store.GetCursor(context.Background()); reached != "50" || len(pub.calledWith) != 1 || pub.calledWith[0].TxHash != "0xlater" {
+		t.Fatalf("expected later deposits published and the cursor at 50, got %s and %+v", reached, pub.calledWith)
+	}
+
+	// Once a slot frees up the parked candidate is retried.
+	delete(held, buildEventKey(candidate("0xheld", 15)))
+	runner.Watcher.Verifier = verifiesOnly{txHash: "0xmore"}
+	runner.Source = holdingSourceStub{sourceStub{nextCursor: "60"}}
+	if err := runner.runOnce(context.Background(), "50", held); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(held) != 0 || len(pub.calledWith) != 2 || checkpoint.cursor != "60" {
+		t.Fatalf("expected the parked candidate published and the checkpoint released, got %d held and %s", len(held), checkpoint.cursor)
+	}
+}
+
+// verifiesOnly verifies the candidate with txHash and nothing else.
+type verifiesOnly struct {
+	txHash string
+}
+
+func (v verifiesOnly) VerifyCandidate(_ context.Context, c FundingCandidate) (CandidateVerification, error) {
+	return CandidateVerification{Verified: c.TxHash == v.txHash}, nil
+}
+
+func TestRunner_KeepsOverdueHeldCandidatesAndAlerts(t *testing.T) {
+	checkpoint := &checkpointStub{cursor: "10"}
+	candidate := FundingCandidate{
+		Chain: "base", Token: "USDC", TxHash: "0xstuck", TransferID: "tr_held", DepositAddress: "dep_1",
+		Confirmations: 10, ConfirmedAt: time.Date(2026, 2, 13, 12, 0, 0, 0, time.UTC),
+		Metadata: map[string]any{"blockNumber": int64(15)},
+	}
+	held := map[string]heldCandidate{
+		buildEventKey(candidate): {candidate: candidate, since: time.Now().Add(-48 * time.Hour), attempts: 20},
+	}
+	var logs strings.Builder
+	runner := Runner{
+		Name:           "base-watcher-test",
+		PollInterval:   time.Hour,
+		Source:         holdingSourceStub{sourceStub{nextCursor: "20"}},
+		Watcher:        Watcher{Chain: "base", MinConfirmations: 1, Publisher: &publisherStub{}, Verifier: &flakyVerifier{calls: -1}},
+		DedupeStore:    &dedupeStub{seen: map[string]bool{}},
+		Logger:         slog.New(slog.NewTextHandler(&logs, nil)),
+		HoldAlertAfter: 24 * time.Hour,
+	}
+	runner.CheckpointStore = &heldCheckpoint{CheckpointStore: checkpoint, source: runner.Source.(HoldingSource), held: held}
+
+	if err := runner.runOnce(context.Background(), "10", held); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(held) != 1 || checkpoint.cursor != "14" {
+		t.Fatalf("expected the overdue candidate kept and the checkpoint before it, got %d held and %s", len(held), checkpoint.cursor)
+	}
+	if !strings.Contains(logs.String(), "level=ERROR") || !strings.Contains(logs.String(), "outcome=unverified_overdue") {
+		t.Fatalf("expected an error alert for the overdue candidate, got %s", logs.String())
+	}
+}
+
+// backlogSourceStub is a chain of numbered blocks; every block holds one
+// deposit and the cursor is the last scanned block.
+type backlogSourceStub struct {
+	mu        sync.Mutex
+	head      int64
+	span      int64
+	failFrom  int64
+	rangeHits int
+}
+
+func (s *backlogSourceStub) deposits(from, to int64) []FundingCandidate {
+	out := make([]FundingCandidate, 0)
+	for block := from; block <= to; block++ {
+		out = append(out, FundingCandidate{
+			Chain: "base", Token: "USDC", TxHash: fmt.Sprintf("0x%d", block), DepositAddress: "dep_1",
+			AmountUSD: 1, Confirmations: 10, ConfirmedAt: time.Date(2026, 2, 13, 12, 0, 0, 0, time.UTC),
+		})
+	}
+	return out
+}
+
+func (s *backlogSourceStub) Poll(_ context.Context, cursor string) ([]FundingCandidate, string, error) {
+	from, _ := strconv.ParseInt(cursor, 10, 64)
+	to := from + s.span
+	if to > s.head {
+		to = s.head
+	}
+	return s.deposits(from+1, to), strconv.FormatInt(to, 10), nil
+}
+
+func (s *backlogSourceStub) Backlog(_ context.Context, cursor string) (int64, error) {
+	block, _ := strconv.ParseInt(cursor, 10, 64)
+	return s.head - block, nil
+}
+
+func (s *backlogSourceStub) PlanBacklog(_ context.Context, cursor string, maxRanges int) ([]ScanRange, error) {
+	block, _ := strconv.ParseInt(cursor, 10, 64)
+	ranges := make([]ScanRange, 0)
+	for from := block + 1; from <= s.head && len(ranges) < maxRanges; from += s.span {
+		to := from + s.span - 1
+		if to > s.head {
+			to = s.head
+		}
+		ranges = append(ranges, ScanRange{From: from, To: to})
+	}
+	return ranges, nil
+}
+
+func (s *backlogSourceStub) PollRange(_ context.Context, r ScanRange) ([]FundingCandidate, string, error) {
+	s.mu.Lock()
+	s.rangeHits++
+	s.mu.Unlock()
+	if s.failFrom != 0 && r.From == s.failFrom {
+		return nil, "", errors.New("range unavailable")
+	}
+	return s.deposits(r.From, r.To), strconv.FormatInt(r.To, 10), nil
+}
+
+type recordingCheckpoint struct {
+	checkpointStub
+	saved []string
+}
+
+func (c *recordingCheckpoint) SaveCursor(ctx context.Context, cursor string) error {
+	c.saved = append(c.saved, cursor)
+	return c.checkpointStub.SaveCursor(ctx, cursor)
+}
+
+func newBacklogRunner(source *backlogSourceStub, checkpoint CheckpointStore, pub *publisherStub) Runner {
+	return Runner{
+		Name:             "base-watcher-test",
+		Source:           source,
+		CheckpointStore:  checkpoint,
+		DedupeStore:      &dedupeStub{seen: map[string]bool{}},
+		Logger:           slog.New(slog.NewTextHandler(io.Discard, nil)),
+		CatchUpThreshold: 6,
+		CatchUpWorkers:   3,
+		Watcher: Watcher{
+			Chain:            "base",
+			MinConfirmations: 1,
+			Resolver:         resolverStub{found: true, match: RouteMatch{TransferID: "tr_1"}},
+			Publisher:        pub,
+		},
+	}
+}
+
+func TestRunner_CatchUpUsesParallelRangesAndCommitsInOrder(t *testing.T) {
+	source := &backlogSourceStub{head: 20, span: 2}
+	checkpoint := &recordingCheckpoint{checkpointStub: checkpointStub{cursor: "0"}}
+	pub := &publisherStub{}
+	runner := newBacklogRunner(source, checkpoint, pub)
+
+	cursor := runner.catchUp(context.Background(), "0", map[string]heldCandidate{})
+
+	if cursor != "20" {
+		t.Fatalf("expected to catch up to head, got cursor %s", cursor)
+	}
+	if len(pub.calledWith) != 20 {
+		t.Fatalf("expected every backlog deposit published once, got %d", len(pub.calledWith))
+	}
+	// Parallel batches cover 6 blocks each until the backlog drops below the
+	// threshold; the rest is polled back-to-back.
+	want := []string{"2", "4", "6", "8", "10", "12", "14", "16", "18", "20"}
+	if strings.Join(checkpoint.saved, ",") != strings.Join(want, ",") {
+		t.Fatalf("expected contiguous checkpoints %v, got %v", want, checkpoint.saved)
+	}
+	if source.rangeHits != 9 {
+		t.Fatalf("expected three parallel batches of three ranges, got %d range polls", source.rangeHits)
+	}
+}
+
+func TestRunner_CatchUpStopsCheckpointAtFailedRange(t *testing.T) {
+	source := &backlogSourceStub{head: 20, span: 2, failFrom: 3}
+	checkpoint := &recordingCheckpoint{checkpointStub: checkpointStub{cursor: "0"}}
+	pub := &publisherStub{}
+	runner := newBacklogRunner(source, checkpoint, pub)
+
+	cursor := runner.catchUp(context.Background(), "0", map[string]heldCandidate{})
+
+	if cursor != "2" {
+		t.Fatalf("expected checkpoint to stop before the failed range, got %s", cursor)
+	}
+	if strings.Join(checkpoint.saved, ",") != "2" {
+		t.Fatalf("expected only the first range committed, got %v", checkpoint.saved)
+	}
+	if len(pub.calledWith) != 2 {
+		t.Fatalf("expected only deposits before the failed range published, got %d", len(pub.calledWith))
+	}
+}
+
+// timestampSourceStub finds the same start cursor for every time.
+type timestampSourceStub struct {
+	sourceStub
+	cursor string
+}
+
+func (s timestampSourceStub) CursorAt(_ context.Context, _ time.Time) (string, error) {
+	return s.cursor, nil
+}
+
+func TestRunner_RewindsToStartOncePerStartTime(t *testing.T) {
+	checkpoint := &checkpointStub{cursor: "100"}
+	marker := &checkpointStub{}
+	runner := Runner{
+		Name:            "base-watcher-test",
+		Source:          timestampSourceStub{cursor: "500"},
+		CheckpointStore: checkpoint,
+		Logger:          slog.New(slog.NewTextHandler(io.Discard, nil)),
+		StartAt:         time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC),
+		StartMarker:     marker,
+	}
+
+	if err := runner.rewindToStart(context.Background()); err != nil {
+		t.Fatalf("unexpected rewind error: %v", err)
+	}
+	if checkpoint.cursor != "500" || marker.cursor != "2026-09-01T00:00:00Z" {
+		t.Fatalf("expected the start cursor and marker saved, got %s and %s", checkpoint.cursor, marker.cursor)
+	}
+
+	// A restart with the same start time keeps the progress made since.
+	checkpoint.cursor = "5009"
+	if err := runner.rewindToStart(context.Background()); err != nil {
+		t.Fatalf("unexpected rewind error: %v", err)
+	}
+	if checkpoint.cursor != "5009" {
+		t.Fatalf("expected the checkpoint kept on restart, got %s", checkpoint.cursor)
+	}
+
+	// A new start time rewinds again.
+	runner.StartAt = time.Date(2026, 8, 1, 0, 0, 0, 0, time.UTC)
+	if err := runner.rewindToStart(context.Background()); err != nil {
+		t.Fatalf("unexpected rewind error: %v", err)
+	}
+	if checkpoint.cursor != "500" || marker.cursor != "2026-08-01T00:00:00Z" {
+		t.Fatalf("expected a rewind for the new start time, got %s and %s", checkpoint.cursor, marker.cursor)
+	}
+}
@@ -0,0 +1,178 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+type receiptLogMatch struct {
+	eventLog evmLog
+	token    string
+	route    *ActiveRoute
+}
+
+// scanBlockReceipts finds candidates by fetching every receipt in
+// [fromBlock, toBlock] with eth_getBlockReceipts and filtering Transfer logs
+// for all token contracts and routes in memory. Blocks are fetched and
+// filtered ReceiptsBatchSize at a time, so only one batch of receipts is held
+// at once. With FactoryLevelScan on, transfers to unknown recipients become
+// candidates too, as in the broad eth_getLogs scan.
+func (s EvmRpcSource) scanBlockReceipts(
+	ctx context.Context,
+	routes []ActiveRoute,
+	fromBlock int64,
+	toBlock int64,
+	latestBlock int64,
+	safeLatestBlock int64,
+	headerRefs []blockHashRef,
+	blockTimestampCache map[int64]time.Time,
+) ([]FundingCandidate, error) {
+	index, err := s.indexRoutes(routes)
+	if err != nil {
+		return nil, err
+	}
+
+	expectedHashes := make(map[int64]string, len(headerRefs))
+	for _, ref := range headerRefs {
+		expectedHashes[ref.Number] = ref.Hash
+	}
+
+	derivable := make(map[string]map[string]bool)
+	if s.FactoryLevelScan && s.Create2 != nil {
+		for _, token := range s.Tokens.Tokens() {
+			derivable[token.Symbol] = s.Create2.derivableAddresses(token.Symbol, routes)
+		}
+	}
+
+	matches := make([]receiptLogMatch, 0)
+	matchedLogs := make([]evmLog, 0)
+	receiptCount := 0
+	batchSize := int64(s.effectiveReceiptsBatchSize())
+	for batchFrom := fromBlock; batchFrom <= toBlock; batchFrom += batchSize {
+		blockNumbers := make([]int64, 0, batchSize)
+		for blockNumber := batchFrom; blockNumber <= toBlock && blockNumber < batchFrom+batchSize; blockNumber++ {
+			blockNumbers = append(blockNumbers, blockNumber)
+		}
+		blocks, blockErrs, err := s.ethGetBlockReceipts(ctx, blockNumbers)
+		if err != nil {
+			return nil, err
+		}
+
+		for i, receipts := range blocks {
+			if blockErrs[i] != nil {
+				return nil, blockErrs[i]
+			}
+			receiptCount += len(receipts)
+
+			for _, receipt := range receipts {
+				if expected := expectedHashes[blockNumbers[i]]; expected != "" && !strings.EqualFold(receipt.BlockHash, expected) {
+					return nil, fmt.Errorf("chain changed during poll at block %d", blockNumbers[i])
+				}
+
+				for _, eventLog := range receipt.Logs {
+					tokenInfo, ok := s.Tokens.TokenByContract(eventLog.Address)
+					token := tokenInfo.Symbol
+					if !ok || len(eventLog.Topics) < 3 || !strings.EqualFold(eventLog.Topics[0], transferTopic) {
+						continue
+					}
+					cacheLogTimestamp(eventLog, blockTimestampCache)
+
+					match := receiptLogMatch{eventLog: eventLog, token: token}
+					if route, ok := index.byTopic[token][strings.ToLower(eventLog.Topics[2])]; ok {
+						match.route = &route
+					} else if !s.FactoryLevelScan {
+						continue
+					} else if s.Create2 != nil && !derivable[token][addressFromTopic(eventLog.Topics[2])] {
+						continue
+					}
+					matches = append(matches, match)
+					matchedLogs = append(matchedLogs, eventLog)
+				}
+			}
+		}
+	}
+	s.fillBlockTimestamps(ctx, matchedLogs, blockTimestampCache)
+
+	candidates := make([]FundingCandidate, 0, len(matches))
+	for _, match := range matches {
+		if match.route != nil {
+			candidates = append(candidates, s.logsToFundingCandidates(ctx, []evmLog{match.eventLog}, match.route.Token, match.route.DepositAddress, match.route.TransferID, latestBlock, safeLatestBlock, blockTimestampCache)...)
+			continue
+		}
+		// A transfer to an unknown recipient is a potential QR/manual deposit;
+		// ProcessCandidate will try to resolve it via the route resolver.
+		candidates = append(candidates, s.logsToFundingCandidates(ctx, []evmLog{match.eventLog}, match.token, addressFromTopic(match.eventLog.Topics[2]), "", latestBlock, safeLatestBlock, blockTimestampCache)...)
+	}
+
+	slog.Info("base-rpc: block receipts scan complete",
+		"blockCount", toBlock-fromBlock+1,
+		"receiptCount", receiptCount,
+		"transferCount", len(matches),
+	)
+
+	return candidates, nil
+}
+
+// cacheLogTimestamp records the block timestamp some clients include on logs,
+// saving a header lookup.
+func cacheLogTimestamp(eventLog evmLog, blockTimestampCache map[int64]time.Time) {
+	if eventLog.BlockTimestamp == "" {
+		return
+	}
+	blockNumber, err := parseHexInt64(eventLog.BlockNumber)
+	if err != nil {
+		return
+	}
+	if _, ok := blockTimestampCache[blockNumber]; ok {
+		return
+	}
+	if confirmedAt, err := blockTimestamp(evmBlock{Timestamp: eventLog.BlockTimestamp}); err == nil {
+		blockTimestampCache[blockNumber] = confirmedAt
+	}
+}
+
+func (s EvmRpcSource) effectiveReceiptsBatchSize() int {
+	if s.ReceiptsBatchSize > 0 {
+		return s.ReceiptsBatchSize
+	}
+	return 10
+}
+
+// ethGetBlockReceipts fetches all receipts of many blocks in batched requests.
+// Receipt payloads are large, so batches use ReceiptsBatchSize rather than
+// MaxBatchSize. Per-block failures are in the error slice.
+func (s EvmRpcSource) ethGetBlockReceipts(ctx context.Context, blockNumbers []int64) ([][]evmReceipt, []error, error) {
+	results := make([]*[]evmReceipt, len(blockNumbers))
+	calls := make([]*rpcBatchCall, len(blockNumbers))
+	for i, blockNumber := range blockNumbers {
+		calls[i] = &rpcBatchCall{
+			Method: "eth_getBlockReceipts",
+			Params: []interface{}{fmt.Sprintf("0x%x", blockNumber)},
+			Out:    &results[i],
+		}
+	}
+
+	client := s.rpc()
+	client.MaxBatchSize = s.effectiveReceiptsBatchSize()
+	if err := client.batch(ctx, calls); err != nil {
+		return nil, nil, fmt.Errorf("eth_getBlockReceipts batch: %w", err)
+	}
+
+	receipts := make([][]evmReceipt, len(blockNumbers))
+	errs := make([]error, len(blockNumbers))
+	for i, call := range calls {
+		switch {
+		case call.Err != nil:
+			errs[i] = fmt.Errorf("eth_getBlockReceipts %d: %w", blockNumbers[i], call.Err)
+		case results[i] == nil:
+			errs[i] = fmt.Errorf("eth_getBlockReceipts %d: %w", blockNumbers[i], errBlockNotFound)
+		default:
+			receipts[i] = *results[i]
+		}
+	}
+
+	return receipts, errs, nil
+}
@@ -0,0 +1,452 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// nativeSymbol is the token name native ETH candidates carry.
+const nativeSymbol = "ETH"
+
+// nativeLogIndexBase offsets the LogIndex of native transfers so their event
+// keys never collide with a Transfer log in the same transaction. The plain
+// value of a transaction uses the base itself, internal transfers follow in
+// trace order.
+const nativeLogIndexBase = 1 << 24
+
+var nativeToken = TokenInfo{Symbol: nativeSymbol, Decimals: 18, Name: "Ether"}
+
+// TraceMethod selects how EvmRpcSource finds internal ETH transfers. Without
+// one only the value of top-level transactions is seen.
+type TraceMethod string
+
+const (
+	TraceNone   TraceMethod = "none"
+	TraceParity TraceMethod = "trace_block"
+	TraceDebug  TraceMethod = "debug_traceBlockByNumber"
+)
+
+type evmTransaction struct {
+	Hash  string `json:"hash"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Value string `json:"value"`
+}
+
+type evmBlockWithTransactions struct {
+	Number       string           `json:"number"`
+	Hash         string           `json:"hash"`
+	Timestamp    string           `json:"timestamp"`
+	Transactions []evmTransaction `json:"transactions"`
+}
+
+// parityTrace is one entry of a trace_block response.
+type parityTrace struct {
+	Type   string `json:"type"`
+	Action struct {
+		CallType string `json:"callType"`
+		From     string `json:"from"`
+		To       string `json:"to"`
+		Value    string `json:"value"`
+	} `json:"action"`
+	TransactionHash string `json:"transactionHash"`
+	TraceAddress    []int  `json:"traceAddress"`
+	Error           string `json:"error,omitempty"`
+}
+
+// callFrame is a node of the callTracer output of debug_traceBlockByNumber.
+type callFrame struct {
+	Type  string      `json:"type"`
+	From  string      `json:"from"`
+	To    string      `json:"to"`
+	Value string      `json:"value"`
+	Error string      `json:"error,omitempty"`
+	Calls []callFrame `json:"calls,omitempty"`
+}
+
+type debugTraceResult struct {
+	TxHash string    `json:"txHash"`
+	Result callFrame `json:"result"`
+}
+
+// nativeTransfer is a value transfer to a route address found in a block.
+type nativeTransfer struct {
+	blockNumber  int64
+	blockHash    string
+	txHash       string
+	from         string
+	to           string
+	value        *big.Int
+	ordinal      int    // 0 for the transaction value, 1.. for internal transfers
+	traceAddress string // position in the call tree, internal transfers only
+}
+
+func isNativeCandidate(c FundingCandidate) bool {
+	return strings.EqualFold(c.Token, nativeSymbol) && c.LogIndex >= nativeLogIndexBase
+}
+
+func (s EvmRpcSource) effectiveTraceMethod() TraceMethod {
+	switch s.NativeTraceMethod {
+	case TraceParity, TraceDebug:
+		return s.NativeTraceMethod
+	default:
+		return TraceNone
+	}
+}
+
+// scanNative finds ETH sent to active route addresses in [fromBlock, toBlock],
+// from transaction values in the block bodies and, with a trace method, from
+// internal calls. Transfers of failed transactions are dropped, and amounts are
+// converted to USD with s.Prices. That is the price at scan time, not at the
+// transfer's block, so a catch-up or backfill values old deposits at today's
+// price; metadata priceAsOf records when the price was taken.
+func (s EvmRpcSource) scanNative(
+	ctx context.Context,
+	routes []ActiveRoute,
+	fromBlock int64,
+	toBlock int64,
+	latestBlock int64,
+	safeLatestBlock int64,
+	headerRefs []blockHashRef,
+	blockTimestampCache map[int64]time.Time,
+) ([]FundingCandidate, error) {
+	routesByAddress := make(map[string]ActiveRoute, len(routes))
+	for _, route := range routes {
+		address := strings.ToLower(route.DepositAddress)
+		if existing, ok := routesByAddress[address]; ok && strings.EqualFold(existing.Token, nativeSymbol) {
+			continue
+		}
+		routesByAddress[address] = route
+	}
+	if len(routesByAddress) == 0 {
+		return nil, nil
+	}
+	if s.Prices == nil {
+		return nil, fmt.Errorf("native scan requires a price source")
+	}
+
+	expectedHashes := make(map[int64]string, len(headerRefs))
+	for _, ref := range headerRefs {
+		expectedHashes[ref.Number] = ref.Hash
+	}
+
+	blockNumbers := make([]int64, 0, toBlock-fromBlock+1)
+	for blockNumber := fromBlock; blockNumber <= toBlock; blockNumber++ {
+		blockNumbers = append(blockNumbers, blockNumber)
+	}
+	blocks, err := s.ethGetBlocksWithTransactions(ctx, blockNumbers)
+	if err != nil {
+		return nil, err
+	}
+
+	transfers := make([]nativeTransfer, 0)
+	traceMethod := s.effectiveTraceMethod()
+	for i, block := range blocks {
+		blockNumber := blockNumbers[i]
+		if expected := expectedHashes[blockNumber]; expected != "" && !strings.EqualFold(block.Hash, expected) {
+			return nil, fmt.Errorf("chain changed during poll at block %d", blockNumber)
+		}
+		if confirmedAt, err := blockTimestamp(evmBlock{Timestamp: block.Timestamp}); err == nil {
+			blockTimestampCache[blockNumber] = confirmedAt
+		}
+
+		for _, tx := range block.Transactions {
+			value, ok := parseNativeValue(tx.Value)
+			if !ok || routesByAddress[strings.ToLower(tx.To)].DepositAddress == "" {
+				continue
+			}
+			transfers = append(transfers, nativeTransfer{
+				blockNumber: blockNumber,
+				blockHash:   block.Hash,
+				txHash:      tx.Hash,
+				from:        tx.From,
+				to:          tx.To,
+				value:       value,
+			})
+		}
+
+		if traceMethod == TraceNone || len(block.Transactions) == 0 {
+			continue
+		}
+		internalTransfers, err := s.traceInternalTransfers(ctx, traceMethod, blockNumber, block)
+		if err != nil {
+			if !isMethodUnsupportedError(err) {
+				return nil, err
+			}
+			slog.Warn("base-rpc: provider does not support tracing, internal ETH transfers are not scanned",
+				"traceMethod", traceMethod,
+				"error", err,
+			)
+			traceMethod = TraceNone
+			continue
+		}
+		for _, transfer := range internalTransfers {
+			if routesByAddress[strings.ToLower(transfer.to)].DepositAddress != "" {
+				transfers = append(transfers, transfer)
+			}
+		}
+	}
+	if len(transfers) == 0 {
+		return nil, nil
+	}
+
+	succeeded, err := s.successfulTransactions(ctx, transfers)
+	if err != nil {
+		return nil, err
+	}
+	priceUSD, err := s.Prices.PriceUSD(ctx, nativeSymbol)
+	if err != nil {
+		return nil, fmt.Errorf("native price: %w", err)
+	}
+	priceAsOf := time.Now().UTC()
+
+	candidates := make([]FundingCandidate, 0, len(transfers))
+	for _, transfer := range transfers {
+		if !succeeded[strings.ToLower(transfer.txHash)] {
+			continue
+		}
+		confirmedAt, ok := blockTimestampCache[transfer.blockNumber]
+		if !ok {
+			continue
+		}
+		route := routesByAddress[strings.ToLower(transfer.to)]
+
+		kind := "transaction"
+		metadata := map[string]any{
+			"blockNumber":  transfer.blockNumber,
+			"blockHash":    transfer.blockHash,
+			"payerAddress": strings.ToLower(transfer.from),
+			"priceUSD":     priceUSD,
+			"priceAsOf":    priceAsOf,
+		}
+		if transfer.ordinal > 0 {
+			kind = "internal"
+			metadata["traceAddress"] = transfer.traceAddress
+		}
+		metadata["nativeTransfer"] = kind
+
+		candidate := FundingCandidate{
+			Chain:          s.Chain,
+			Token:          nativeSymbol,
+			TxHash:         transfer.txHash,
+			LogIndex:       nativeLogIndexBase + transfer.ordinal,
+			DepositAddress: route.DepositAddress,
+			ConfirmedAt:    confirmedAt,
+			Confirmations:  int(latestBlock-transfer.blockNumber) + 1,
+			Finalized:      transfer.blockNumber <= safeLatestBlock,
+			Metadata:       metadata,
+		}
+		// Routes opened for another token still see the ETH, but the watcher
+		// resolves those by token instead of crediting the route's transfer.
+		if strings.EqualFold(route.Token, nativeSymbol) {
+			candidate.TransferID = route.TransferID
+		}
+		candidate.setAmount(nativeToken, transfer.value)
+		candidate.AmountUSD *= priceUSD
+		candidates = append(candidates, candidate)
+	}
+
+	slog.Info("base-rpc: native scan complete",
+		"blockRange", fmt.Sprintf("%d-%d", fromBlock, toBlock),
+		"transfers", len(transfers),
+		"candidates", len(candidates),
+		"traceMethod", traceMethod,
+	)
+
+	return candidates, nil
+}
+
+// ethGetBlocksWithTransactions fetches full blocks in batched requests.
+func (s EvmRpcSource) ethGetBlocksWithTransactions(ctx context.Context, blockNumbers []int64) ([]evmBlockWithTransactions, error) {
+	results := make([]*evmBlockWithTransactions, len(blockNumbers))
+	calls := make([]*rpcBatchCall, len(blockNumbers))
+	for i, blockNumber := range blockNumbers {
+		calls[i] = &rpcBatchCall{
+			Method: "eth_getBlockByNumber",
+			Params: []interface{}{fmt.Sprintf("0x%x", blockNumber), true},
+			Out:    &results[i],
+		}
+	}
+
+	client := s.rpc()
+	client.MaxBatchSize = s.effectiveReceiptsBatchSize()
+	if err := client.batch(ctx, calls); err != nil {
+		return nil, fmt.Errorf("eth_getBlockByNumber batch: %w", err)
+	}
+
+	blocks := make([]evmBlockWithTransactions, len(blockNumbers))
+	for i, call := range calls {
+		if call.Err != nil {
+			return nil, fmt.Errorf("eth_getBlockByNumber %d: %w", blockNumbers[i], call.Err)
+		}
+		if results[i] == nil || results[i].Hash == "" {
+			return nil, fmt.Errorf("eth_getBlockByNumber %d: %w", blockNumbers[i], errBlockNotFound)
+		}
+		blocks[i] = *results[i]
+	}
+	return blocks, nil
+}
+
+// traceInternalTransfers returns every value-carrying internal call of a block
+// that did not fail. Ordinals count all value-carrying calls of a transaction
+// in call-tree order, so they are stable whichever trace method is used.
+func (s EvmRpcSource) traceInternalTransfers(ctx context.Context, method TraceMethod, blockNumber int64, block evmBlockWithTransactions) ([]nativeTransfer, error) {
+	blockParam := fmt.Sprintf("0x%x", blockNumber)
+	transfers := make([]nativeTransfer, 0)
+	ordinals := make(map[string]int)
+
+	switch method {
+	case TraceParity:
+		var traces []parityTrace
+		if err := s.rpcCall(ctx, "trace_block", []interface{}{blockParam}, &traces); err != nil {
+			return nil, fmt.Errorf("trace_block %d: %w", blockNumber, err)
+		}
+		failed := make([]string, 0)
+		for _, trace := range traces {
+			address := formatTraceAddress(trace.TraceAddress)
+			// Any frame can revert, whatever its type or value, and takes its
+			// subcalls with it.
+			if trace.Error != "" {
+				failed = append(failed, trace.TransactionHash+":"+address)
+			}
+			if len(trace.TraceAddress) == 0 || trace.Type != "call" || !strings.EqualFold(trace.Action.CallType, "call") {
+				continue
+			}
+			value, ok := parseNativeValue(trace.Action.Value)
+			if !ok {
+				continue
+			}
+			ordinals[trace.TransactionHash]++
+			if trace.Error != "" || insideFailedCall(failed, trace.TransactionHash, address) {
+				continue
+			}
+			transfers = append(transfers, nativeTransfer{
+				blockNumber:  blockNumber,
+				blockHash:    block.Hash,
+				txHash:       trace.TransactionHash,
+				from:         trace.Action.From,
+				to:           trace.Action.To,
+				value:        value,
+				ordinal:      ordinals[trace.TransactionHash],
+				traceAddress: address,
+			})
+		}
+	case TraceDebug:
+		var results []debugTraceResult
+		params := []interface{}{blockParam, map[string]string{"tracer": "callTracer"}}
+		if err := s.rpcCall(ctx, "debug_traceBlockByNumber", params, &results); err != nil {
+			return nil, fmt.Errorf("debug_traceBlockByNumber %d: %w", blockNumber, err)
+		}
+		for i, result := range results {
+			txHash := result.TxHash
+			if txHash == "" && i < len(block.Transactions) {
+				// Older clients omit txHash; results follow transaction order.
+				txHash = block.Transactions[i].Hash
+			}
+			var walk func(frame callFrame, path []int, failed bool)
+			walk = func(frame callFrame, path []int, failed bool) {
+				failed = failed || frame.Error != ""
+				if len(path) > 0 && strings.EqualFold(frame.Type, "CALL") {
+					if value, ok := parseNativeValue(frame.Value); ok {
+						ordinals[txHash]++
+						if !failed {
+							transfers = append(transfers, nativeTransfer{
+								blockNumber:  blockNumber,
+								blockHash:    block.Hash,
+								txHash:       txHash,
+								from:         frame.From,
+								to:           frame.To,
+								value:        value,
+								ordinal:      ordinals[txHash],
+								traceAddress: formatTraceAddress(path),
+							})
+						}
+					}
+				}
+				for j, call := range frame.Calls {
+					walk(call, append(append([]int(nil), path...), j), failed)
+				}
+			}
+			walk(result.Result, nil, false)
+		}
+	}
+
+	return transfers, nil
+}
+
+// successfulTransactions fetches the receipts of the transactions behind
+// transfers and returns the lower-cased hashes of those that succeeded.
+func (s EvmRpcSource) successfulTransactions(ctx context.Context, transfers []nativeTransfer) (map[string]bool, error) {
+	hashes := make([]string, 0, len(transfers))
+	seen := make(map[string]bool, len(transfers))
+	for _, transfer := range transfers {
+		hash := strings.ToLower(transfer.txHash)
+		if !seen[hash] {
+			seen[hash] = true
+			hashes = append(hashes, transfer.txHash)
+		}
+	}
+
+	receipts := make([]*evmReceipt, len(hashes))
+	calls := make([]*rpcBatchCall, len(hashes))
+	for i, hash := range hashes {
+		calls[i] = &rpcBatchCall{Method: "eth_getTransactionReceipt", Params: []interface{}{hash}, Out: &receipts[i]}
+	}
+	if err := s.rpc().batch(ctx, calls); err != nil {
+		return nil, fmt.Errorf("eth_getTransactionReceipt batch: %w", err)
+	}
+
+	succeeded := make(map[string]bool, len(hashes))
+	for i, call := range calls {
+		if call.Err != nil {
+			return nil, fmt.Errorf("eth_getTransactionReceipt %s: %w", hashes[i], call.Err)
+		}
+		if receipts[i] != nil && receipts[i].Status == "0x1" {
+			succeeded[strings.ToLower(hashes[i])] = true
+		}
+	}
+	return succeeded, nil
+}
+
+// parseNativeValue parses a hex wei value and reports whether it is positive.
+func parseNativeValue(hexValue string) (*big.Int, bool) {
+	trimmed := strings.TrimPrefix(strings.TrimSpace(hexValue), "0x")
+	if trimmed == "" {
+		return nil, false
+	}
+	value, ok := new(big.Int).SetString(trimmed, 16)
+	if !ok || value.Sign() <= 0 {
+		return nil, false
+	}
+	return value, true
+}
+
+func formatTraceAddress(path []int) string {
+	parts := make([]string, len(path))
+	for i, index := range path {
+		parts[i] = strconv.Itoa(index)
+	}
+	return strings.Join(parts, ".")
+}
+
+// insideFailedCall reports whether the call at address sits below a failed
+// call of the same transaction; trace_block does not mark those itself.
+// A failed top-level frame has an empty address and covers the whole
+// transaction.
+func insideFailedCall(failed []string, txHash string, address string) bool {
+	for _, key := range failed {
+		failedTx, failedAddress, _ := strings.Cut(key, ":")
+		if failedTx != txHash {
+			continue
+		}
+		if failedAddress == "" || strings.HasPrefix(address, failedAddress+".") {
+			return true
+		}
+	}
+	return false
+}
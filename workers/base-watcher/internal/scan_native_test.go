@@ -0,0 +1,163 @@
+package internal
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const testEthRouteAddr = "0x4444444444444444444444444444444444444444"
+
+func newTestNativeSource(url string) EvmRpcSource {
+	source := newTestEvmSource(url)
+	source.RouteStore = routeStoreStub{routes: []ActiveRoute{
+		{TransferID: "tr_usdc", Token: "USDC", DepositAddress: testDepositAddr},
+		{TransferID: "tr_eth", Token: "ETH", DepositAddress: testEthRouteAddr},
+	}}
+	source.NativeScan = true
+	source.NativeTraceMethod = TraceParity
+	source.Prices = StaticPriceSource{"ETH": 2000}
+	return source
+}
+
+func TestEvmRpcSource_NativeScanFindsPlainAndInternalTransfers(t *testing.T) {
+	node := newFakeEvmNode(110)
+	node.addNativeTransfer(103, "0xplain", testEthRouteAddr, 500_000_000_000_000_000)
+	node.addNativeTransfer(104, "0xother", "0x5555555555555555555555555555555555555555", 1_000_000_000_000_000_000)
+	node.addNativeTransfer(104, "0xfailed", testEthRouteAddr, 1_000_000_000_000_000_000)
+	node.failedTxs = map[string]bool{"0xfailed": true}
+	node.txs[105] = []evmTransaction{{Hash: "0xrouter", To: "0x6666666666666666666666666666666666666666", Value: "0x0"}}
+	node.addInternalTransfer(105, "0xrouter", "0x7777777777777777777777777777777777777777", 1_000, false, 0)
+	node.addInternalTransfer(105, "0xrouter", testDepositAddr, 250_000_000_000_000_000, false, 1)
+	node.addInternalTransfer(105, "0xrouter", testEthRouteAddr, 1_000_000_000_000_000_000, true, 2)
+	node.addInternalTransfer(105, "0xrouter", testEthRouteAddr, 1_000_000_000_000_000_000, false, 2, 0)
+	source := newTestNativeSource(node.serve(t).URL)
+
+	candidates, _, err := source.Poll(context.Background(), "100")
+	if err != nil {
+		t.Fatalf("unexpected poll error: %v", err)
+	}
+	if len(candidates) != 2 {
+		t.Fatalf("expected plain and internal ETH candidates, got %+v", candidates)
+	}
+
+	plain, traced := candidates[0], candidates[1]
+	if plain.TxHash != "0xplain" || plain.Token != "ETH" || plain.LogIndex != nativeLogIndexBase {
+		t.Fatalf("unexpected plain candidate: %+v", plain)
+	}
+	if plain.TransferID != "tr_eth" || plain.Amount != "0.5" || plain.AmountBaseUnits != "500000000000000000" || plain.AmountUSD != 1000 {
+		t.Fatalf("unexpected plain candidate amount or route: %+v", plain)
+	}
+	if asOf, _ := plain.Metadata["priceAsOf"].(time.Time); asOf.IsZero() {
+		t.Fatalf("expected the candidate to record when it was priced: %+v", plain.Metadata)
+	}
+	if plain.Metadata["nativeTransfer"] != "transaction" || plain.Metadata["blockHash"] != node.hashes[103] {
+		t.Fatalf("unexpected plain candidate metadata: %+v", plain.Metadata)
+	}
+
+	// The ETH reached a USDC route, so the watcher resolves it by token.
+	if traced.TxHash != "0xrouter" || traced.LogIndex != nativeLogIndexBase+2 || traced.TransferID != "" {
+		t.Fatalf("unexpected internal candidate: %+v", traced)
+	}
+	if traced.DepositAddress != testDepositAddr || traced.AmountUSD != 500 || traced.Metadata["traceAddress"] != "1" {
+		t.Fatalf("unexpected internal candidate details: %+v", traced)
+	}
+}
+
+func TestEvmRpcSource_NativeScanSkipsCallsUnderAnyRevertedFrame(t *testing.T) {
+	node := newFakeEvmNode(110)
+	node.txs, node.traces = map[int64][]evmTransaction{}, map[int64][]parityTrace{}
+	node.txs[105] = []evmTransaction{{Hash: "0xrouter", To: "0x6666666666666666666666666666666666666666", Value: "0x0"}}
+	node.txs[106] = []evmTransaction{{Hash: "0xreverted", To: "0x6666666666666666666666666666666666666666", Value: "0x0"}}
+	// A reverted zero-value delegatecall, a reverted staticcall and a reverted
+	// create, each with a value-carrying call to the route below it.
+	for i, frameType := range []string{"delegatecall", "staticcall", "create"} {
+		frame := parityTrace{Type: "call", TransactionHash: "0xrouter", TraceAddress: []int{i}, Error: "Reverted"}
+		frame.Action.CallType = frameType
+		if frameType == "create" {
+			frame.Type, frame.Action.CallType = "create", ""
+		}
+		node.traces[105] = append(node.traces[105], frame)
+		node.addInternalTransfer(105, "0xrouter", testEthRouteAddr, 1_000_000_000_000_000_000, false, i, 0)
+	}
+	node.addInternalTransfer(105, "0xrouter", testEthRouteAddr, 250_000_000_000_000_000, false, 3)
+	// A reverted top-level call takes every internal call with it.
+	root := parityTrace{Type: "call", TransactionHash: "0xreverted", Error: "Reverted"}
+	root.Action.CallType = "call"
+	node.traces[106] = append(node.traces[106], root)
+	node.addInternalTransfer(106, "0xreverted", testEthRouteAddr, 1_000_000_000_000_000_000, false, 0)
+	source := newTestNativeSource(node.serve(t).URL)
+
+	candidates, _, err := source.Poll(context.Background(), "100")
+	if err != nil {
+		t.Fatalf("unexpected poll error: %v", err)
+	}
+	if len(candidates) != 1 || candidates[0].TxHash != "0xrouter" || candidates[0].Metadata["traceAddress"] != "3" {
+		t.Fatalf("expected only the call outside the reverted frames, got %+v", candidates)
+	}
+}
+
+func TestEvmRpcSource_NativeScanWithoutTraceSupport(t *testing.T) {
+	node := newFakeEvmNode(110)
+	node.addNativeTransfer(103, "0xplain", testEthRouteAddr, 500_000_000_000_000_000)
+	source := newTestNativeSource(node.serve(t).URL)
+
+	candidates, _, err := source.Poll(context.Background(), "100")
+	if err != nil {
+		t.Fatalf("expected unsupported tracing to be skipped, got %v", err)
+	}
+	if len(candidates) != 1 || candidates[0].TxHash != "0xplain" {
+		t.Fatalf("expected the plain transfer only, got %+v", candidates)
+	}
+	if node.calls["trace_block"] != 1 {
+		t.Fatalf("expected tracing to be tried once per poll, got %d calls", node.calls["trace_block"])
+	}
+}
+
+func TestEvmRpcSource_NativeCandidatePassesReceiptVerifier(t *testing.T) {
+	node := newFakeEvmNode(110)
+	node.addNativeTransfer(103, "0xplain", testEthRouteAddr, 500_000_000_000_000_000)
+	source := newTestNativeSource(node.serve(t).URL)
+
+	candidates, _, err := source.Poll(context.Background(), "100")
+	if err != nil || len(candidates) != 1 {
+		t.Fatalf("unexpected poll result: %+v %v", candidates, err)
+	}
+
+	verification, err := ReceiptVerifier{Source: source}.VerifyCandidate(context.Background(), candidates[0])
+	if err != nil {
+		t.Fatalf("unexpected verify error: %v", err)
+	}
+	if !verification.Verified {
+		t.Fatalf("expected native candidate to verify, got %+v", verification)
+	}
+}
+
+func TestHTTPPriceSource_ReadsNestedStringField(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/v2/prices/ETH-USD/spot" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":{"amount":"2543.21","currency":"USD"}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	prices := NewHTTPPriceSource(server.URL+"/v2/prices/{symbol}-USD/spot", "data.amount")
+	for i := 0; i < 2; i++ {
+		price, err := prices.PriceUSD(context.Background(), "eth")
+		if err != nil {
+			t.Fatalf("unexpected price error: %v", err)
+		}
+		if price != 2543.21 {
+			t.Fatalf("unexpected price: %v", price)
+		}
+	}
+	if requests != 1 {
+		t.Fatalf("expected the second lookup to hit the cache, got %d requests", requests)
+	}
+}
@@ -0,0 +1,118 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// Backlog returns how many final blocks the cursor trails.
+func (s EvmRpcSource) Backlog(ctx context.Context, cursor string) (int64, error) {
+	_, safeLatestBlock, err := s.scanBounds(ctx)
+	if err != nil {
+		return 0, err
+	}
+	lag := safeLatestBlock - parseEvmCursor(cursor).Block
+	if lag < 0 {
+		return 0, nil
+	}
+	return lag, nil
+}
+
+// PlanBacklog splits the final blocks after cursor into at most maxRanges
+// contiguous ranges of up to MaxBlockSpan blocks each. The first range carries
+// cursor, so it checks the blocks the cursor tracks for a reorg.
+func (s EvmRpcSource) PlanBacklog(ctx context.Context, cursor string, maxRanges int) ([]ScanRange, error) {
+	_, safeLatestBlock, err := s.scanBounds(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	span := s.effectiveMaxBlockSpan()
+	ranges := make([]ScanRange, 0, maxRanges)
+	for from := parseEvmCursor(cursor).Block + 1; from <= safeLatestBlock && len(ranges) < maxRanges; from += span {
+		to := from + span - 1
+		if to > safeLatestBlock {
+			to = safeLatestBlock
+		}
+		ranges = append(ranges, ScanRange{From: from, To: to})
+	}
+	if len(ranges) > 0 {
+		ranges[0].After = cursor
+	}
+	return ranges, nil
+}
+
+// PollRange scans one planned range. A range with After continues that cursor,
+// rewinding and reporting reverted candidates if its tracked blocks were
+// reorged out. Any other range's cursor tracks only its own tail; see
+// MergeRangeCursor. Planned ranges are already final, so every candidate is
+// too.
+func (s EvmRpcSource) PollRange(ctx context.Context, r ScanRange) ([]FundingCandidate, string, error) {
+	if r.To < r.From {
+		return nil, "", fmt.Errorf("invalid scan range %d-%d", r.From, r.To)
+	}
+	ctx = s.RPCPool.Pin(ctx)
+
+	latestBlock, err := s.ethBlockNumber(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	previous := evmCursor{Block: r.From - 1}
+	if r.After != "" {
+		previous = parseEvmCursor(r.After)
+	}
+	ancestor, reorged, err := s.findCommonAncestor(ctx, previous)
+	if err != nil {
+		return nil, "", fmt.Errorf("check reorg: %w", err)
+	}
+	state := previous
+	orphaned := []trackedCandidate(nil)
+	if reorged {
+		state, orphaned = previous.rewind(ancestor)
+		slog.Warn("base-rpc: reorg detected before backlog range, rewinding cursor",
+			"previousBlock", previous.Block,
+			"commonAncestor", ancestor,
+			"orphanedCandidates", len(orphaned),
+		)
+	}
+
+	candidates, next, err := s.scanRange(ctx, state, state.Block+1, r.To, latestBlock, r.To)
+	if err != nil {
+		return nil, "", err
+	}
+	if reorged {
+		candidates = append(candidates, s.revertedCandidates(orphaned, previous, ancestor, candidates)...)
+	}
+	return candidates, next.String(), nil
+}
+
+// MergeRangeCursor adds the hashes and candidates committed tracks to the
+// cursor of the range that follows it, within the reorg window.
+func (s EvmRpcSource) MergeRangeCursor(committed string, next string) string {
+	window := s.effectiveReorgWindow()
+	tail := parseEvmCursor(next)
+	merged := parseEvmCursor(committed).advance(tail.Block, tail.Hashes, nil, window)
+	for _, candidate := range tail.Candidates {
+		if candidate.BlockNumber > tail.Block-window {
+			merged.Candidates = append(merged.Candidates, candidate)
+		}
+	}
+	return merged.String()
+}
+
+func (s EvmRpcSource) scanBounds(ctx context.Context) (int64, int64, error) {
+	if s.RPCURL == "" && s.RPCPool == nil {
+		return 0, 0, fmt.Errorf("base rpc url is required")
+	}
+	latestBlock, err := s.ethBlockNumber(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	safeLatestBlock, err := s.finalizedBound(ctx, latestBlock)
+	if err != nil {
+		return 0, 0, err
+	}
+	return latestBlock, safeLatestBlock, nil
+}
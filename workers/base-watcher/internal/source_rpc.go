@@ -1,15 +1,16 @@
 package internal
 
 import (
-	"bytes"
 	"context"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"math/big"
+	"math/rand"
 	"net/http"
-	"strconv"
+	"sort"
 	"strings"
 	"time"
 )
@@ -18,15 +19,55 @@ const transferTopic = "0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4
 
 type EvmRpcSource struct {
 	RPCURL                 string
+	RPCPool                *RPCPool   // when set, requests are spread over its providers instead of RPCURL
+	Budget                 *RPCBudget // when set, requests are paced and metered in compute units
 	HTTPClient             *http.Client
 	RouteStore             RouteStore
-	TokenContracts         map[string]string
+	Tokens                 *TokenRegistry
 	Chain                  string
-	FinalizedConfirmations int  // number of confirmations to consider finalized (default: 12)
-	FactoryLevelScan       bool // if true, also scan token contracts globally for QR/manual deposits
+	FinalityMode           FinalityMode
+	FinalizedConfirmations int             // number of confirmations to consider finalized in confirmation mode (default: 1)
+	FactoryLevelScan       bool            // if true, also scan token contracts globally for QR/manual deposits
+	Create2                *Create2Deriver // when set, factory-level scans keep only recipients it can derive
+	Filter                 *TransferFilter // when set, dust and address-poisoning transfers are tagged
 	MaxBlockSpan           int64
+	MaxTopicAddresses      int   // deposit addresses OR-ed into one eth_getLogs topic filter (default: 100)
+	ReorgWindow            int64 // number of recent block hashes kept in the cursor for reorg detection (default: 64)
+	MaxBatchSize           int   // requests per JSON-RPC batch (default: 50)
+	ScanMode               ScanMode
+	ReceiptsBatchSize      int           // blocks per eth_getBlockReceipts batch in block-receipts mode (default: 10)
+	NativeScan             bool          // if true, also find ETH sent to route addresses
+	NativeTraceMethod      TraceMethod   // how internal ETH transfers are traced (default: none)
+	Prices                 PriceSource   // USD prices for native ETH candidates, taken at scan time
+	RateLimitRetries       int           // retries of a rate-limited eth_getLogs call (default: 4)
+	RateLimitBackoff       time.Duration // first backoff after a rate limit, doubled per retry (default: 500ms)
+
+	logCache *wsLogBuffer // set by EvmWebsocketSource to answer covered ranges from its subscription
 }
 
+// FinalityMode selects how EvmRpcSource decides which blocks are final enough
+// to scan. Counting confirmations is fastest; the "safe" and "finalized" block
+// tags follow the OP-stack derivation from L1 and trade latency for safety.
+type FinalityMode string
+
+const (
+	FinalityConfirmations FinalityMode = "confirmations"
+	FinalitySafe          FinalityMode = "safe"
+	FinalityFinalized     FinalityMode = "finalized"
+)
+
+// ScanMode selects how EvmRpcSource finds Transfer logs. The default queries
+// eth_getLogs per route chunk (plus a broad query per token when
+// FactoryLevelScan is on); block-receipts mode downloads every receipt in the
+// range with eth_getBlockReceipts and filters in memory, which is cheaper on
+// providers that price eth_getLogs per call or per address.
+type ScanMode string
+
+const (
+	ScanLogs          ScanMode = "logs"
+	ScanBlockReceipts ScanMode = "block_receipts"
+)
+
 type rpcRequest struct {
 	JSONRPC string      `json:"jsonrpc"`
 	ID      int         `json:"id"`
@@ -35,6 +76,7 @@ type rpcRequest struct {
 }
 
 type rpcResponse struct {
+	ID     int             `json:"id"`
 	Result json.RawMessage `json:"result"`
 	Error  *struct {
 		Code    int    `json:"code"`
@@ -46,14 +88,27 @@ type evmLog struct {
 	TxHash      string   `json:"transactionHash"`
 	LogIndex    string   `json:"logIndex"`
 	BlockNumber string   `json:"blockNumber"`
+	BlockHash   string   `json:"blockHash"`
 	Data        string   `json:"data"`
 	Topics      []string `json:"topics"`
+	Address     string   `json:"address"`
+	Removed     bool     `json:"removed,omitempty"`
+
+	// BlockTimestamp is returned by newer clients on logs and receipts.
+	BlockTimestamp string `json:"blockTimestamp,omitempty"`
 }
 
 type evmBlock struct {
-	Timestamp string `json:"timestamp"`
+	Number     string `json:"number"`
+	Hash       string `json:"hash"`
+	ParentHash string `json:"parentHash"`
+	Timestamp  string `json:"timestamp"`
+
+	ReceiptsRoot string `json:"receiptsRoot,omitempty"`
 }
 
+var errBlockNotFound = errors.New("block not found")
+
 func (s EvmRpcSource) effectiveMaxBlockSpan() int64 {
 	if s.MaxBlockSpan > 0 {
 		return s.MaxBlockSpan
@@ -61,49 +116,118 @@ func (s EvmRpcSource) effectiveMaxBlockSpan() int64 {
 	return 250
 }
 
+func (s EvmRpcSource) effectiveFinalityMode() FinalityMode {
+	switch s.FinalityMode {
+	case FinalitySafe, FinalityFinalized:
+		return s.FinalityMode
+	default:
+		return FinalityConfirmations
+	}
+}
+
+// finalizedBound returns the highest block that may be scanned this poll.
+func (s EvmRpcSource) finalizedBound(ctx context.Context, latestBlock int64) (int64, error) {
+	mode := s.effectiveFinalityMode()
+	if mode == FinalityConfirmations {
+		finalizedThreshold := s.FinalizedConfirmations
+		if finalizedThreshold <= 0 {
+			finalizedThreshold = 1
+		}
+		return latestBlock - int64(finalizedThreshold-1), nil
+	}
+
+	block, err := s.ethGetBlock(ctx, string(mode))
+	if err != nil {
+		return 0, err
+	}
+	bound, err := parseHexInt64(block.Number)
+	if err != nil {
+		return 0, err
+	}
+	if bound > latestBlock {
+		bound = latestBlock
+	}
+	return bound, nil
+}
+
 func (s EvmRpcSource) Poll(ctx context.Context, cursor string) ([]FundingCandidate, string, error) {
-	if s.RPCURL == "" {
+	if s.RPCURL == "" && s.RPCPool == nil {
 		return nil, cursor, fmt.Errorf("base rpc url is required")
 	}
+	// Every block of one poll is read from the same provider.
+	ctx = s.RPCPool.Pin(ctx)
 
 	latestBlock, err := s.ethBlockNumber(ctx)
 	if err != nil {
 		return nil, cursor, err
 	}
 
-	current, err := strconv.ParseInt(cursor, 10, 64)
-	if err != nil {
-		current = 0
+	previous := parseEvmCursor(cursor)
+	if latestBlock <= previous.Block {
+		return nil, cursor, nil
 	}
 
-	if latestBlock <= current {
-		return nil, strconv.FormatInt(current, 10), nil
+	// Make sure the blocks scanned by earlier polls are still canonical before
+	// moving on; otherwise rewind to the last block both chains share.
+	ancestor, reorged, err := s.findCommonAncestor(ctx, previous)
+	if err != nil {
+		return nil, cursor, fmt.Errorf("check reorg: %w", err)
 	}
 
-	finalizedThreshold := s.FinalizedConfirmations
-	if finalizedThreshold <= 0 {
-		finalizedThreshold = 1
+	state := previous
+	orphaned := []trackedCandidate(nil)
+	if reorged {
+		state, orphaned = previous.rewind(ancestor)
+		slog.Warn("base-rpc: reorg detected, rewinding cursor",
+			"previousBlock", previous.Block,
+			"commonAncestor", ancestor,
+			"orphanedCandidates", len(orphaned),
+		)
 	}
+	current := state.Block
 
-	// Only scan blocks that already meet confirmation threshold; otherwise
-	// events seen with low confirmations are missed forever once cursor advances.
-	safeLatestBlock := latestBlock - int64(finalizedThreshold-1)
+	// Only scan blocks that are already final under the configured mode;
+	// otherwise events seen too early are missed forever once cursor advances.
+	safeLatestBlock, err := s.finalizedBound(ctx, latestBlock)
+	if err != nil {
+		return nil, cursor, err
+	}
 	if safeLatestBlock <= current {
-		return nil, strconv.FormatInt(current, 10), nil
+		return s.revertedCandidates(orphaned, previous, ancestor, nil), state.String(), nil
 	}
 
-	routes, err := s.RouteStore.ListActiveRoutes(ctx, s.Chain)
+	fromBlock := current + 1
+	toBlock := safeLatestBlock
+	if toBlock-fromBlock+1 > s.effectiveMaxBlockSpan() {
+		toBlock = fromBlock + s.effectiveMaxBlockSpan() - 1
+	}
+
+	candidates, next, err := s.scanRange(ctx, state, fromBlock, toBlock, latestBlock, safeLatestBlock)
 	if err != nil {
 		return nil, cursor, err
 	}
+	if reorged {
+		candidates = append(candidates, s.revertedCandidates(orphaned, previous, ancestor, candidates)...)
+	}
 
-	fromBlock := current + 1
-	toBlock := safeLatestBlock
-	maxBlockSpan := s.effectiveMaxBlockSpan()
-	if toBlock-fromBlock+1 > maxBlockSpan {
-		toBlock = fromBlock + maxBlockSpan - 1
+	return candidates, next.String(), nil
+}
+
+// scanRange scans [fromBlock, toBlock] on top of state and returns the
+// candidates found and the cursor advanced past the range.
+func (s EvmRpcSource) scanRange(
+	ctx context.Context,
+	state evmCursor,
+	fromBlock int64,
+	toBlock int64,
+	latestBlock int64,
+	safeLatestBlock int64,
+) ([]FundingCandidate, evmCursor, error) {
+	routes, err := s.RouteStore.ListActiveRoutes(ctx, s.Chain)
+	if err != nil {
+		return nil, state, err
 	}
-	candidates := make([]FundingCandidate, 0)
+
 	blockTimestampCache := make(map[int64]time.Time)
 
 	slog.Info("base-rpc: polling",
@@ -111,30 +235,97 @@ func (s EvmRpcSource) Poll(ctx context.Context, cursor string) ([]FundingCandida
 		"toBlock", toBlock,
 		"latestBlock", latestBlock,
 		"safeLatestBlock", safeLatestBlock,
-		"finalizedConfirmations", finalizedThreshold,
-		"maxBlockSpan", maxBlockSpan,
+		"finalityMode", s.effectiveFinalityMode(),
+		"scanMode", s.ScanMode,
+		"maxBlockSpan", s.effectiveMaxBlockSpan(),
 		"routeCount", len(routes),
 		"factoryLevelScan", s.FactoryLevelScan,
+		"nativeScan", s.NativeScan,
 	)
 
-	// Build a set of known deposit addresses for dedup when factory-level scanning
-	knownAddresses := make(map[string]bool)
-
-	// —— Route-targeted scanning: query logs filtered by deposit address ——
-	for _, route := range routes {
-		contract, ok := s.TokenContracts[strings.ToUpper(route.Token)]
-		if !ok || contract == "" {
-			continue
+	// Record hashes for the tail of the range so the next poll can detect a
+	// reorg. The headers also seed the block timestamp cache.
+	reorgWindow := s.effectiveReorgWindow()
+	headerFrom := fromBlock
+	if toBlock-headerFrom+1 > reorgWindow {
+		headerFrom = toBlock - reorgWindow + 1
+	}
+	headerNumbers := make([]int64, 0, toBlock-headerFrom+1)
+	for blockNumber := headerFrom; blockNumber <= toBlock; blockNumber++ {
+		headerNumbers = append(headerNumbers, blockNumber)
+	}
+	headers, headerErrs, err := s.ethGetBlockHeaders(ctx, headerNumbers)
+	if err != nil {
+		return nil, state, err
+	}
+	headerRefs := make([]blockHashRef, 0, len(headers))
+	for i, header := range headers {
+		if headerErrs[i] != nil {
+			return nil, state, headerErrs[i]
 		}
+		headerRefs = append(headerRefs, blockHashRef{Number: headerNumbers[i], Hash: header.Hash})
+		if confirmedAt, err := blockTimestamp(header); err == nil {
+			blockTimestampCache[headerNumbers[i]] = confirmedAt
+		}
+	}
+	anchorHash := ""
+	if headerFrom == fromBlock {
+		anchorHash = state.hashAt(fromBlock - 1)
+	}
+	if err := checkHeaderChain(anchorHash, headers); err != nil {
+		return nil, state, err
+	}
 
-		knownAddresses[strings.ToLower(route.DepositAddress)] = true
+	var candidates []FundingCandidate
+	if s.ScanMode == ScanBlockReceipts {
+		candidates, err = s.scanBlockReceipts(ctx, routes, fromBlock, toBlock, latestBlock, safeLatestBlock, headerRefs, blockTimestampCache)
+		if err != nil {
+			return nil, state, err
+		}
+	} else {
+		candidates, err = s.scanLogs(ctx, routes, fromBlock, toBlock, latestBlock, safeLatestBlock, blockTimestampCache)
+		if err != nil {
+			return nil, state, err
+		}
+	}
 
-		logs, err := s.ethGetLogs(ctx, contract, route.DepositAddress, fromBlock, toBlock)
+	if s.NativeScan {
+		native, err := s.scanNative(ctx, routes, fromBlock, toBlock, latestBlock, safeLatestBlock, headerRefs, blockTimestampCache)
 		if err != nil {
-			return nil, cursor, err
+			return nil, state, fmt.Errorf("native scan: %w", err)
 		}
+		candidates = append(candidates, native...)
+	}
+
+	filtered := s.Filter.apply(candidates)
 
-		candidates = append(candidates, s.logsToFundingCandidates(ctx, logs, route.Token, route.DepositAddress, route.TransferID, latestBlock, finalizedThreshold, blockTimestampCache)...)
+	slog.Info("base-rpc: poll complete",
+		"candidateCount", len(candidates),
+		"zeroValueFiltered", filtered[FilterZeroValue],
+		"belowMinimumFiltered", filtered[FilterBelowMinimum],
+		"lookalikeSenderTagged", filtered[FilterLookalikeSender],
+		"blockRange", fmt.Sprintf("%d-%d", fromBlock, toBlock),
+	)
+
+	return candidates, state.advance(toBlock, headerRefs, candidates, reorgWindow), nil
+}
+
+// scanLogs finds candidates with eth_getLogs: targeted queries for active
+// routes, plus broad per-token queries when FactoryLevelScan is on.
+func (s EvmRpcSource) scanLogs(
+	ctx context.Context,
+	routes []ActiveRoute,
+	fromBlock int64,
+	toBlock int64,
+	latestBlock int64,
+	safeLatestBlock int64,
+	blockTimestampCache map[int64]time.Time,
+) ([]FundingCandidate, error) {
+	// —— Route-targeted scanning: query logs filtered by deposit address ——
+	// knownAddresses is used to skip route addresses during factory-level scanning.
+	candidates, knownAddresses, err := s.scanRouteLogs(ctx, routes, fromBlock, toBlock, latestBlock, safeLatestBlock, blockTimestampCache)
+	if err != nil {
+		return nil, err
 	}
 
 	// —— Factory-level scanning: broad scan for QR/manual deposits ——
@@ -142,10 +333,8 @@ func (s EvmRpcSource) Poll(ctx context.Context, cursor string) ([]FundingCandida
 	// Candidates whose "to" is NOT in knownAddresses are potential QR deposits
 	// that the watcher would otherwise miss.
 	if s.FactoryLevelScan {
-		for tokenName, contract := range s.TokenContracts {
-			if contract == "" {
-				continue
-			}
+		for _, tokenInfo := range s.Tokens.Tokens() {
+			tokenName, contract := tokenInfo.Symbol, tokenInfo.Contract
 
 			logs, err := s.ethGetLogsBroad(ctx, contract, fromBlock, toBlock)
 			if err != nil {
@@ -155,17 +344,29 @@ func (s EvmRpcSource) Poll(ctx context.Context, cursor string) ([]FundingCandida
 				)
 				continue
 			}
-
+			derivable := s.Create2.derivableAddresses(tokenName, routes)
+			filtered := 0
+			unknownLogs := make([]evmLog, 0, len(logs))
 			for _, eventLog := range logs {
 				if len(eventLog.Topics) < 3 {
 					continue
 				}
-
 				toAddr := addressFromTopic(eventLog.Topics[2])
 				if knownAddresses[toAddr] {
 					// Already scanned via route-targeted mode
 					continue
 				}
+				if derivable != nil && !derivable[toAddr] {
+					// Not one of our deposit proxies; skip the resolver round trip.
+					filtered++
+					continue
+				}
+				unknownLogs = append(unknownLogs, eventLog)
+			}
+			s.fillBlockTimestamps(ctx, unknownLogs, blockTimestampCache)
+
+			for _, eventLog := range unknownLogs {
+				toAddr := addressFromTopic(eventLog.Topics[2])
 
 				// This is a potential QR/manual deposit to an unknown address.
 				// Create a candidate — ProcessCandidate will try to resolve it via the route resolver.
@@ -176,7 +377,7 @@ func (s EvmRpcSource) Poll(ctx context.Context, cursor string) ([]FundingCandida
 					toAddr,
 					"",
 					latestBlock,
-					finalizedThreshold,
+					safeLatestBlock,
 					blockTimestampCache,
 				)...)
 			}
@@ -184,16 +385,118 @@ func (s EvmRpcSource) Poll(ctx context.Context, cursor string) ([]FundingCandida
 			slog.Info("base-rpc: factory-level scan complete",
 				"token", tokenName,
 				"broadLogCount", len(logs),
+				"nonDerivableSkipped", filtered,
 			)
 		}
 	}
 
-	slog.Info("base-rpc: poll complete",
-		"candidateCount", len(candidates),
-		"blockRange", fmt.Sprintf("%d-%d", fromBlock, toBlock),
-	)
+	return candidates, nil
+}
+
+func (s EvmRpcSource) effectiveMaxTopicAddresses() int {
+	if s.MaxTopicAddresses > 0 {
+		return s.MaxTopicAddresses
+	}
+	return 100
+}
+
+// scanRouteLogs queries Transfer logs for all active routes, grouping deposit
+// addresses per token contract into OR-ed topic filters so a poll costs one
+// eth_getLogs call per chunk instead of one per route. Logs are mapped back to
+// their route locally by recipient topic.
+func (s EvmRpcSource) scanRouteLogs(
+	ctx context.Context,
+	routes []ActiveRoute,
+	fromBlock int64,
+	toBlock int64,
+	latestBlock int64,
+	finalizedBlock int64,
+	blockTimestampCache map[int64]time.Time,
+) ([]FundingCandidate, map[string]bool, error) {
+	index, err := s.indexRoutes(routes)
+	if err != nil {
+		return nil, nil, err
+	}
+	routesByToken, topicsByToken := index.byTopic, index.topics
+
+	tokens := make([]string, 0, len(topicsByToken))
+	for token := range topicsByToken {
+		tokens = append(tokens, token)
+	}
+	sort.Strings(tokens)
+
+	candidates := make([]FundingCandidate, 0)
+	chunkSize := s.effectiveMaxTopicAddresses()
+	for _, token := range tokens {
+		contract := s.Tokens.Contract(token)
+		topics := topicsByToken[token]
+
+		for start := 0; start < len(topics); start += chunkSize {
+			end := start + chunkSize
+			if end > len(topics) {
+				end = len(topics)
+			}
+
+			logs, err := s.ethGetLogs(ctx, contract, topics[start:end], fromBlock, toBlock)
+			if err != nil {
+				return nil, nil, err
+			}
+			s.fillBlockTimestamps(ctx, logs, blockTimestampCache)
+
+			for _, eventLog := range logs {
+				if len(eventLog.Topics) < 3 {
+					continue
+				}
+				route, ok := routesByToken[token][strings.ToLower(eventLog.Topics[2])]
+				if !ok {
+					continue
+				}
+				candidates = append(candidates, s.logsToFundingCandidates(ctx, []evmLog{eventLog}, route.Token, route.DepositAddress, route.TransferID, latestBlock, finalizedBlock, blockTimestampCache)...)
+			}
+		}
+	}
+
+	return candidates, index.knownAddresses, nil
+}
 
-	return candidates, strconv.FormatInt(toBlock, 10), nil
+// routeIndex maps active routes by token and recipient topic.
+type routeIndex struct {
+	byTopic        map[string]map[string]ActiveRoute // token -> recipient topic -> route
+	topics         map[string][]string               // token -> recipient topics in route order
+	knownAddresses map[string]bool                   // lowercased deposit addresses
+}
+
+func (s EvmRpcSource) indexRoutes(routes []ActiveRoute) (routeIndex, error) {
+	index := routeIndex{
+		byTopic:        make(map[string]map[string]ActiveRoute),
+		topics:         make(map[string][]string),
+		knownAddresses: make(map[string]bool),
+	}
+
+	for _, route := range routes {
+		token := strings.ToUpper(route.Token)
+		if s.Tokens.Contract(token) == "" {
+			continue
+		}
+
+		toTopic, err := encodeAddressTopic(route.DepositAddress)
+		if err != nil {
+			return routeIndex{}, err
+		}
+
+		index.knownAddresses[strings.ToLower(route.DepositAddress)] = true
+
+		if index.byTopic[token] == nil {
+			index.byTopic[token] = make(map[string]ActiveRoute)
+		}
+		if _, exists := index.byTopic[token][toTopic]; exists {
+			continue
+		}
+		index.byTopic[token][toTopic] = route
+		index.topics[token] = append(index.topics[token], toTopic)
+	}
+
+	return index, nil
 }
 
 // logsToFundingCandidates converts raw EVM logs to FundingCandidate structs.
@@ -204,13 +507,17 @@ func (s EvmRpcSource) logsToFundingCandidates(
 	depositAddress string,
 	transferID string,
 	latestBlock int64,
-	finalizedThreshold int,
+	finalizedBlock int64,
 	blockTimestampCache map[int64]time.Time,
 ) []FundingCandidate {
 	candidates := make([]FundingCandidate, 0, len(logs))
+	tokenInfo, ok := s.Tokens.Token(token)
+	if !ok {
+		return candidates
+	}
 
 	for _, eventLog := range logs {
-		amountUSD, err := parseTokenAmountUSD(eventLog.Data)
+		amount, err := parseTransferAmount(eventLog.Data)
 		if err != nil {
 			continue
 		}
@@ -237,7 +544,7 @@ func (s EvmRpcSource) logsToFundingCandidates(
 
 		confirmations := int(latestBlock-blockNumber) + 1
 
-		finalized := confirmations >= finalizedThreshold
+		finalized := blockNumber <= finalizedBlock
 
 		var payerAddress string
 		if len(eventLog.Topics) >= 2 && len(eventLog.Topics[1]) >= 26 {
@@ -248,34 +555,136 @@ func (s EvmRpcSource) logsToFundingCandidates(
 			"blockNumber": blockNumber,
 			"logIndex":    logIndexInt64,
 		}
+		if eventLog.BlockHash != "" {
+			metadata["blockHash"] = eventLog.BlockHash
+		}
 		if payerAddress != "" {
 			metadata["payerAddress"] = payerAddress
 		}
 
-		candidates = append(candidates, FundingCandidate{
+		candidate := FundingCandidate{
 			Chain:          s.Chain,
 			Token:          strings.ToUpper(token),
 			TxHash:         eventLog.TxHash,
 			LogIndex:       int(logIndexInt64),
 			TransferID:     transferID,
 			DepositAddress: depositAddress,
-			AmountUSD:      amountUSD,
 			ConfirmedAt:    confirmedAt,
 			Confirmations:  confirmations,
 			Finalized:      finalized,
 			Metadata:       metadata,
-		})
+		}
+		candidate.setAmount(tokenInfo, amount)
+		candidates = append(candidates, candidate)
 	}
 
 	return candidates
 }
 
 func (s EvmRpcSource) ethBlockTimestamp(ctx context.Context, blockNumberHex string) (time.Time, error) {
-	var block evmBlock
-	if err := s.rpcCall(ctx, "eth_getBlockByNumber", []interface{}{blockNumberHex, false}, &block); err != nil {
-		return time.Time{}, fmt.Errorf("eth_getBlockByNumber: %w", err)
+	blockNumber, err := parseHexInt64(blockNumberHex)
+	if err != nil {
+		return time.Time{}, err
 	}
 
+	block, err := s.ethGetBlockHeader(ctx, blockNumber)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return blockTimestamp(block)
+}
+
+// fillBlockTimestamps resolves the timestamps of all blocks referenced by logs
+// that are not cached yet with a single batched request. Blocks that fail here
+// are retried one by one by logsToFundingCandidates.
+func (s EvmRpcSource) fillBlockTimestamps(ctx context.Context, logs []evmLog, blockTimestampCache map[int64]time.Time) {
+	missing := make([]int64, 0)
+	queued := make(map[int64]bool)
+	for _, eventLog := range logs {
+		blockNumber, err := parseHexInt64(eventLog.BlockNumber)
+		if err != nil {
+			continue
+		}
+		if _, ok := blockTimestampCache[blockNumber]; ok || queued[blockNumber] {
+			continue
+		}
+		queued[blockNumber] = true
+		missing = append(missing, blockNumber)
+	}
+	if len(missing) == 0 {
+		return
+	}
+
+	headers, headerErrs, err := s.ethGetBlockHeaders(ctx, missing)
+	if err != nil {
+		slog.Warn("base-rpc: batched block timestamp lookup failed",
+			"blockCount", len(missing),
+			"error", err,
+		)
+		return
+	}
+	for i, header := range headers {
+		if headerErrs[i] != nil {
+			continue
+		}
+		if confirmedAt, err := blockTimestamp(header); err == nil {
+			blockTimestampCache[missing[i]] = confirmedAt
+		}
+	}
+}
+
+// ethGetBlockHeaders fetches many headers in batched requests. The returned
+// error covers transport failures; per-block failures are in the error slice.
+func (s EvmRpcSource) ethGetBlockHeaders(ctx context.Context, blockNumbers []int64) ([]evmBlock, []error, error) {
+	blocks := make([]*evmBlock, len(blockNumbers))
+	calls := make([]*rpcBatchCall, len(blockNumbers))
+	for i, blockNumber := range blockNumbers {
+		calls[i] = &rpcBatchCall{
+			Method: "eth_getBlockByNumber",
+			Params: []interface{}{fmt.Sprintf("0x%x", blockNumber), false},
+			Out:    &blocks[i],
+		}
+	}
+
+	if err := s.rpc().batch(ctx, calls); err != nil {
+		return nil, nil, fmt.Errorf("eth_getBlockByNumber batch: %w", err)
+	}
+
+	headers := make([]evmBlock, len(blockNumbers))
+	errs := make([]error, len(blockNumbers))
+	for i, call := range calls {
+		switch {
+		case call.Err != nil:
+			errs[i] = fmt.Errorf("eth_getBlockByNumber %d: %w", blockNumbers[i], call.Err)
+		case blocks[i] == nil || blocks[i].Hash == "":
+			errs[i] = fmt.Errorf("eth_getBlockByNumber %d: %w", blockNumbers[i], errBlockNotFound)
+		default:
+			headers[i] = *blocks[i]
+		}
+	}
+
+	return headers, errs, nil
+}
+
+func (s EvmRpcSource) ethGetBlockHeader(ctx context.Context, blockNumber int64) (evmBlock, error) {
+	return s.ethGetBlock(ctx, fmt.Sprintf("0x%x", blockNumber))
+}
+
+// ethGetBlock fetches a block header by hex number or by tag such as "safe".
+func (s EvmRpcSource) ethGetBlock(ctx context.Context, blockParam string) (evmBlock, error) {
+	var block *evmBlock
+	if err := s.rpcCall(ctx, "eth_getBlockByNumber", []interface{}{blockParam, false}, &block); err != nil {
+		return evmBlock{}, fmt.Errorf("eth_getBlockByNumber: %w", err)
+	}
+	if block == nil || block.Hash == "" {
+		return evmBlock{}, fmt.Errorf("eth_getBlockByNumber %s: %w", blockParam, errBlockNotFound)
+	}
+
+	return *block, nil
+}
+
+func blockTimestamp(block evmBlock) (time.Time, error) {
 	timestampSeconds, err := parseHexInt64(block.Timestamp)
 	if err != nil {
 		return time.Time{}, err
@@ -287,6 +696,10 @@ func (s EvmRpcSource) ethBlockTimestamp(ctx context.Context, blockNumberHex stri
 	return time.Unix(timestampSeconds, 0).UTC(), nil
 }
 
+func isBlockNotFoundError(err error) bool {
+	return errors.Is(err, errBlockNotFound)
+}
+
 func (s EvmRpcSource) ethBlockNumber(ctx context.Context) (int64, error) {
 	var out string
 	if err := s.rpcCall(ctx, "eth_blockNumber", []interface{}{}, &out); err != nil {
@@ -295,13 +708,9 @@ func (s EvmRpcSource) ethBlockNumber(ctx context.Context) (int64, error) {
 	return parseHexInt64(out)
 }
 
-func (s EvmRpcSource) ethGetLogs(ctx context.Context, contract string, depositAddress string, fromBlock int64, toBlock int64) ([]evmLog, error) {
-	toTopic, err := encodeAddressTopic(depositAddress)
-	if err != nil {
-		return nil, err
-	}
-
-	return s.ethGetLogsAdaptive(ctx, contract, []interface{}{transferTopic, nil, toTopic}, fromBlock, toBlock)
+// ethGetLogs queries Transfer logs to any of the given recipient topics.
+func (s EvmRpcSource) ethGetLogs(ctx context.Context, contract string, toTopics []string, fromBlock int64, toBlock int64) ([]evmLog, error) {
+	return s.ethGetLogsAdaptive(ctx, contract, []interface{}{transferTopic, nil, toTopics}, fromBlock, toBlock)
 }
 
 func (s EvmRpcSource) ethGetLogsAdaptive(
@@ -311,6 +720,10 @@ func (s EvmRpcSource) ethGetLogsAdaptive(
 	fromBlock int64,
 	toBlock int64,
 ) ([]evmLog, error) {
+	if logs, ok := s.logCache.lookup(contract, topics, fromBlock, toBlock); ok {
+		return logs, nil
+	}
+
 	params := map[string]interface{}{
 		"fromBlock": fmt.Sprintf("0x%x", fromBlock),
 		"toBlock":   fmt.Sprintf("0x%x", toBlock),
@@ -319,7 +732,7 @@ func (s EvmRpcSource) ethGetLogsAdaptive(
 	}
 
 	var logs []evmLog
-	if err := s.rpcCall(ctx, "eth_getLogs", []interface{}{params}, &logs); err != nil {
+	if err := s.rpcCallWithBackoff(ctx, "eth_getLogs", []interface{}{params}, &logs); err != nil {
 		if isPayloadTooLargeError(err) && fromBlock < toBlock {
 			mid := fromBlock + ((toBlock - fromBlock) / 2)
 			left, leftErr := s.ethGetLogsAdaptive(ctx, contract, topics, fromBlock, mid)
@@ -358,58 +771,61 @@ func addressFromTopic(topic string) string {
 	return "0x" + strings.ToLower(topic[len(topic)-40:])
 }
 
-func isPayloadTooLargeError(err error) bool {
-	if err == nil {
-		return false
+func (s EvmRpcSource) rpc() rpcClient {
+	return rpcClient{
+		URL:            s.RPCURL,
+		Pool:           s.RPCPool,
+		HTTPClient:     s.HTTPClient,
+		DefaultTimeout: 8 * time.Second,
+		MaxBatchSize:   s.MaxBatchSize,
+		Budget:         s.Budget,
 	}
-	return strings.Contains(strings.ToLower(err.Error()), "status 413")
 }
 
-func (s EvmRpcSource) rpcCall(ctx context.Context, method string, params interface{}, out interface{}) error {
-	client := s.HTTPClient
-	if client == nil {
-		client = &http.Client{Timeout: 8 * time.Second}
-	}
-
-	reqBody, err := json.Marshal(rpcRequest{
-		JSONRPC: "2.0",
-		ID:      1,
-		Method:  method,
-		Params:  params,
-	})
-	if err != nil {
-		return err
-	}
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.RPCURL, bytes.NewReader(reqBody))
-	if err != nil {
-		return err
-	}
-	req.Header.Set("content-type", "application/json")
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
+// rpcCallWithBackoff retries calls the provider rate-limited, sleeping a
+// jittered exponential backoff in between. Every other error is returned at
+// once for the caller to split, skip or fail on.
+func (s EvmRpcSource) rpcCallWithBackoff(ctx context.Context, method string, params interface{}, out interface{}) error {
+	delay := s.effectiveRateLimitBackoff()
+	for attempt := 0; ; attempt++ {
+		err := s.rpcCall(ctx, method, params, out)
+		if err == nil || !isRateLimitedError(err) || attempt >= s.effectiveRateLimitRetries() {
+			return err
+		}
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return fmt.Errorf("rpc status %d", resp.StatusCode)
+		// Sleep between half and all of the delay so parallel workers spread out.
+		sleep := delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+		slog.Warn("base-rpc: rate limited, backing off",
+			"method", method,
+			"attempt", attempt+1,
+			"backoff", sleep.String(),
+			"error", err,
+		)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(sleep):
+		}
+		delay *= 2
 	}
+}
 
-	var rpcResp rpcResponse
-	if err := json.NewDecoder(resp.Body).Decode(&rpcResp); err != nil {
-		return err
-	}
-	if rpcResp.Error != nil {
-		return fmt.Errorf("rpc error %d: %s", rpcResp.Error.Code, rpcResp.Error.Message)
+func (s EvmRpcSource) effectiveRateLimitRetries() int {
+	if s.RateLimitRetries > 0 {
+		return s.RateLimitRetries
 	}
+	return 4
+}
 
-	if out == nil {
-		return nil
+func (s EvmRpcSource) effectiveRateLimitBackoff() time.Duration {
+	if s.RateLimitBackoff > 0 {
+		return s.RateLimitBackoff
 	}
+	return 500 * time.Millisecond
+}
 
-	return json.Unmarshal(rpcResp.Result, out)
+func (s EvmRpcSource) rpcCall(ctx context.Context, method string, params interface{}, out interface{}) error {
+	return s.rpc().call(ctx, method, params, out)
 }
 
 func parseHexInt64(input string) (int64, error) {
@@ -424,22 +840,19 @@ func parseHexInt64(input string) (int64, error) {
 	return value.Int64(), nil
 }
 
-func parseTokenAmountUSD(dataHex string) (float64, error) {
+// parseTransferAmount decodes a Transfer log's amount in base units.
+func parseTransferAmount(dataHex string) (*big.Int, error) {
 	trimmed := strings.TrimPrefix(strings.TrimSpace(dataHex), "0x")
 	if trimmed == "" {
-		return 0, nil
+		return big.NewInt(0), nil
 	}
 
 	rawBytes, err := hex.DecodeString(trimmed)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	amount := new(big.Int).SetBytes(rawBytes)
-	f := new(big.Float).SetInt(amount)
-	usd := new(big.Float).Quo(f, big.NewFloat(1_000_000))
-	result, _ := usd.Float64()
-	return result, nil
+	return new(big.Int).SetBytes(rawBytes), nil
 }
 
 func encodeAddressTopic(address string) (string, error) {
@@ -0,0 +1,823 @@
+package internal
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const (
+	testUSDCContract = "0x833589fcd6edb6e08f4c7c32d4f71b54bda02913"
+	testDepositAddr  = "0x1111111111111111111111111111111111111111"
+	testPayerTopic   = "0x0000000000000000000000002222222222222222222222222222222222222222"
+)
+
+type routeStoreStub struct {
+	routes []ActiveRoute
+}
+
+func (r routeStoreStub) ListActiveRoutes(_ context.Context, _ string) ([]ActiveRoute, error) {
+	return r.routes, nil
+}
+
+// fakeEvmNode serves the subset of the Ethereum JSON-RPC API used by EvmRpcSource
+// from an in-memory chain that tests can mutate between polls.
+type fakeEvmNode struct {
+	mu        sync.Mutex
+	head      int64
+	safe      int64
+	finalized int64
+	hashes    map[int64]string
+	logs      []evmLog
+	failedTxs map[string]bool
+	decimals  map[string]int64
+	calls     map[string]int
+
+	// txs and traces hold native ETH transfers; a nil traces map makes
+	// trace_block unsupported.
+	txs    map[int64][]evmTransaction
+	traces map[int64][]parityTrace
+
+	// pinnedRoots fixes a block's receiptsRoot so tests can make the node
+	// serve receipts the header does not commit to.
+	pinnedRoots map[int64]string
+
+	// missingReceipts makes eth_getBlockReceipts return null for a block.
+	missingReceipts map[int64]bool
+
+	// logsErr, when set, may fail an eth_getLogs call for the queried range.
+	logsErr func(from int64, to int64) *rpcError
+}
+
+func newFakeEvmNode(head int64) *fakeEvmNode {
+	node := &fakeEvmNode{head: head, hashes: map[int64]string{}, calls: map[string]int{}}
+	for n := int64(0); n <= head; n++ {
+		node.hashes[n] = fmt.Sprintf("0xa%063x", n)
+	}
+	return node
+}
+
+// reorg replaces every block from the given height with a new branch.
+func (n *fakeEvmNode) reorg(from int64, branch string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for number := from; number <= n.head; number++ {
+		n.hashes[number] = fmt.Sprintf("0x%s%063x", branch, number)
+	}
+	kept := n.logs[:0]
+	for _, eventLog := range n.logs {
+		blockNumber, _ := parseHexInt64(eventLog.BlockNumber)
+		if blockNumber < from {
+			kept = append(kept, eventLog)
+		}
+	}
+	n.logs = kept
+}
+
+func (n *fakeEvmNode) extend(blocks int64) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for i := int64(0); i < blocks; i++ {
+		n.head++
+		n.hashes[n.head] = fmt.Sprintf("0xa%063x", n.head)
+	}
+}
+
+func (n *fakeEvmNode) addTransfer(blockNumber int64, txHash string, logIndex int, to string, amount int64) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	toTopic, _ := encodeAddressTopic(to)
+	n.logs = append(n.logs, evmLog{
+		TxHash:      txHash,
+		LogIndex:    fmt.Sprintf("0x%x", logIndex),
+		BlockNumber: fmt.Sprintf("0x%x", blockNumber),
+		BlockHash:   n.hashes[blockNumber],
+		Data:        fmt.Sprintf("0x%064x", amount),
+		Topics:      []string{transferTopic, testPayerTopic, toTopic},
+		Address:     testUSDCContract,
+	})
+}
+
+func (n *fakeEvmNode) block(number int64) map[string]any {
+	hash, ok := n.hashes[number]
+	if !ok || number > n.head {
+		return nil
+	}
+	parent := ""
+	if number > 0 {
+		parent = n.hashes[number-1]
+	}
+	root, ok := n.pinnedRoots[number]
+	if !ok {
+		computed, _ := receiptsRoot(n.blockReceipts(number))
+		root = "0x" + hex.EncodeToString(computed)
+	}
+	zeroHash := "0x" + strings.Repeat("00", 32)
+	return map[string]any{
+		"number":           fmt.Sprintf("0x%x", number),
+		"hash":             hash,
+		"parentHash":       parent,
+		"timestamp":        fmt.Sprintf("0x%x", 1_770_000_000+number*2),
+		"receiptsRoot":     root,
+		"sha3Uncles":       "0x1dcc4de8dec75d7aab85b567b6ccd41ad312451b948a7413f0a142fd40d49347",
+		"miner":            "0x4200000000000000000000000000000000000011",
+		"stateRoot":        zeroHash,
+		"transactionsRoot": zeroHash,
+		"logsBloom":        "0x" + strings.Repeat("00", 256),
+		"difficulty":       "0x0",
+		"gasLimit":         "0x1c9c380",
+		"gasUsed":          "0x0",
+		"extraData":        "0x",
+		"mixHash":          zeroHash,
+		"nonce":            "0x0000000000000000",
+		"baseFeePerGas":    "0x1",
+	}
+}
+
+// sealBlock replaces a block's made-up hash with the hash of its header, for
+// tests that check headers against their hash.
+func (n *fakeEvmNode) sealBlock(number int64) {
+	raw, _ := json.Marshal(n.block(number))
+	var header evmHeader
+	_ = json.Unmarshal(raw, &header)
+	sealed, _ := headerHash(header)
+	hash := "0x" + hex.EncodeToString(sealed)
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.hashes[number] = hash
+	for i, eventLog := range n.logs {
+		if eventLog.BlockNumber == fmt.Sprintf("0x%x", number) {
+			n.logs[i].BlockHash = hash
+		}
+	}
+}
+
+func (n *fakeEvmNode) blockNumberByHash(hash string) (int64, bool) {
+	for number, candidate := range n.hashes {
+		if strings.EqualFold(candidate, hash) && number <= n.head {
+			return number, true
+		}
+	}
+	return 0, false
+}
+
+// blockReceipts returns one receipt per transaction with logs in the block, in
+// the order the transactions' logs were added.
+func (n *fakeEvmNode) blockReceipts(number int64) []evmReceipt {
+	receipts := make([]evmReceipt, 0)
+	byTx := map[string]int{}
+	for _, eventLog := range n.logs {
+		blockNumber, _ := parseHexInt64(eventLog.BlockNumber)
+		if blockNumber != number {
+			continue
+		}
+		i, ok := byTx[eventLog.TxHash]
+		if !ok {
+			status := "0x1"
+			if n.failedTxs[eventLog.TxHash] {
+				status = "0x0"
+			}
+			i = len(receipts)
+			byTx[eventLog.TxHash] = i
+			receipts = append(receipts, evmReceipt{
+				TransactionHash:   eventLog.TxHash,
+				TransactionIndex:  fmt.Sprintf("0x%x", i),
+				BlockHash:         eventLog.BlockHash,
+				BlockNumber:       eventLog.BlockNumber,
+				Type:              "0x2",
+				Status:            status,
+				CumulativeGasUsed: fmt.Sprintf("0x%x", 52_000*(i+1)),
+				LogsBloom:         "0x" + strings.Repeat("00", 256),
+			})
+		}
+		receipts[i].Logs = append(receipts[i].Logs, eventLog)
+	}
+	return receipts
+}
+
+func (n *fakeEvmNode) receipt(txHash string) *evmReceipt {
+	for _, eventLog := range n.logs {
+		if eventLog.TxHash != txHash {
+			continue
+		}
+		blockNumber, _ := parseHexInt64(eventLog.BlockNumber)
+		for _, receipt := range n.blockReceipts(blockNumber) {
+			if receipt.TransactionHash == txHash {
+				return &receipt
+			}
+		}
+	}
+	return nil
+}
+
+// addNativeTransfer adds a transaction sending wei from the payer to "to".
+func (n *fakeEvmNode) addNativeTransfer(blockNumber int64, txHash string, to string, wei int64) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.txs == nil {
+		n.txs = map[int64][]evmTransaction{}
+	}
+	n.txs[blockNumber] = append(n.txs[blockNumber], evmTransaction{
+		Hash:  txHash,
+		From:  "0x" + testPayerTopic[26:],
+		To:    to,
+		Value: fmt.Sprintf("0x%x", wei),
+	})
+}
+
+// addInternalTransfer adds a call carrying wei to "to" below a transaction's
+// top-level call, in trace_block format.
+func (n *fakeEvmNode) addInternalTransfer(blockNumber int64, txHash string, to string, wei int64, failed bool, traceAddress ...int) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.traces == nil {
+		n.traces = map[int64][]parityTrace{}
+	}
+	trace := parityTrace{Type: "call", TransactionHash: txHash, TraceAddress: traceAddress}
+	trace.Action.CallType = "call"
+	trace.Action.From = "0x3333333333333333333333333333333333333333"
+	trace.Action.To = to
+	trace.Action.Value = fmt.Sprintf("0x%x", wei)
+	if failed {
+		trace.Error = "Reverted"
+	}
+	n.traces[blockNumber] = append(n.traces[blockNumber], trace)
+}
+
+// nativeReceipt returns a log-less receipt for a transaction added with
+// addNativeTransfer or addInternalTransfer.
+func (n *fakeEvmNode) nativeReceipt(txHash string) *evmReceipt {
+	blockNumber := int64(-1)
+	for number, txs := range n.txs {
+		for _, tx := range txs {
+			if tx.Hash == txHash {
+				blockNumber = number
+			}
+		}
+	}
+	for number, traces := range n.traces {
+		for _, trace := range traces {
+			if trace.TransactionHash == txHash {
+				blockNumber = number
+			}
+		}
+	}
+	if blockNumber < 0 {
+		return nil
+	}
+	status := "0x1"
+	if n.failedTxs[txHash] {
+		status = "0x0"
+	}
+	return &evmReceipt{
+		TransactionHash: txHash,
+		BlockHash:       n.hashes[blockNumber],
+		BlockNumber:     fmt.Sprintf("0x%x", blockNumber),
+		Status:          status,
+	}
+}
+
+func (n *fakeEvmNode) handle(method string, params []json.RawMessage) (any, *rpcError) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.calls[method]++
+
+	switch method {
+	case "eth_blockNumber":
+		return fmt.Sprintf("0x%x", n.head), nil
+	case "eth_call":
+		var call struct {
+			To   string `json:"to"`
+			Data string `json:"data"`
+		}
+		_ = json.Unmarshal(params[0], &call)
+		decimals, ok := n.decimals[strings.ToLower(call.To)]
+		if !ok || call.Data != decimalsSelector {
+			return nil, &rpcError{Code: 3, Message: "execution reverted"}
+		}
+		return fmt.Sprintf("0x%064x", decimals), nil
+	case "eth_getBlockByNumber":
+		var tag string
+		_ = json.Unmarshal(params[0], &tag)
+		switch tag {
+		case "latest":
+			return n.block(n.head), nil
+		case "safe":
+			return n.block(n.safe), nil
+		case "finalized":
+			return n.block(n.finalized), nil
+		}
+		number, err := parseHexInt64(tag)
+		if err != nil {
+			return nil, &rpcError{Code: -32602, Message: "invalid block"}
+		}
+		var fullTxs bool
+		if len(params) > 1 {
+			_ = json.Unmarshal(params[1], &fullTxs)
+		}
+		block := n.block(number)
+		if fullTxs && block != nil {
+			txs := n.txs[number]
+			if txs == nil {
+				txs = []evmTransaction{}
+			}
+			block["transactions"] = txs
+		}
+		return block, nil
+	case "eth_getLogs":
+		var filter struct {
+			FromBlock string            `json:"fromBlock"`
+			ToBlock   string            `json:"toBlock"`
+			Address   string            `json:"address"`
+			Topics    []json.RawMessage `json:"topics"`
+		}
+		_ = json.Unmarshal(params[0], &filter)
+		from, _ := parseHexInt64(filter.FromBlock)
+		to, _ := parseHexInt64(filter.ToBlock)
+		if n.logsErr != nil {
+			if rpcErr := n.logsErr(from, to); rpcErr != nil {
+				return nil, rpcErr
+			}
+		}
+		toTopics := map[string]bool{}
+		if len(filter.Topics) >= 3 {
+			var single string
+			var many []string
+			if json.Unmarshal(filter.Topics[2], &single) == nil && single != "" {
+				toTopics[strings.ToLower(single)] = true
+			} else if json.Unmarshal(filter.Topics[2], &many) == nil {
+				for _, topic := range many {
+					toTopics[strings.ToLower(topic)] = true
+				}
+			}
+		}
+		out := make([]evmLog, 0)
+		for _, eventLog := range n.logs {
+			blockNumber, _ := parseHexInt64(eventLog.BlockNumber)
+			if blockNumber < from || blockNumber > to {
+				continue
+			}
+			if !strings.EqualFold(eventLog.Address, filter.Address) {
+				continue
+			}
+			if len(toTopics) > 0 && !toTopics[strings.ToLower(eventLog.Topics[2])] {
+				continue
+			}
+			out = append(out, eventLog)
+		}
+		return out, nil
+	case "eth_getBlockByHash":
+		var hash string
+		_ = json.Unmarshal(params[0], &hash)
+		number, ok := n.blockNumberByHash(hash)
+		if !ok {
+			return nil, nil
+		}
+		return n.block(number), nil
+	case "eth_getBlockReceipts":
+		var ref string
+		_ = json.Unmarshal(params[0], &ref)
+		number, ok := n.blockNumberByHash(ref)
+		if !ok {
+			parsed, err := parseHexInt64(ref)
+			if err != nil {
+				return nil, nil
+			}
+			number = parsed
+		}
+		if n.missingReceipts[number] {
+			return nil, nil
+		}
+		return n.blockReceipts(number), nil
+	case "eth_getTransactionReceipt":
+		var txHash string
+		_ = json.Unmarshal(params[0], &txHash)
+		if receipt := n.receipt(txHash); receipt != nil {
+			return receipt, nil
+		}
+		return n.nativeReceipt(txHash), nil
+	case "trace_block":
+		if n.traces == nil {
+			return nil, &rpcError{Code: -32601, Message: "the method trace_block does not exist/is not available"}
+		}
+		var tag string
+		_ = json.Unmarshal(params[0], &tag)
+		number, _ := parseHexInt64(tag)
+		traces := n.traces[number]
+		if traces == nil {
+			traces = []parityTrace{}
+		}
+		return traces, nil
+	default:
+		return nil, &rpcError{Code: -32601, Message: "method not found"}
+	}
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type fakeRPCRequest struct {
+	ID     int               `json:"id"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+}
+
+func (n *fakeEvmNode) respond(req fakeRPCRequest) map[string]any {
+	result, rpcErr := n.handle(req.Method, req.Params)
+	resp := map[string]any{"jsonrpc": "2.0", "id": req.ID}
+	if rpcErr != nil {
+		resp["error"] = rpcErr
+	} else {
+		resp["result"] = result
+	}
+	return resp
+}
+
+func (n *fakeEvmNode) serve(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var raw json.RawMessage
+		if err := json.NewDecoder(r.Body).Decode(&raw); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if strings.HasPrefix(strings.TrimSpace(string(raw)), "[") {
+			var reqs []fakeRPCRequest
+			_ = json.Unmarshal(raw, &reqs)
+			resps := make([]map[string]any, 0, len(reqs))
+			for _, req := range reqs {
+				resps = append(resps, n.respond(req))
+			}
+			_ = json.NewEncoder(w).Encode(resps)
+			return
+		}
+		var req fakeRPCRequest
+		_ = json.Unmarshal(raw, &req)
+		_ = json.NewEncoder(w).Encode(n.respond(req))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// mustTokenRegistry builds a registry for tests, defaulting to USDC with 6
+// decimals.
+func mustTokenRegistry(tokens ...TokenInfo) *TokenRegistry {
+	if len(tokens) == 0 {
+		tokens = []TokenInfo{{Symbol: "USDC", Contract: testUSDCContract, Decimals: 6}}
+	}
+	registry, err := NewTokenRegistry(tokens)
+	if err != nil {
+		panic(err)
+	}
+	return registry
+}
+
+func newTestEvmSource(url string) EvmRpcSource {
+	return EvmRpcSource{
+		RPCURL:                 url,
+		RouteStore:             routeStoreStub{routes: []ActiveRoute{{TransferID: "tr_1", Token: "USDC", DepositAddress: testDepositAddr}}},
+		Tokens:                 mustTokenRegistry(),
+		Chain:                  "base",
+		FinalizedConfirmations: 1,
+	}
+}
+
+func TestEvmRpcSource_PollAcceptsLegacyNumericCursor(t *testing.T) {
+	node := newFakeEvmNode(110)
+	node.addTransfer(105, "0xtx1", 0, testDepositAddr, 5_000_000)
+	source := newTestEvmSource(node.serve(t).URL)
+
+	candidates, next, err := source.Poll(context.Background(), "100")
+	if err != nil {
+		t.Fatalf("unexpected poll error: %v", err)
+	}
+	if len(candidates) != 1 || candidates[0].TxHash != "0xtx1" || candidates[0].AmountUSD != 5 {
+		t.Fatalf("unexpected candidates: %+v", candidates)
+	}
+
+	cursor := parseEvmCursor(next)
+	if cursor.Block != 110 {
+		t.Fatalf("expected cursor block 110, got %d", cursor.Block)
+	}
+	if cursor.hashAt(110) != node.hashes[110] {
+		t.Fatalf("expected tip hash to be tracked")
+	}
+	if len(cursor.Candidates) != 1 {
+		t.Fatalf("expected candidate to be tracked, got %d", len(cursor.Candidates))
+	}
+}
+
+func TestEvmRpcSource_PollEmitsRevertedCandidatesAfterReorg(t *testing.T) {
+	node := newFakeEvmNode(110)
+	node.addTransfer(108, "0xorphan", 1, testDepositAddr, 7_000_000)
+	node.addTransfer(109, "0xmoved", 0, testDepositAddr, 3_000_000)
+	source := newTestEvmSource(node.serve(t).URL)
+
+	_, cursor, err := source.Poll(context.Background(), "100")
+	if err != nil {
+		t.Fatalf("unexpected poll error: %v", err)
+	}
+
+	node.reorg(107, "b")
+	node.extend(3)
+	node.addTransfer(111, "0xmoved", 0, testDepositAddr, 3_000_000)
+
+	candidates, next, err := source.Poll(context.Background(), cursor)
+	if err != nil {
+		t.Fatalf("unexpected poll error: %v", err)
+	}
+
+	var reverted, fresh []FundingCandidate
+	for _, candidate := range candidates {
+		if candidate.Reverted {
+			reverted = append(reverted, candidate)
+		} else {
+			fresh = append(fresh, candidate)
+		}
+	}
+	if len(reverted) != 1 || reverted[0].TxHash != "0xorphan" || reverted[0].TransferID != "tr_1" {
+		t.Fatalf("expected only the orphaned candidate to be reverted, got %+v", reverted)
+	}
+	if reverted[0].Metadata["reorgAncestorBlock"] != int64(106) {
+		t.Fatalf("expected common ancestor 106, got %v", reverted[0].Metadata["reorgAncestorBlock"])
+	}
+	if len(fresh) != 1 || fresh[0].TxHash != "0xmoved" {
+		t.Fatalf("expected re-included candidate from rescan, got %+v", fresh)
+	}
+
+	nextCursor := parseEvmCursor(next)
+	if nextCursor.Block != 113 {
+		t.Fatalf("expected cursor block 113, got %d", nextCursor.Block)
+	}
+	if nextCursor.hashAt(108) != node.hashes[108] {
+		t.Fatalf("expected rewound blocks to track the new branch")
+	}
+}
+
+func TestEvmRpcSource_PollKeepsCursorWithoutNewBlocks(t *testing.T) {
+	node := newFakeEvmNode(110)
+	source := newTestEvmSource(node.serve(t).URL)
+
+	_, cursor, err := source.Poll(context.Background(), "100")
+	if err != nil {
+		t.Fatalf("unexpected poll error: %v", err)
+	}
+
+	candidates, next, err := source.Poll(context.Background(), cursor)
+	if err != nil {
+		t.Fatalf("unexpected poll error: %v", err)
+	}
+	if len(candidates) != 0 || next != cursor {
+		t.Fatalf("expected unchanged cursor, got %s", next)
+	}
+}
+
+func TestEvmRpcSource_PollBoundsScanBySafeTag(t *testing.T) {
+	node := newFakeEvmNode(120)
+	node.safe = 110
+	node.addTransfer(108, "0xsafe", 0, testDepositAddr, 1_000_000)
+	node.addTransfer(115, "0xunsafe", 0, testDepositAddr, 1_000_000)
+	source := newTestEvmSource(node.serve(t).URL)
+	source.FinalityMode = FinalitySafe
+	source.FinalizedConfirmations = 50
+
+	candidates, next, err := source.Poll(context.Background(), "100")
+	if err != nil {
+		t.Fatalf("unexpected poll error: %v", err)
+	}
+	if len(candidates) != 1 || candidates[0].TxHash != "0xsafe" {
+		t.Fatalf("expected only the safe candidate, got %+v", candidates)
+	}
+	if !candidates[0].Finalized {
+		t.Fatalf("expected candidate below the safe head to be finalized")
+	}
+	if candidates[0].Confirmations != 13 {
+		t.Fatalf("expected confirmations relative to latest head, got %d", candidates[0].Confirmations)
+	}
+	if parseEvmCursor(next).Block != 110 {
+		t.Fatalf("expected cursor at safe head, got %s", next)
+	}
+}
+
+func TestEvmRpcSource_PollBatchesRouteAddressesIntoTopicChunks(t *testing.T) {
+	node := newFakeEvmNode(110)
+	routes := make([]ActiveRoute, 0, 5)
+	for i := 0; i < 5; i++ {
+		address := fmt.Sprintf("0x%040x", 0x1000+i)
+		routes = append(routes, ActiveRoute{TransferID: fmt.Sprintf("tr_%d", i), Token: "USDC", DepositAddress: address})
+		node.addTransfer(102+int64(i), fmt.Sprintf("0xtx%d", i), 0, address, 1_000_000)
+	}
+	source := newTestEvmSource(node.serve(t).URL)
+	source.RouteStore = routeStoreStub{routes: routes}
+	source.MaxTopicAddresses = 2
+
+	candidates, _, err := source.Poll(context.Background(), "100")
+	if err != nil {
+		t.Fatalf("unexpected poll error: %v", err)
+	}
+	if node.calls["eth_getLogs"] != 3 {
+		t.Fatalf("expected 3 chunked eth_getLogs calls, got %d", node.calls["eth_getLogs"])
+	}
+	if len(candidates) != 5 {
+		t.Fatalf("expected 5 candidates, got %d", len(candidates))
+	}
+	for _, candidate := range candidates {
+		want := "tr_" + strings.TrimPrefix(candidate.TxHash, "0xtx")
+		if candidate.TransferID != want {
+			t.Fatalf("expected %s mapped to %s, got %s", candidate.TxHash, want, candidate.TransferID)
+		}
+	}
+}
+
+func TestEvmRpcSource_BlockReceiptsModeFiltersTransfersInMemory(t *testing.T) {
+	node := newFakeEvmNode(110)
+	node.addTransfer(103, "0xroute", 0, testDepositAddr, 2_000_000)
+	node.addTransfer(104, "0xqr", 0, "0x3333333333333333333333333333333333333333", 5_000_000)
+	node.addTransfer(105, "0xother", 0, testDepositAddr, 9_000_000)
+	node.logs[2].Address = "0x4444444444444444444444444444444444444444"
+
+	source := newTestEvmSource(node.serve(t).URL)
+	source.ScanMode = ScanBlockReceipts
+	source.ReceiptsBatchSize = 4
+
+	candidates, _, err := source.Poll(context.Background(), "100")
+	if err != nil {
+		t.Fatalf("unexpected poll error: %v", err)
+	}
+	if node.calls["eth_getLogs"] != 0 {
+		t.Fatalf("expected no eth_getLogs calls, got %d", node.calls["eth_getLogs"])
+	}
+	if node.calls["eth_getBlockReceipts"] != 10 {
+		t.Fatalf("expected one eth_getBlockReceipts per block, got %d", node.calls["eth_getBlockReceipts"])
+	}
+	if len(candidates) != 1 || candidates[0].TxHash != "0xroute" || candidates[0].TransferID != "tr_1" {
+		t.Fatalf("expected only the routed transfer, got %+v", candidates)
+	}
+	if candidates[0].ConfirmedAt.IsZero() {
+		t.Fatalf("expected block timestamp on candidate")
+	}
+
+	source.FactoryLevelScan = true
+	candidates, _, err = source.Poll(context.Background(), "100")
+	if err != nil {
+		t.Fatalf("unexpected poll error: %v", err)
+	}
+	if len(candidates) != 2 || candidates[1].TxHash != "0xqr" || candidates[1].TransferID != "" {
+		t.Fatalf("expected routed and factory-level candidates, got %+v", candidates)
+	}
+
+	// Receipts are fetched one batch at a time, so a failing batch stops the
+	// scan before the rest of the range is downloaded.
+	node.missingReceipts = map[int64]bool{102: true}
+	node.calls["eth_getBlockReceipts"] = 0
+	if _, _, err := source.Poll(context.Background(), "100"); err == nil {
+		t.Fatalf("expected missing receipts to fail the poll")
+	}
+	if node.calls["eth_getBlockReceipts"] != 4 {
+		t.Fatalf("expected only the first batch fetched, got %d blocks", node.calls["eth_getBlockReceipts"])
+	}
+}
+
+func TestEvmRpcSource_PlansAndPollsBacklogRanges(t *testing.T) {
+	node := newFakeEvmNode(110)
+	node.addTransfer(60, "0xmid", 0, testDepositAddr, 1_000_000)
+	source := newTestEvmSource(node.serve(t).URL)
+	source.MaxBlockSpan = 50
+
+	backlog, err := source.Backlog(context.Background(), "0")
+	if err != nil || backlog != 110 {
+		t.Fatalf("expected backlog of 110 blocks, got %d (%v)", backlog, err)
+	}
+
+	ranges, err := source.PlanBacklog(context.Background(), "0", 2)
+	if err != nil {
+		t.Fatalf("unexpected plan error: %v", err)
+	}
+	if len(ranges) != 2 || ranges[0] != (ScanRange{From: 1, To: 50, After: "0"}) || ranges[1] != (ScanRange{From: 51, To: 100}) {
+		t.Fatalf("unexpected ranges %+v", ranges)
+	}
+
+	candidates, cursor, err := source.PollRange(context.Background(), ranges[1])
+	if err != nil {
+		t.Fatalf("unexpected range poll error: %v", err)
+	}
+	if len(candidates) != 1 || !candidates[0].Finalized {
+		t.Fatalf("expected one finalized candidate, got %+v", candidates)
+	}
+	if parseEvmCursor(cursor).Block != 100 {
+		t.Fatalf("expected cursor at range end, got %s", cursor)
+	}
+}
+
+func TestEvmRpcSource_BacklogRangesContinueTheCommittedCursor(t *testing.T) {
+	node := newFakeEvmNode(110)
+	node.addTransfer(108, "0xorphan", 1, testDepositAddr, 7_000_000)
+	source := newTestEvmSource(node.serve(t).URL)
+	source.MaxBlockSpan = 50
+
+	_, cursor, err := source.Poll(context.Background(), "100")
+	if err != nil {
+		t.Fatalf("unexpected poll error: %v", err)
+	}
+
+	node.reorg(107, "b")
+	node.extend(150)
+
+	ranges, err := source.PlanBacklog(context.Background(), cursor, 2)
+	if err != nil || len(ranges) != 2 {
+		t.Fatalf("expected two ranges, got %+v (%v)", ranges, err)
+	}
+	candidates, first, err := source.PollRange(context.Background(), ranges[0])
+	if err != nil {
+		t.Fatalf("unexpected range poll error: %v", err)
+	}
+	if len(candidates) != 1 || !candidates[0].Reverted || candidates[0].TxHash != "0xorphan" {
+		t.Fatalf("expected the orphaned candidate to be reverted, got %+v", candidates)
+	}
+	if parseEvmCursor(first).hashAt(108) != node.hashes[108] {
+		t.Fatalf("expected rewound blocks to track the new branch")
+	}
+
+	_, second, err := source.PollRange(context.Background(), ranges[1])
+	if err != nil {
+		t.Fatalf("unexpected range poll error: %v", err)
+	}
+	merged := parseEvmCursor(source.MergeRangeCursor(first, second))
+	if merged.Block != ranges[1].To {
+		t.Fatalf("expected merged cursor at range end, got %d", merged.Block)
+	}
+	window := source.effectiveReorgWindow()
+	for block := merged.Block - window + 1; block <= merged.Block; block++ {
+		if merged.hashAt(block) != node.hashes[block] {
+			t.Fatalf("expected merged cursor to track block %d", block)
+		}
+	}
+}
+
+func TestEvmRpcSource_TokenDecimalsFromChain(t *testing.T) {
+	node := newFakeEvmNode(110)
+	node.decimals = map[string]int64{testUSDCContract: 18}
+	node.addTransfer(105, "0xtx18", 0, testDepositAddr, 2_500_000_000_000_000_000)
+	source := newTestEvmSource(node.serve(t).URL)
+	source.Tokens = mustTokenRegistry(TokenInfo{Symbol: "USDC", Contract: testUSDCContract})
+
+	if err := source.VerifyTokenDecimals(context.Background()); err != nil {
+		t.Fatalf("unexpected verify error: %v", err)
+	}
+	if token, _ := source.Tokens.Token("usdc"); token.Decimals != 18 {
+		t.Fatalf("expected decimals read from chain, got %d", token.Decimals)
+	}
+
+	candidates, _, err := source.Poll(context.Background(), "100")
+	if err != nil {
+		t.Fatalf("unexpected poll error: %v", err)
+	}
+	if len(candidates) != 1 || candidates[0].AmountUSD != 2.5 {
+		t.Fatalf("expected 2.5 USD from an 18-decimal transfer, got %+v", candidates)
+	}
+	if candidates[0].AmountBaseUnits != "2500000000000000000" || candidates[0].TokenDecimals != 18 || candidates[0].Amount != "2.5" {
+		t.Fatalf("expected exact base-unit amount, got %+v", candidates[0])
+	}
+}
+
+func TestFormatBaseUnits(t *testing.T) {
+	cases := []struct {
+		baseUnits string
+		decimals  int
+		want      string
+	}{
+		{"2500000", 6, "2.5"},
+		{"100000000", 6, "100"},
+		{"1", 6, "0.000001"},
+		{"0", 6, "0"},
+		{"99999999999999999999", 18, "99.999999999999999999"},
+		{"42", 0, "42"},
+	}
+	for _, tc := range cases {
+		baseUnits, _ := new(big.Int).SetString(tc.baseUnits, 10)
+		if got := formatBaseUnits(baseUnits, tc.decimals); got != tc.want {
+			t.Fatalf("formatBaseUnits(%s, %d) = %q, want %q", tc.baseUnits, tc.decimals, got, tc.want)
+		}
+	}
+}
+
+func TestEvmRpcSource_TokenDecimalsMismatch(t *testing.T) {
+	node := newFakeEvmNode(110)
+	node.decimals = map[string]int64{testUSDCContract: 18}
+	source := newTestEvmSource(node.serve(t).URL)
+
+	err := source.VerifyTokenDecimals(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "configured with 6 decimals but contract reports 18") {
+		t.Fatalf("expected decimals mismatch error, got %v", err)
+	}
+}
@@ -0,0 +1,446 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EvmWebsocketSource is a push-mode CandidateSource. It keeps eth_subscribe
+// subscriptions to newHeads and to Transfer logs of the token contracts open,
+// wakes the runner on every new head and serves eth_getLogs queries for blocks
+// the subscription saw from memory.
+//
+// Polling, reorg handling and finality stay in the wrapped EvmRpcSource. Any
+// range the live subscription did not fully cover (before the first head,
+// after a reconnect, a skipped head or a removed log) is read through the
+// HTTP eth_getLogs path, so a dropped connection never leaves a gap.
+type EvmWebsocketSource struct {
+	WSURL             string
+	Source            EvmRpcSource
+	Logger            *slog.Logger
+	ReconnectDelay    time.Duration // first delay after a dropped connection (default: 1s)
+	MaxReconnectDelay time.Duration // cap for repeated failures (default: 30s)
+	IdleTimeout       time.Duration // reconnect when no message arrives for this long (default: 60s)
+
+	buffer *wsLogBuffer
+	wake   chan struct{}
+}
+
+// NewEvmWebsocketSource wraps an HTTP source with a WebSocket subscription.
+func NewEvmWebsocketSource(wsURL string, source EvmRpcSource, logger *slog.Logger) *EvmWebsocketSource {
+	contracts := make(map[string]bool)
+	for _, token := range source.Tokens.Tokens() {
+		contracts[token.Contract] = true
+	}
+	return &EvmWebsocketSource{
+		WSURL:  wsURL,
+		Source: source,
+		Logger: logger,
+		buffer: newWsLogBuffer(contracts),
+		wake:   make(chan struct{}, 1),
+	}
+}
+
+// Wake fires whenever a new head arrives.
+func (s *EvmWebsocketSource) Wake() <-chan struct{} {
+	return s.wake
+}
+
+func (s *EvmWebsocketSource) Poll(ctx context.Context, cursor string) ([]FundingCandidate, string, error) {
+	source := s.Source
+	source.logCache = s.buffer
+	return source.Poll(ctx, cursor)
+}
+
+func (s *EvmWebsocketSource) Backlog(ctx context.Context, cursor string) (int64, error) {
+	return s.Source.Backlog(ctx, cursor)
+}
+
+func (s *EvmWebsocketSource) PlanBacklog(ctx context.Context, cursor string, maxRanges int) ([]ScanRange, error) {
+	return s.Source.PlanBacklog(ctx, cursor, maxRanges)
+}
+
+func (s *EvmWebsocketSource) CursorAt(ctx context.Context, at time.Time) (string, error) {
+	return s.Source.CursorAt(ctx, at)
+}
+
+func (s *EvmWebsocketSource) CursorBefore(cursor string, candidate FundingCandidate) (string, bool) {
+	return s.Source.CursorBefore(cursor, candidate)
+}
+
+func (s *EvmWebsocketSource) MergeRangeCursor(committed string, next string) string {
+	return s.Source.MergeRangeCursor(committed, next)
+}
+
+func (s *EvmWebsocketSource) PollRange(ctx context.Context, r ScanRange) ([]FundingCandidate, string, error) {
+	source := s.Source
+	source.logCache = s.buffer
+	return source.PollRange(ctx, r)
+}
+
+// Start keeps the subscription alive in the background until ctx is done.
+func (s *EvmWebsocketSource) Start(ctx context.Context) {
+	go s.run(ctx)
+}
+
+func (s *EvmWebsocketSource) logger() *slog.Logger {
+	if s.Logger != nil {
+		return s.Logger
+	}
+	return slog.Default()
+}
+
+func (s *EvmWebsocketSource) effectiveReconnectDelay() time.Duration {
+	if s.ReconnectDelay > 0 {
+		return s.ReconnectDelay
+	}
+	return time.Second
+}
+
+func (s *EvmWebsocketSource) effectiveMaxReconnectDelay() time.Duration {
+	if s.MaxReconnectDelay > 0 {
+		return s.MaxReconnectDelay
+	}
+	return 30 * time.Second
+}
+
+func (s *EvmWebsocketSource) effectiveIdleTimeout() time.Duration {
+	if s.IdleTimeout > 0 {
+		return s.IdleTimeout
+	}
+	return 60 * time.Second
+}
+
+func (s *EvmWebsocketSource) run(ctx context.Context) {
+	delay := s.effectiveReconnectDelay()
+	for {
+		subscribed, err := s.session(ctx)
+		lastCovered := s.buffer.reset()
+		if ctx.Err() != nil {
+			return
+		}
+		if subscribed {
+			delay = s.effectiveReconnectDelay()
+		}
+
+		s.logger().Warn("base-ws: subscription dropped, reconnecting; missed blocks are backfilled over http",
+			"endpoint", redactRPCURL(s.WSURL),
+			"lastCoveredBlock", lastCovered,
+			"reconnectIn", delay.String(),
+			"error", err,
+		)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay *= 2
+		if delay > s.effectiveMaxReconnectDelay() {
+			delay = s.effectiveMaxReconnectDelay()
+		}
+	}
+}
+
+type wsMessage struct {
+	ID     *int            `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+	Method string `json:"method"`
+	Params struct {
+		Subscription string          `json:"subscription"`
+		Result       json.RawMessage `json:"result"`
+	} `json:"params"`
+}
+
+const (
+	wsHeadsRequestID = 1
+	wsLogsRequestID  = 2
+)
+
+// session runs one connection until it fails. It reports whether both
+// subscriptions were established.
+func (s *EvmWebsocketSource) session(ctx context.Context) (bool, error) {
+	conn, err := dialWebsocket(ctx, s.WSURL)
+	if err != nil {
+		return false, err
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-done:
+		}
+		conn.close()
+	}()
+
+	contracts := s.buffer.contractList()
+	requests := []rpcRequest{
+		{JSONRPC: "2.0", ID: wsHeadsRequestID, Method: "eth_subscribe", Params: []interface{}{"newHeads"}},
+		{JSONRPC: "2.0", ID: wsLogsRequestID, Method: "eth_subscribe", Params: []interface{}{"logs", map[string]interface{}{
+			"address": contracts,
+			"topics":  []interface{}{transferTopic},
+		}}},
+	}
+	for _, request := range requests {
+		encoded, err := json.Marshal(request)
+		if err != nil {
+			return false, err
+		}
+		if err := conn.writeText(encoded); err != nil {
+			return false, err
+		}
+	}
+
+	headsSubscription, logsSubscription := "", ""
+	for {
+		raw, err := conn.readMessage(s.effectiveIdleTimeout())
+		if err != nil {
+			return headsSubscription != "" && logsSubscription != "", err
+		}
+
+		var msg wsMessage
+		if err := json.Unmarshal(raw, &msg); err != nil {
+			return headsSubscription != "" && logsSubscription != "", fmt.Errorf("decode websocket message: %w", err)
+		}
+
+		if msg.ID != nil {
+			if msg.Error != nil {
+				return false, fmt.Errorf("eth_subscribe: %w", &rpcCallError{Code: msg.Error.Code, Message: msg.Error.Message})
+			}
+			var subscription string
+			if err := json.Unmarshal(msg.Result, &subscription); err != nil {
+				return false, fmt.Errorf("decode eth_subscribe result: %w", err)
+			}
+			switch *msg.ID {
+			case wsHeadsRequestID:
+				headsSubscription = subscription
+			case wsLogsRequestID:
+				logsSubscription = subscription
+			}
+			if headsSubscription != "" && logsSubscription != "" {
+				s.logger().Info("base-ws: subscribed",
+					"endpoint", redactRPCURL(s.WSURL),
+					"contracts", len(contracts),
+				)
+			}
+			continue
+		}
+
+		if msg.Method != "eth_subscription" {
+			continue
+		}
+		switch msg.Params.Subscription {
+		case headsSubscription:
+			if logsSubscription == "" {
+				// Logs for this block may predate the logs subscription.
+				continue
+			}
+			var head evmBlock
+			if err := json.Unmarshal(msg.Params.Result, &head); err != nil {
+				return true, fmt.Errorf("decode newHeads: %w", err)
+			}
+			if restartedAt, restarted := s.buffer.addHead(head); restarted {
+				s.logger().Info("base-ws: live coverage (re)started, earlier blocks are read over http",
+					"coveredFrom", restartedAt,
+				)
+			}
+			select {
+			case s.wake <- struct{}{}:
+			default:
+			}
+		case logsSubscription:
+			var eventLog evmLog
+			if err := json.Unmarshal(msg.Params.Result, &eventLog); err != nil {
+				return true, fmt.Errorf("decode log notification: %w", err)
+			}
+			s.buffer.addLog(eventLog)
+		}
+	}
+}
+
+// wsLogBuffer holds logs received over the subscription. Logs for a block are
+// served only once a later head arrived on an unbroken chain of heads that
+// started after the subscription was in place.
+type wsLogBuffer struct {
+	mu          sync.Mutex
+	contracts   map[string]bool
+	retain      int64
+	coveredFrom int64 // first fully covered block; 0 while nothing is covered
+	lastHead    int64
+	lastHash    string
+	heads       map[int64]string
+	logs        map[int64][]evmLog
+}
+
+func newWsLogBuffer(contracts map[string]bool) *wsLogBuffer {
+	return &wsLogBuffer{
+		contracts: contracts,
+		retain:    4096,
+		heads:     map[int64]string{},
+		logs:      map[int64][]evmLog{},
+	}
+}
+
+func (b *wsLogBuffer) contractList() []string {
+	list := make([]string, 0, len(b.contracts))
+	for contract := range b.contracts {
+		list = append(list, contract)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// reset drops all coverage and returns the last block that was covered.
+func (b *wsLogBuffer) reset() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	lastCovered := int64(0)
+	if b.coveredFrom > 0 && b.lastHead > b.coveredFrom {
+		lastCovered = b.lastHead - 1
+	}
+	b.coveredFrom, b.lastHead, b.lastHash = 0, 0, ""
+	b.heads = map[int64]string{}
+	b.logs = map[int64][]evmLog{}
+	return lastCovered
+}
+
+// addHead records a new head. A head that does not extend the previous one
+// (reorg or missed notification) restarts coverage after it; the returned
+// block is where coverage now begins.
+func (b *wsLogBuffer) addHead(head evmBlock) (int64, bool) {
+	number, err := parseHexInt64(head.Number)
+	if err != nil {
+		return 0, false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	restart := b.lastHead == 0 || number != b.lastHead+1 || !strings.EqualFold(head.ParentHash, b.lastHash)
+	if restart {
+		b.coveredFrom = number + 1
+		b.heads = map[int64]string{}
+		for blockNumber := range b.logs {
+			if blockNumber <= number {
+				delete(b.logs, blockNumber)
+			}
+		}
+	}
+
+	b.heads[number] = head.Hash
+	b.lastHead, b.lastHash = number, head.Hash
+
+	oldest := number - b.retain + 1
+	if b.coveredFrom < oldest {
+		b.coveredFrom = oldest
+	}
+	for blockNumber := range b.heads {
+		if blockNumber < oldest {
+			delete(b.heads, blockNumber)
+		}
+	}
+	for blockNumber := range b.logs {
+		if blockNumber < oldest {
+			delete(b.logs, blockNumber)
+		}
+	}
+
+	return b.coveredFrom, restart
+}
+
+// addLog buffers a log notification. A removed log means the node reorged
+// under us, so coverage restarts with the next head.
+func (b *wsLogBuffer) addLog(eventLog evmLog) {
+	blockNumber, err := parseHexInt64(eventLog.BlockNumber)
+	if err != nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if eventLog.Removed {
+		b.lastHead, b.lastHash = 0, ""
+		return
+	}
+	b.logs[blockNumber] = append(b.logs[blockNumber], eventLog)
+}
+
+// lookup returns the buffered logs of contract in [fromBlock, toBlock] that
+// match topics, or false when the range is not fully covered.
+func (b *wsLogBuffer) lookup(contract string, topics []interface{}, fromBlock int64, toBlock int64) ([]evmLog, bool) {
+	if b == nil {
+		return nil, false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.contracts[strings.ToLower(contract)] || b.coveredFrom == 0 || b.lastHead == 0 {
+		return nil, false
+	}
+	if fromBlock < b.coveredFrom || toBlock >= b.lastHead {
+		return nil, false
+	}
+
+	out := make([]evmLog, 0)
+	for blockNumber := fromBlock; blockNumber <= toBlock; blockNumber++ {
+		hash, ok := b.heads[blockNumber]
+		if !ok {
+			return nil, false
+		}
+		for _, eventLog := range b.logs[blockNumber] {
+			if !strings.EqualFold(eventLog.BlockHash, hash) {
+				return nil, false
+			}
+			if strings.EqualFold(eventLog.Address, contract) && logMatchesTopics(eventLog, topics) {
+				out = append(out, eventLog)
+			}
+		}
+	}
+	return out, true
+}
+
+// logMatchesTopics applies an eth_getLogs topic filter: nil matches anything,
+// a string must match exactly and a list matches any of its entries.
+func logMatchesTopics(eventLog evmLog, topics []interface{}) bool {
+	for i, filter := range topics {
+		var allowed []string
+		switch value := filter.(type) {
+		case nil:
+			continue
+		case string:
+			allowed = []string{value}
+		case []string:
+			allowed = value
+		default:
+			return false
+		}
+		if i >= len(eventLog.Topics) {
+			return false
+		}
+		matched := false
+		for _, topic := range allowed {
+			if strings.EqualFold(eventLog.Topics[i], topic) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
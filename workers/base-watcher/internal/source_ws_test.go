@@ -0,0 +1,262 @@
+package internal
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeWSPeer is the server side of one WebSocket connection.
+type fakeWSPeer struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func (p *fakeWSPeer) send(v any) error {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	header := []byte{0x81}
+	switch {
+	case len(payload) < 126:
+		header = append(header, byte(len(payload)))
+	case len(payload) <= 0xffff:
+		header = append(header, 126, 0, 0)
+		binary.BigEndian.PutUint16(header[2:], uint16(len(payload)))
+	default:
+		header = append(header, 127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(header[2:], uint64(len(payload)))
+	}
+	_, err = p.conn.Write(append(header, payload...))
+	return err
+}
+
+func (p *fakeWSPeer) readJSON(v any) error {
+	var head [2]byte
+	if _, err := io.ReadFull(p.reader, head[:]); err != nil {
+		return err
+	}
+	length := int(head[1] & 0x7f)
+	if length == 126 {
+		var ext [2]byte
+		if _, err := io.ReadFull(p.reader, ext[:]); err != nil {
+			return err
+		}
+		length = int(binary.BigEndian.Uint16(ext[:]))
+	}
+	var mask [4]byte
+	if _, err := io.ReadFull(p.reader, mask[:]); err != nil {
+		return err
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(p.reader, payload); err != nil {
+		return err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return json.Unmarshal(payload, v)
+}
+
+// acceptSubscriptions answers the newHeads and logs eth_subscribe requests.
+func (p *fakeWSPeer) acceptSubscriptions() error {
+	for i := 0; i < 2; i++ {
+		var req fakeRPCRequest
+		if err := p.readJSON(&req); err != nil {
+			return err
+		}
+		var kind string
+		_ = json.Unmarshal(req.Params[0], &kind)
+		if err := p.send(map[string]any{"jsonrpc": "2.0", "id": req.ID, "result": "0x" + kind}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *fakeWSPeer) notify(subscription string, result any) error {
+	return p.send(map[string]any{
+		"jsonrpc": "2.0",
+		"method":  "eth_subscription",
+		"params":  map[string]any{"subscription": subscription, "result": result},
+	})
+}
+
+// serveFakeWebsocket runs sessions[i] for the i-th connection; the last one is
+// reused for any further connections.
+func serveFakeWebsocket(t *testing.T, sessions ...func(p *fakeWSPeer)) *httptest.Server {
+	t.Helper()
+	var mu sync.Mutex
+	connections := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accept := websocketAccept(r.Header.Get("Sec-WebSocket-Key"))
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: " + accept + "\r\n\r\n")
+		_ = rw.Flush()
+
+		mu.Lock()
+		session := sessions[len(sessions)-1]
+		if connections < len(sessions) {
+			session = sessions[connections]
+		}
+		connections++
+		mu.Unlock()
+
+		session(&fakeWSPeer{conn: conn, reader: rw.Reader})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func (b *wsLogBuffer) currentHead() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.lastHead
+}
+
+func waitForHead(t *testing.T, source *EvmWebsocketSource, head int64) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for source.buffer.currentHead() != head {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for head %d, at %d", head, source.buffer.currentHead())
+		}
+		select {
+		case <-source.Wake():
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func TestEvmWebsocketSource_ServesSubscribedLogsAndBackfillsAfterReconnect(t *testing.T) {
+	node := newFakeEvmNode(102)
+	node.addTransfer(101, "0xlive", 0, testDepositAddr, 3_000_000)
+	rpcServer := node.serve(t)
+
+	firstSessionDone := make(chan struct{})
+	wsServer := serveFakeWebsocket(t,
+		func(p *fakeWSPeer) {
+			if p.acceptSubscriptions() != nil {
+				return
+			}
+			_ = p.notify("0xnewHeads", node.block(100))
+			_ = p.notify("0xlogs", node.logs[0])
+			_ = p.notify("0xnewHeads", node.block(101))
+			_ = p.notify("0xnewHeads", node.block(102))
+			<-firstSessionDone
+		},
+		func(p *fakeWSPeer) {
+			if p.acceptSubscriptions() != nil {
+				return
+			}
+			_ = p.notify("0xnewHeads", node.block(103))
+			var ignored any
+			_ = p.readJSON(&ignored)
+		},
+	)
+
+	httpSource := newTestEvmSource(rpcServer.URL)
+	httpSource.FinalizedConfirmations = 2
+	source := NewEvmWebsocketSource("ws"+strings.TrimPrefix(wsServer.URL, "http"), httpSource, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	source.ReconnectDelay = 10 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	source.Start(ctx)
+	waitForHead(t, source, 102)
+
+	candidates, cursor, err := source.Poll(ctx, "100")
+	if err != nil {
+		t.Fatalf("poll: %v", err)
+	}
+	if len(candidates) != 1 || candidates[0].TxHash != "0xlive" {
+		t.Fatalf("expected the subscribed transfer, got %+v", candidates)
+	}
+	if node.calls["eth_getLogs"] != 0 {
+		t.Fatalf("expected covered range to be served from the subscription, got %d eth_getLogs calls", node.calls["eth_getLogs"])
+	}
+
+	// The connection drops; a transfer lands in block 102 while nobody listens.
+	node.addTransfer(102, "0xmissed", 0, testDepositAddr, 7_000_000)
+	node.extend(1)
+	close(firstSessionDone)
+	waitForHead(t, source, 103)
+
+	candidates, _, err = source.Poll(ctx, cursor)
+	if err != nil {
+		t.Fatalf("poll after reconnect: %v", err)
+	}
+	if len(candidates) != 1 || candidates[0].TxHash != "0xmissed" {
+		t.Fatalf("expected backfilled transfer, got %+v", candidates)
+	}
+	if node.calls["eth_getLogs"] == 0 {
+		t.Fatalf("expected the missed range to be backfilled over http")
+	}
+}
+
+func TestWsLogBuffer_RestartsCoverageOnBrokenHeadChain(t *testing.T) {
+	node := newFakeEvmNode(110)
+	node.addTransfer(102, "0xa", 0, testDepositAddr, 1_000_000)
+	buffer := newWsLogBuffer(map[string]bool{testUSDCContract: true})
+	topics := []interface{}{transferTopic}
+
+	buffer.addHead(evmBlockFromFake(node.block(100)))
+	buffer.addHead(evmBlockFromFake(node.block(101)))
+	buffer.addLog(node.logs[0])
+	buffer.addHead(evmBlockFromFake(node.block(102)))
+	buffer.addHead(evmBlockFromFake(node.block(103)))
+
+	if _, ok := buffer.lookup(testUSDCContract, topics, 100, 102); ok {
+		t.Fatalf("block 100 predates coverage and must not be served")
+	}
+	logs, ok := buffer.lookup(testUSDCContract, topics, 101, 102)
+	if !ok || len(logs) != 1 {
+		t.Fatalf("expected covered range with one log, got %v %v", logs, ok)
+	}
+	if _, ok := buffer.lookup(testUSDCContract, topics, 101, 103); ok {
+		t.Fatalf("the latest head's logs may still be arriving and must not be served")
+	}
+
+	// A skipped head breaks the chain; coverage restarts after it.
+	buffer.addHead(evmBlockFromFake(node.block(105)))
+	buffer.addHead(evmBlockFromFake(node.block(106)))
+	buffer.addHead(evmBlockFromFake(node.block(107)))
+	if _, ok := buffer.lookup(testUSDCContract, topics, 104, 106); ok {
+		t.Fatalf("range across the skipped head must not be served")
+	}
+	if _, ok := buffer.lookup(testUSDCContract, topics, 106, 106); !ok {
+		t.Fatalf("expected coverage to restart after the gap")
+	}
+
+	// A removed log means the node reorged; the next head restarts coverage.
+	removed := node.logs[0]
+	removed.Removed = true
+	buffer.addLog(removed)
+	buffer.addHead(evmBlockFromFake(node.block(108)))
+	if _, ok := buffer.lookup(testUSDCContract, topics, 106, 106); ok {
+		t.Fatalf("expected coverage to restart after a removed log")
+	}
+}
+
+func evmBlockFromFake(block map[string]any) evmBlock {
+	return evmBlock{
+		Number:     block["number"].(string),
+		Hash:       block["hash"].(string),
+		ParentHash: block["parentHash"].(string),
+	}
+}
@@ -0,0 +1,68 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ParseStartTime parses a backfill start time such as "2026-09-01T00:00Z".
+// Seconds and the time of day may be omitted; a bare date is midnight UTC.
+func ParseStartTime(raw string) (time.Time, error) {
+	trimmed := strings.TrimSpace(raw)
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04Z07:00", "2006-01-02"} {
+		if parsed, err := time.Parse(layout, trimmed); err == nil {
+			return parsed.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid start time %q (expected e.g. 2026-09-01T00:00Z)", raw)
+}
+
+// CursorAt returns a cursor from which the next poll scans the first block
+// produced at or after at. Block timestamps only grow, so the block is found
+// by binary search over eth_getBlockByNumber headers.
+func (s EvmRpcSource) CursorAt(ctx context.Context, at time.Time) (string, error) {
+	ctx = s.RPCPool.Pin(ctx)
+	latestBlock, err := s.ethBlockNumber(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	timestampOf := func(blockNumber int64) (time.Time, error) {
+		header, err := s.ethGetBlockHeader(ctx, blockNumber)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return blockTimestamp(header)
+	}
+
+	latestAt, err := timestampOf(latestBlock)
+	if err != nil {
+		return "", err
+	}
+	if latestAt.Before(at) {
+		return "", fmt.Errorf("start time %s is after the latest block %d (%s)", at.Format(time.RFC3339), latestBlock, latestAt.Format(time.RFC3339))
+	}
+
+	// Find the first block in [low, high] whose timestamp is not before at.
+	low, high := int64(0), latestBlock
+	for low < high {
+		mid := low + (high-low)/2
+		midAt, err := timestampOf(mid)
+		if err != nil {
+			return "", err
+		}
+		if midAt.Before(at) {
+			low = mid + 1
+		} else {
+			high = mid
+		}
+	}
+
+	// The cursor is the last scanned block; genesis holds no transfers.
+	if low == 0 {
+		return evmCursor{}.String(), nil
+	}
+	return evmCursor{Block: low - 1}.String(), nil
+}
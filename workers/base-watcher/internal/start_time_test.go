@@ -0,0 +1,42 @@
+package internal
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestEvmRpcSource_CursorAtFindsFirstBlockAtTime(t *testing.T) {
+	node := newFakeEvmNode(110)
+	source := newTestEvmSource(node.serve(t).URL)
+	block105 := time.Unix(1_770_000_000+105*2, 0)
+
+	for _, at := range []time.Time{block105, block105.Add(-time.Second)} {
+		cursor, err := source.CursorAt(context.Background(), at)
+		if err != nil {
+			t.Fatalf("unexpected cursor error: %v", err)
+		}
+		if cursor != "104" {
+			t.Fatalf("%s: expected the cursor before block 105, got %s", at, cursor)
+		}
+	}
+	if cursor, err := source.CursorAt(context.Background(), time.Unix(0, 0)); err != nil || cursor != "0" {
+		t.Fatalf("expected a time before genesis to start at genesis, got %q %v", cursor, err)
+	}
+	if _, err := source.CursorAt(context.Background(), block105.Add(time.Hour)); err == nil {
+		t.Fatalf("expected a time after the latest block to fail")
+	}
+}
+
+func TestParseStartTime(t *testing.T) {
+	want := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	for _, raw := range []string{"2026-09-01T00:00Z", "2026-09-01T00:00:00Z", "2026-09-01T02:00+02:00", "2026-09-01"} {
+		got, err := ParseStartTime(raw)
+		if err != nil || !got.Equal(want) {
+			t.Fatalf("%s: got %s %v", raw, got, err)
+		}
+	}
+	if _, err := ParseStartTime("yesterday"); err == nil {
+		t.Fatalf("expected an invalid start time to fail")
+	}
+}
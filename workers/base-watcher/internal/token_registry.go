@@ -0,0 +1,197 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"sort"
+	"strings"
+)
+
+// TokenInfo describes one token a source watches.
+type TokenInfo struct {
+	Symbol   string // e.g. "USDC"; candidates carry it as Token
+	Contract string // ERC-20 contract address
+	Decimals int    // 0 means "read from chain" in VerifyTokenDecimals
+	Name     string // display name, e.g. "USD Coin"
+}
+
+// TokenRegistry holds the tokens of a source, keyed by symbol and contract.
+// Every conversion from base units goes through it, so tokens with other
+// than 6 decimals are credited correctly.
+type TokenRegistry struct {
+	bySymbol   map[string]*TokenInfo
+	byContract map[string]*TokenInfo
+}
+
+// NewTokenRegistry builds a registry. Tokens without a contract are skipped so
+// optional tokens can stay unconfigured.
+func NewTokenRegistry(tokens []TokenInfo) (*TokenRegistry, error) {
+	registry := &TokenRegistry{
+		bySymbol:   make(map[string]*TokenInfo),
+		byContract: make(map[string]*TokenInfo),
+	}
+	for _, token := range tokens {
+		token.Symbol = strings.ToUpper(strings.TrimSpace(token.Symbol))
+		token.Contract = strings.ToLower(strings.TrimSpace(token.Contract))
+		if token.Contract == "" {
+			continue
+		}
+		if token.Symbol == "" {
+			return nil, fmt.Errorf("token %s has no symbol", token.Contract)
+		}
+		if token.Decimals < 0 || token.Decimals > 36 {
+			return nil, fmt.Errorf("token %s has invalid decimals %d", token.Symbol, token.Decimals)
+		}
+		if _, exists := registry.bySymbol[token.Symbol]; exists {
+			return nil, fmt.Errorf("duplicate token symbol %s", token.Symbol)
+		}
+		if _, exists := registry.byContract[token.Contract]; exists {
+			return nil, fmt.Errorf("duplicate token contract %s", token.Contract)
+		}
+
+		info := token
+		registry.bySymbol[info.Symbol] = &info
+		registry.byContract[info.Contract] = &info
+	}
+	return registry, nil
+}
+
+// Token returns the token with the given symbol.
+func (r *TokenRegistry) Token(symbol string) (TokenInfo, bool) {
+	if r == nil {
+		return TokenInfo{}, false
+	}
+	info, ok := r.bySymbol[strings.ToUpper(symbol)]
+	if !ok {
+		return TokenInfo{}, false
+	}
+	return *info, true
+}
+
+// TokenByContract returns the token deployed at contract.
+func (r *TokenRegistry) TokenByContract(contract string) (TokenInfo, bool) {
+	if r == nil {
+		return TokenInfo{}, false
+	}
+	info, ok := r.byContract[strings.ToLower(contract)]
+	if !ok {
+		return TokenInfo{}, false
+	}
+	return *info, true
+}
+
+// Tokens returns all tokens ordered by symbol.
+func (r *TokenRegistry) Tokens() []TokenInfo {
+	if r == nil {
+		return nil
+	}
+	tokens := make([]TokenInfo, 0, len(r.bySymbol))
+	for _, info := range r.bySymbol {
+		tokens = append(tokens, *info)
+	}
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].Symbol < tokens[j].Symbol })
+	return tokens
+}
+
+// Contract returns the contract of the token with the given symbol, or "".
+func (r *TokenRegistry) Contract(symbol string) string {
+	token, _ := r.Token(symbol)
+	return token.Contract
+}
+
+// AmountUSD converts base units to whole tokens. The watched tokens are
+// USD stablecoins, so one token counts as one USD.
+func (t TokenInfo) AmountUSD(baseUnits *big.Int) float64 {
+	divisor := new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(t.Decimals)), nil))
+	usd, _ := new(big.Float).Quo(new(big.Float).SetInt(baseUnits), divisor).Float64()
+	return usd
+}
+
+// formatBaseUnits renders an integer base-unit amount as an exact decimal
+// string, e.g. 2500000 with 6 decimals is "2.5".
+func formatBaseUnits(baseUnits *big.Int, decimals int) string {
+	digits := new(big.Int).Abs(baseUnits).String()
+	sign := ""
+	if baseUnits.Sign() < 0 {
+		sign = "-"
+	}
+	if decimals <= 0 {
+		return sign + digits
+	}
+	if len(digits) <= decimals {
+		digits = strings.Repeat("0", decimals-len(digits)+1) + digits
+	}
+	whole, fraction := digits[:len(digits)-decimals], strings.TrimRight(digits[len(digits)-decimals:], "0")
+	if fraction == "" {
+		return sign + whole
+	}
+	return sign + whole + "." + fraction
+}
+
+// parseDecimalAmount converts a decimal string such as "2.5" into base units,
+// the inverse of formatBaseUnits. More fractional digits than decimals are an
+// error rather than being rounded.
+func parseDecimalAmount(raw string, decimals int) (*big.Int, error) {
+	trimmed := strings.TrimSpace(raw)
+	whole, fraction, _ := strings.Cut(trimmed, ".")
+	if whole == "" {
+		whole = "0"
+	}
+	if len(fraction) > decimals {
+		return nil, fmt.Errorf("%q has more than %d decimals", raw, decimals)
+	}
+	amount, ok := new(big.Int).SetString(whole+fraction+strings.Repeat("0", decimals-len(fraction)), 10)
+	if !ok || amount.Sign() < 0 || strings.ContainsAny(whole+fraction, "+-") {
+		return nil, fmt.Errorf("%q is not a non-negative decimal amount", raw)
+	}
+	return amount, nil
+}
+
+// setAmount records an exact base-unit amount on the candidate, together with
+// the token decimals and the exact decimal string. AmountUSD is derived from
+// it for consumers that still read the float.
+func (c *FundingCandidate) setAmount(token TokenInfo, baseUnits *big.Int) {
+	c.AmountBaseUnits = baseUnits.String()
+	c.TokenDecimals = token.Decimals
+	c.Amount = formatBaseUnits(baseUnits, token.Decimals)
+	c.AmountUSD = token.AmountUSD(baseUnits)
+}
+
+// decimalsSelector is the ABI selector of decimals().
+const decimalsSelector = "0x313ce567"
+
+// VerifyTokenDecimals reads decimals() from every token contract. Tokens
+// configured without decimals take the on-chain value; a configured value
+// that disagrees with the chain is an error, so a wrong divisor never reaches
+// a payout.
+func (s EvmRpcSource) VerifyTokenDecimals(ctx context.Context) error {
+	for _, token := range s.Tokens.Tokens() {
+		var result string
+		call := map[string]interface{}{"to": token.Contract, "data": decimalsSelector}
+		if err := s.rpcCall(ctx, "eth_call", []interface{}{call, "latest"}, &result); err != nil {
+			return fmt.Errorf("eth_call decimals() on %s: %w", token.Symbol, err)
+		}
+		onChain, err := parseHexInt64(result)
+		if err != nil || onChain < 0 || onChain > 36 {
+			return fmt.Errorf("token %s returned invalid decimals %q", token.Symbol, result)
+		}
+
+		info := s.Tokens.bySymbol[token.Symbol]
+		switch {
+		case info.Decimals == 0:
+			info.Decimals = int(onChain)
+		case int64(info.Decimals) != onChain:
+			return fmt.Errorf("token %s configured with %d decimals but contract reports %d", token.Symbol, info.Decimals, onChain)
+		}
+
+		slog.Info("base-rpc: token verified",
+			"token", token.Symbol,
+			"name", info.Name,
+			"contract", token.Contract,
+			"decimals", info.Decimals,
+		)
+	}
+	return nil
+}
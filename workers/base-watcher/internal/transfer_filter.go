@@ -0,0 +1,182 @@
+package internal
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+)
+
+// Reasons a transfer is tagged instead of being treated as a deposit.
+const (
+	FilterZeroValue    = "zero_value"    // address-poisoning bots send 0-amount Transfers
+	FilterBelowMinimum = "below_minimum" // dust under the token's configured minimum
+)
+
+// FilterLookalikeSender tags a transfer whose sender mimics the head and tail
+// of a known address. The value still reached the deposit address, so the
+// transfer is published with the tag rather than filtered.
+const FilterLookalikeSender = "lookalike_sender"
+
+// TransferFilter tags candidates that look like dust or address poisoning.
+// Filtered candidates are still returned by the source, so they are counted
+// and logged, but the watcher neither resolves their route nor publishes them.
+//
+// A lookalike sender shares the first and last LookalikeChars hex characters
+// with, but is not, the deposit address or a sender that already made a
+// genuine transfer to it. Genuine senders are remembered per deposit address
+// across polls. Lookalike transfers above the minimum are only marked with
+// lookalikeSender metadata, since the funds are real.
+type TransferFilter struct {
+	MinAmounts     map[string]*big.Int // token -> smallest deposit in base units
+	LookalikeChars int                 // hex characters compared at each end (default: 4)
+	RecentSenders  int                 // genuine senders remembered per deposit address (default: 16)
+
+	mu     sync.Mutex
+	recent map[string][]string // deposit address -> genuine senders, newest first
+}
+
+// maxFilterAddresses bounds how many deposit addresses the filter remembers
+// senders for; the memory is reset when it is exceeded.
+const maxFilterAddresses = 10000
+
+// NewTransferFilter builds a filter from minimum amounts in whole token units,
+// e.g. {"USDC": "0.5"}, converted with each token's decimals.
+func NewTransferFilter(minAmounts map[string]string, tokens *TokenRegistry) (*TransferFilter, error) {
+	filter := &TransferFilter{MinAmounts: make(map[string]*big.Int, len(minAmounts))}
+	for symbol, raw := range minAmounts {
+		symbol = strings.ToUpper(strings.TrimSpace(symbol))
+		token, ok := tokens.Token(symbol)
+		if !ok && symbol == nativeSymbol {
+			token, ok = nativeToken, true
+		}
+		if !ok {
+			return nil, fmt.Errorf("minimum amount for unknown token %s", symbol)
+		}
+		amount, err := parseDecimalAmount(raw, token.Decimals)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s minimum amount: %w", symbol, err)
+		}
+		filter.MinAmounts[symbol] = amount
+	}
+	return filter, nil
+}
+
+func (f *TransferFilter) effectiveLookalikeChars() int {
+	if f.LookalikeChars > 0 {
+		return f.LookalikeChars
+	}
+	return 4
+}
+
+func (f *TransferFilter) effectiveRecentSenders() int {
+	if f.RecentSenders > 0 {
+		return f.RecentSenders
+	}
+	return 16
+}
+
+// apply tags candidates in place and returns how many were tagged per reason.
+// A nil filter tags nothing.
+func (f *TransferFilter) apply(candidates []FundingCandidate) map[string]int {
+	counts := make(map[string]int)
+	if f == nil {
+		return counts
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.recent == nil || len(f.recent) > maxFilterAddresses {
+		f.recent = make(map[string][]string)
+	}
+
+	for i := range candidates {
+		candidate := &candidates[i]
+		if candidate.Reverted {
+			continue
+		}
+		if reason := f.reason(*candidate); reason != "" {
+			candidate.FilterReason = reason
+			candidate.Metadata = withMetadata(candidate.Metadata, "filterReason", reason)
+			counts[reason]++
+			continue
+		}
+		if mimicked := f.mimicked(*candidate); mimicked != "" {
+			candidate.Metadata = withMetadata(candidate.Metadata, "lookalikeSender", mimicked)
+			counts[FilterLookalikeSender]++
+			continue
+		}
+		f.remember(candidate.DepositAddress, candidatePayer(*candidate))
+	}
+	return counts
+}
+
+// reason returns why a candidate is filtered, or "" when it is published.
+func (f *TransferFilter) reason(candidate FundingCandidate) string {
+	amount, ok := new(big.Int).SetString(candidate.AmountBaseUnits, 10)
+	if ok && amount.Sign() == 0 {
+		return FilterZeroValue
+	}
+	if minimum := f.MinAmounts[strings.ToUpper(candidate.Token)]; ok && minimum != nil && amount.Cmp(minimum) < 0 {
+		return FilterBelowMinimum
+	}
+	return ""
+}
+
+// mimicked returns the known address the candidate's sender looks like, or ""
+// when it looks like none. Callers hold f.mu.
+func (f *TransferFilter) mimicked(candidate FundingCandidate) string {
+	payer := candidatePayer(candidate)
+	if payer == "" {
+		return ""
+	}
+	depositAddress := strings.ToLower(candidate.DepositAddress)
+	known := append([]string{depositAddress}, f.recent[depositAddress]...)
+	for _, address := range known {
+		if address != "" && address != payer && f.looksAlike(address, payer) {
+			return address
+		}
+	}
+	return ""
+}
+
+// withMetadata returns a copy of metadata with key set to value.
+func withMetadata(metadata map[string]any, key string, value any) map[string]any {
+	out := make(map[string]any, len(metadata)+1)
+	for k, v := range metadata {
+		out[k] = v
+	}
+	out[key] = value
+	return out
+}
+
+// remember records a genuine sender to a deposit address. Callers hold f.mu.
+func (f *TransferFilter) remember(depositAddress string, payer string) {
+	if depositAddress == "" || payer == "" {
+		return
+	}
+	depositAddress = strings.ToLower(depositAddress)
+	senders := []string{payer}
+	for _, sender := range f.recent[depositAddress] {
+		if sender != payer && len(senders) < f.effectiveRecentSenders() {
+			senders = append(senders, sender)
+		}
+	}
+	f.recent[depositAddress] = senders
+}
+
+// looksAlike reports whether two addresses share their leading and trailing
+// hex characters, which is all most wallets show.
+func (f *TransferFilter) looksAlike(a string, b string) bool {
+	a, b = strings.TrimPrefix(a, "0x"), strings.TrimPrefix(b, "0x")
+	n := f.effectiveLookalikeChars()
+	if len(a) < 2*n || len(b) < 2*n {
+		return false
+	}
+	return a[:n] == b[:n] && a[len(a)-n:] == b[len(b)-n:]
+}
+
+func candidatePayer(candidate FundingCandidate) string {
+	payer, _ := candidate.Metadata["payerAddress"].(string)
+	return strings.ToLower(payer)
+}
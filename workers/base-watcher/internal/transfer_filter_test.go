@@ -0,0 +1,105 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestEvmRpcSource_FilterTagsDustAndPoisoningTransfers(t *testing.T) {
+	node := newFakeEvmNode(110)
+	node.addTransfer(102, "0xgenuine", 0, testDepositAddr, 5_000_000)
+	node.addTransfer(103, "0xzero", 0, testDepositAddr, 0)
+	node.addTransfer(104, "0xdust", 0, testDepositAddr, 1_000)
+	node.addTransfer(105, "0xpoison", 0, testDepositAddr, 5_000_000)
+	// Same head and tail as the genuine payer 0x2222…2222.
+	node.logs[len(node.logs)-1].Topics[1] = "0x0000000000000000000000002222abcdef0123456789abcdef01234567892222"
+
+	filter, err := NewTransferFilter(map[string]string{"usdc": "0.01"}, mustTokenRegistry())
+	if err != nil {
+		t.Fatalf("unexpected filter error: %v", err)
+	}
+	source := newTestEvmSource(node.serve(t).URL)
+	source.Filter = filter
+
+	candidates, next, err := source.Poll(context.Background(), "100")
+	if err != nil {
+		t.Fatalf("unexpected poll error: %v", err)
+	}
+	if len(candidates) != 4 {
+		t.Fatalf("expected filtered transfers to be returned tagged, got %+v", candidates)
+	}
+	want := map[string]string{"0xgenuine": "", "0xzero": FilterZeroValue, "0xdust": FilterBelowMinimum, "0xpoison": ""}
+	for _, candidate := range candidates {
+		if candidate.FilterReason != want[candidate.TxHash] {
+			t.Fatalf("%s: expected reason %q, got %q", candidate.TxHash, want[candidate.TxHash], candidate.FilterReason)
+		}
+		if candidate.FilterReason != "" && candidate.Metadata["filterReason"] != candidate.FilterReason {
+			t.Fatalf("%s: expected the reason in metadata, got %+v", candidate.TxHash, candidate.Metadata)
+		}
+	}
+	// The lookalike sender's transfer carries real value, so it is published
+	// with a tag naming the address it mimics.
+	for _, candidate := range candidates {
+		lookalike, tagged := candidate.Metadata["lookalikeSender"]
+		if tagged != (candidate.TxHash == "0xpoison") {
+			t.Fatalf("%s: unexpected lookalike tag %v", candidate.TxHash, lookalike)
+		}
+		if tagged && lookalike != "0x2222222222222222222222222222222222222222" {
+			t.Fatalf("expected the mimicked genuine payer in the tag, got %v", lookalike)
+		}
+	}
+	if tracked := parseEvmCursor(next).Candidates; len(tracked) != 2 || tracked[0].TxHash != "0xgenuine" || tracked[1].TxHash != "0xpoison" {
+		t.Fatalf("expected the published transfers to be tracked for reorgs, got %+v", tracked)
+	}
+}
+
+func TestWatcher_FilteredCandidateIsNotResolved(t *testing.T) {
+	pub := &publisherStub{}
+	w := Watcher{Chain: "base", MinConfirmations: 1, Resolver: resolverStub{err: errors.New("resolver must not be called")}, Publisher: pub}
+
+	result, err := w.ProcessCandidate(context.Background(), FundingCandidate{
+		Chain: "base", Token: "USDC", TxHash: "0x1", Confirmations: 5, ConfirmedAt: time.Date(2026, 2, 13, 12, 0, 0, 0, time.UTC),
+		FilterReason: FilterZeroValue,
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if result != ProcessFiltered || len(pub.calledWith) != 0 {
+		t.Fatalf("expected filtered without publishing, got %s", result)
+	}
+}
+
+func TestTransferFilter_LookalikeSenderBelowMinimumIsFiltered(t *testing.T) {
+	filter := &TransferFilter{MinAmounts: map[string]*big.Int{"USDC": big.NewInt(10_000)}}
+	payer := func(address string) map[string]any { return map[string]any{"payerAddress": address} }
+	candidates := []FundingCandidate{
+		{Token: "USDC", DepositAddress: testDepositAddr, AmountBaseUnits: "5000000", Metadata: payer("0x2222222222222222222222222222222222222222")},
+		{Token: "USDC", DepositAddress: testDepositAddr, AmountBaseUnits: "1000", Metadata: payer("0x2222abcdef0123456789abcdef01234567892222")},
+	}
+
+	counts := filter.apply(candidates)
+	if candidates[1].FilterReason != FilterBelowMinimum || candidates[1].Metadata["lookalikeSender"] != nil {
+		t.Fatalf("expected dust from a lookalike sender to be filtered as dust, got %+v", candidates[1])
+	}
+	if counts[FilterBelowMinimum] != 1 || counts[FilterLookalikeSender] != 0 {
+		t.Fatalf("unexpected counts %v", counts)
+	}
+}
+
+func TestParseDecimalAmount(t *testing.T) {
+	cases := map[string]string{"0.5": "500000", "12": "12000000", ".000001": "1", "0": "0"}
+	for raw, want := range cases {
+		got, err := parseDecimalAmount(raw, 6)
+		if err != nil || got.String() != want {
+			t.Fatalf("%s: got %v %v, want %s", raw, got, err, want)
+		}
+	}
+	for _, raw := range []string{"0.0000001", "-1", "abc", "1.2.3"} {
+		if _, err := parseDecimalAmount(raw, 6); err == nil {
+			t.Fatalf("%s: expected an error", raw)
+		}
+	}
+}
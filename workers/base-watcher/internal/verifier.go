@@ -0,0 +1,249 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CandidateVerifier double-checks a candidate before the watcher publishes it.
+// A candidate that is not verified is held back, never dropped.
+type CandidateVerifier interface {
+	VerifyCandidate(ctx context.Context, c FundingCandidate) (CandidateVerification, error)
+}
+
+// CandidateVerification is the outcome of a verifier. A candidate that is not
+// verified is held and retried, unless Rejected marks the failure as permanent.
+type CandidateVerification struct {
+	Verified bool
+	Rejected bool
+	Reason   string
+	Metadata map[string]any
+}
+
+// VerifierChain runs verifiers in order and stops at the first one that does
+// not verify the candidate. Metadata from every verifier that ran is merged.
+type VerifierChain []CandidateVerifier
+
+func (chain VerifierChain) VerifyCandidate(ctx context.Context, c FundingCandidate) (CandidateVerification, error) {
+	merged := CandidateVerification{Verified: true, Metadata: map[string]any{}}
+	for _, verifier := range chain {
+		verification, err := verifier.VerifyCandidate(ctx, c)
+		if err != nil {
+			return CandidateVerification{}, err
+		}
+		for key, value := range verification.Metadata {
+			merged.Metadata[key] = value
+		}
+		if !verification.Verified {
+			merged.Verified = false
+			merged.Rejected = verification.Rejected
+			merged.Reason = verification.Reason
+			return merged, nil
+		}
+	}
+	return merged, nil
+}
+
+// QuorumVerifier re-fetches a candidate's transfer log from independent RPC
+// providers and only verifies it when at least Quorum of them agree on the
+// tx hash, log index, token contract, recipient and amount. For native ETH
+// candidates they must agree that the tx succeeded in the candidate's block,
+// and for a plain transfer also on its recipient and value.
+type QuorumVerifier struct {
+	Endpoints  []string
+	Quorum     int // providers that must agree (default: all endpoints)
+	Tokens     *TokenRegistry
+	HTTPClient *http.Client
+	Logger     *slog.Logger
+}
+
+type quorumVote struct {
+	provider string
+	agreed   bool
+	reason   string
+}
+
+type evmReceipt struct {
+	TransactionHash       string   `json:"transactionHash"`
+	TransactionIndex      string   `json:"transactionIndex"`
+	BlockHash             string   `json:"blockHash"`
+	BlockNumber           string   `json:"blockNumber"`
+	Type                  string   `json:"type"`
+	Status                string   `json:"status"`
+	CumulativeGasUsed     string   `json:"cumulativeGasUsed"`
+	LogsBloom             string   `json:"logsBloom"`
+	Logs                  []evmLog `json:"logs"`
+	DepositNonce          *string  `json:"depositNonce,omitempty"`
+	DepositReceiptVersion *string  `json:"depositReceiptVersion,omitempty"`
+}
+
+func (v QuorumVerifier) effectiveQuorum() int {
+	if v.Quorum > 0 && v.Quorum <= len(v.Endpoints) {
+		return v.Quorum
+	}
+	return len(v.Endpoints)
+}
+
+func (v QuorumVerifier) logger() *slog.Logger {
+	if v.Logger != nil {
+		return v.Logger
+	}
+	return slog.Default()
+}
+
+func (v QuorumVerifier) VerifyCandidate(ctx context.Context, c FundingCandidate) (CandidateVerification, error) {
+	if len(v.Endpoints) == 0 {
+		return CandidateVerification{}, fmt.Errorf("quorum verifier has no endpoints")
+	}
+
+	votes := make([]quorumVote, len(v.Endpoints))
+	var wg sync.WaitGroup
+	for i, endpoint := range v.Endpoints {
+		wg.Add(1)
+		go func(i int, endpoint string) {
+			defer wg.Done()
+			votes[i] = v.vote(ctx, endpoint, c)
+		}(i, endpoint)
+	}
+	wg.Wait()
+
+	agreed := 0
+	disagreements := make([]string, 0)
+	for _, vote := range votes {
+		if vote.agreed {
+			agreed++
+			continue
+		}
+		disagreements = append(disagreements, vote.provider+": "+vote.reason)
+	}
+
+	quorum := v.effectiveQuorum()
+	metadata := map[string]any{
+		"quorumAgreed":    agreed,
+		"quorumProviders": len(v.Endpoints),
+		"quorumRequired":  quorum,
+	}
+
+	if len(disagreements) > 0 {
+		v.logger().Warn("quorum verification disagreement",
+			"chain", c.Chain,
+			"txHash", c.TxHash,
+			"logIndex", c.LogIndex,
+			"depositAddress", c.DepositAddress,
+			"agreed", agreed,
+			"required", quorum,
+			"disagreements", strings.Join(disagreements, "; "),
+		)
+	}
+
+	if agreed < quorum {
+		return CandidateVerification{
+			Verified: false,
+			Reason:   fmt.Sprintf("quorum_not_reached: %d/%d providers agreed", agreed, quorum),
+			Metadata: metadata,
+		}, nil
+	}
+
+	return CandidateVerification{Verified: true, Metadata: metadata}, nil
+}
+
+func (v QuorumVerifier) vote(ctx context.Context, endpoint string, c FundingCandidate) quorumVote {
+	vote := quorumVote{provider: redactRPCURL(endpoint)}
+
+	client := rpcClient{URL: endpoint, HTTPClient: v.HTTPClient, DefaultTimeout: 8 * time.Second}
+	var receipt *evmReceipt
+	if err := client.call(ctx, "eth_getTransactionReceipt", []interface{}{c.TxHash}, &receipt); err != nil {
+		vote.reason = "fetch receipt: " + err.Error()
+		return vote
+	}
+	if receipt == nil {
+		vote.reason = "receipt not found"
+		return vote
+	}
+
+	if isNativeCandidate(c) {
+		vote.reason = compareNativeTransfer(ctx, client, receipt, c)
+		vote.agreed = vote.reason == ""
+		return vote
+	}
+
+	for _, eventLog := range receipt.Logs {
+		logIndex, err := parseHexInt64(eventLog.LogIndex)
+		if err != nil || int(logIndex) != c.LogIndex {
+			continue
+		}
+		vote.reason = compareTransferLog(eventLog, c, v.Tokens)
+		vote.agreed = vote.reason == ""
+		return vote
+	}
+
+	vote.reason = fmt.Sprintf("log index %d not in receipt", c.LogIndex)
+	return vote
+}
+
+// compareNativeTransfer returns an empty string when the provider agrees with a
+// native ETH candidate, or a short mismatch description otherwise. Internal
+// transfers are only visible in traces, so for those the tx status and block
+// are compared.
+func compareNativeTransfer(ctx context.Context, client rpcClient, receipt *evmReceipt, c FundingCandidate) string {
+	if receipt.Status != "0x1" {
+		return "tx failed"
+	}
+	if blockHash, _ := c.Metadata["blockHash"].(string); blockHash != "" && !strings.EqualFold(blockHash, receipt.BlockHash) {
+		return "block hash mismatch"
+	}
+	if c.LogIndex != nativeLogIndexBase {
+		return ""
+	}
+
+	var tx *evmTransaction
+	if err := client.call(ctx, "eth_getTransactionByHash", []interface{}{c.TxHash}, &tx); err != nil {
+		return "fetch transaction: " + err.Error()
+	}
+	if tx == nil {
+		return "transaction not found"
+	}
+	if !strings.EqualFold(tx.To, c.DepositAddress) {
+		return "recipient mismatch"
+	}
+	value, ok := parseNativeValue(tx.Value)
+	if !ok || value.String() != c.AmountBaseUnits {
+		return "amount mismatch"
+	}
+	return ""
+}
+
+// compareTransferLog returns an empty string when the log is the Transfer the
+// candidate describes, or a short mismatch description otherwise.
+func compareTransferLog(eventLog evmLog, c FundingCandidate, tokens *TokenRegistry) string {
+	token, ok := tokens.Token(c.Token)
+	if !ok {
+		return "unknown token"
+	}
+	if !strings.EqualFold(eventLog.Address, token.Contract) {
+		return "token contract mismatch"
+	}
+	if len(eventLog.Topics) < 3 || !strings.EqualFold(eventLog.Topics[0], transferTopic) {
+		return "not a transfer log"
+	}
+	if !strings.EqualFold(addressFromTopic(eventLog.Topics[2]), c.DepositAddress) {
+		return "recipient mismatch"
+	}
+	amount, err := parseTransferAmount(eventLog.Data)
+	if err != nil {
+		return "amount mismatch"
+	}
+	if c.AmountBaseUnits != "" {
+		if amount.String() != c.AmountBaseUnits {
+			return "amount mismatch"
+		}
+	} else if token.AmountUSD(amount) != c.AmountUSD {
+		return "amount mismatch"
+	}
+	return ""
+}
@@ -0,0 +1,227 @@
+package internal
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestQuorumVerifier_RequiresAgreeingProviders(t *testing.T) {
+	honest := newFakeEvmNode(110)
+	honest.addTransfer(105, "0xq", 3, testDepositAddr, 2_500_000)
+	lagging := newFakeEvmNode(110)
+	liar := newFakeEvmNode(110)
+	liar.addTransfer(105, "0xq", 3, testDepositAddr, 9_500_000)
+
+	candidate := FundingCandidate{
+		Chain: "base", Token: "USDC", TxHash: "0xq", LogIndex: 3,
+		DepositAddress: testDepositAddr, AmountUSD: 2.5,
+	}
+	verifier := QuorumVerifier{
+		Endpoints: []string{honest.serve(t).URL, lagging.serve(t).URL, liar.serve(t).URL},
+		Quorum:    2,
+		Tokens:    mustTokenRegistry(),
+		Logger:    slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+
+	verification, err := verifier.VerifyCandidate(context.Background(), candidate)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if verification.Verified {
+		t.Fatalf("expected quorum of 2 to fail with one agreeing provider")
+	}
+	if verification.Metadata["quorumAgreed"] != 1 {
+		t.Fatalf("expected one agreeing provider, got %v", verification.Metadata["quorumAgreed"])
+	}
+
+	verifier.Quorum = 1
+	verification, err = verifier.VerifyCandidate(context.Background(), candidate)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !verification.Verified {
+		t.Fatalf("expected quorum of 1 to pass")
+	}
+}
+
+func TestReceiptVerifier_ChecksStatusLogAndCanonicalBlock(t *testing.T) {
+	node := newFakeEvmNode(110)
+	node.addTransfer(105, "0xr", 2, testDepositAddr, 4_000_000)
+	node.addTransfer(106, "0xf", 0, testDepositAddr, 1_000_000)
+	node.failedTxs = map[string]bool{"0xf": true}
+
+	verifier := ReceiptVerifier{
+		Source: newTestEvmSource(node.serve(t).URL),
+		Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	candidate := FundingCandidate{
+		Chain: "base", Token: "USDC", TxHash: "0xr", LogIndex: 2,
+		DepositAddress: testDepositAddr, AmountUSD: 4,
+		Metadata: map[string]any{"blockHash": node.hashes[105]},
+	}
+
+	cases := []struct {
+		name     string
+		mutate   func(c *FundingCandidate)
+		reason   string
+		rejected bool
+	}{
+		{name: "verified"},
+		{name: "receipt missing", mutate: func(c *FundingCandidate) { c.TxHash = "0xmissing" }, reason: ReceiptReasonNotFound},
+		{name: "tx failed", mutate: func(c *FundingCandidate) { c.TxHash, c.LogIndex, c.AmountUSD = "0xf", 0, 1 }, reason: ReceiptReasonTxFailed, rejected: true},
+		{name: "log index absent", mutate: func(c *FundingCandidate) { c.LogIndex = 7 }, reason: ReceiptReasonLogNotInReceipt},
+		{name: "amount differs", mutate: func(c *FundingCandidate) { c.AmountUSD = 40 }, reason: ReceiptReasonLogMismatch, rejected: true},
+		{name: "stale block hash", mutate: func(c *FundingCandidate) { c.Metadata = map[string]any{"blockHash": "0xother"} }, reason: ReceiptReasonBlockHashMismatch},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := candidate
+			if tc.mutate != nil {
+				tc.mutate(&c)
+			}
+			verification, err := verifier.VerifyCandidate(context.Background(), c)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if verification.Verified != (tc.reason == "") {
+				t.Fatalf("expected verified=%v, got %+v", tc.reason == "", verification)
+			}
+			if verification.Reason != tc.reason || verification.Rejected != tc.rejected {
+				t.Fatalf("expected reason %q rejected=%v, got %q rejected=%v", tc.reason, tc.rejected, verification.Reason, verification.Rejected)
+			}
+		})
+	}
+}
+
+func TestReceiptVerifier_HoldsReceiptFromNonCanonicalBlock(t *testing.T) {
+	node := newFakeEvmNode(110)
+	node.addTransfer(105, "0xr", 2, testDepositAddr, 4_000_000)
+	orphanedHash := node.hashes[105]
+	node.reorg(105, "b")
+	// A lagging node still serves the old receipt, but the canonical header moved on.
+	node.logs = append(node.logs, evmLog{
+		TxHash: "0xr", LogIndex: "0x2", BlockNumber: "0x69", BlockHash: orphanedHash,
+		Data: "0x" + strings.Repeat("0", 58) + "3d0900", Topics: []string{transferTopic, testPayerTopic, mustAddressTopic(t, testDepositAddr)},
+		Address: testUSDCContract,
+	})
+
+	verifier := ReceiptVerifier{
+		Source: newTestEvmSource(node.serve(t).URL),
+		Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	verification, err := verifier.VerifyCandidate(context.Background(), FundingCandidate{
+		Chain: "base", Token: "USDC", TxHash: "0xr", LogIndex: 2,
+		DepositAddress: testDepositAddr, AmountUSD: 4,
+		Metadata: map[string]any{"blockHash": orphanedHash},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if verification.Verified || verification.Rejected || verification.Reason != ReceiptReasonBlockNotCanonical {
+		t.Fatalf("expected held candidate with %q, got %+v", ReceiptReasonBlockNotCanonical, verification)
+	}
+}
+
+func TestReceiptProofVerifier_HoldsNativeCandidatesUnproven(t *testing.T) {
+	node := newFakeEvmNode(110)
+	verifier := ReceiptProofVerifier{
+		Source:       newTestEvmSource(node.serve(t).URL),
+		MinAmountUSD: 100,
+		Logger:       slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	verification, err := verifier.VerifyCandidate(context.Background(), FundingCandidate{
+		Chain: "base", Token: nativeSymbol, TxHash: "0xeth", LogIndex: nativeLogIndexBase,
+		DepositAddress: testDepositAddr, AmountUSD: 250,
+		Metadata: map[string]any{"blockNumber": int64(105)},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if verification.Verified || verification.Rejected || verification.Reason != ReceiptProofReasonUnprovenNative {
+		t.Fatalf("expected native candidate held as %q, got %+v", ReceiptProofReasonUnprovenNative, verification)
+	}
+	if verification.Metadata["receiptProof"] != ReceiptProofReasonUnprovenNative || len(node.calls) != 0 {
+		t.Fatalf("expected no proof attempted, got %+v after %v", verification.Metadata, node.calls)
+	}
+}
+
+func mustAddressTopic(t *testing.T, address string) string {
+	t.Helper()
+	topic, err := encodeAddressTopic(address)
+	if err != nil {
+		t.Fatalf("encode topic: %v", err)
+	}
+	return topic
+}
+
+func TestReceiptProofVerifier_ProvesLogAgainstReceiptsRoot(t *testing.T) {
+	node := newFakeEvmNode(110)
+	node.addTransfer(105, "0xsmall", 0, testDepositAddr, 1_000_000)
+	node.addTransfer(105, "0xlarge", 1, testDepositAddr, 250_000_000)
+	node.sealBlock(105)
+
+	verifier := ReceiptProofVerifier{
+		Source:       newTestEvmSource(node.serve(t).URL),
+		MinAmountUSD: 100,
+		Logger:       slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	large := FundingCandidate{
+		Chain: "base", Token: "USDC", TxHash: "0xlarge", LogIndex: 1,
+		DepositAddress: testDepositAddr, AmountUSD: 250,
+		Metadata: map[string]any{"blockHash": node.hashes[105]},
+	}
+
+	small := large
+	small.TxHash, small.LogIndex, small.AmountUSD = "0xsmall", 0, 1
+	verification, err := verifier.VerifyCandidate(context.Background(), small)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !verification.Verified || node.calls["eth_getBlockReceipts"] != 0 {
+		t.Fatalf("expected candidate below threshold to skip the proof, got %+v", verification)
+	}
+
+	verification, err = verifier.VerifyCandidate(context.Background(), large)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !verification.Verified || verification.Metadata["receiptProof"] != "verified" {
+		t.Fatalf("expected proven candidate, got %+v", verification)
+	}
+
+	// The node now inflates the amount, but the header still commits to the
+	// original receipts.
+	root, _ := receiptsRoot(node.blockReceipts(105))
+	node.pinnedRoots = map[int64]string{105: "0x" + hex.EncodeToString(root)}
+	node.logs[1].Data = fmt.Sprintf("0x%064x", 900_000_000)
+	inflated := large
+	inflated.AmountUSD = 900
+
+	verification, err = verifier.VerifyCandidate(context.Background(), inflated)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if verification.Verified || verification.Rejected || verification.Reason != ReceiptProofReasonRootMismatch {
+		t.Fatalf("expected held candidate with %q, got %+v", ReceiptProofReasonRootMismatch, verification)
+	}
+	if verification.Metadata["receiptProof"] != ReceiptProofReasonRootMismatch {
+		t.Fatalf("expected proof outcome in metadata, got %v", verification.Metadata)
+	}
+
+	// A node that also rewrites the header's receiptsRoot to match is caught
+	// by the header no longer hashing to the recorded block hash.
+	node.pinnedRoots = nil
+	verification, err = verifier.VerifyCandidate(context.Background(), inflated)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if verification.Verified || verification.Rejected || verification.Reason != ReceiptProofReasonHeaderMismatch {
+		t.Fatalf("expected held candidate with %q, got %+v", ReceiptProofReasonHeaderMismatch, verification)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -15,11 +16,18 @@ type FundingCandidate struct {
 	TransferID     string
 	ReferenceHash  string
 	DepositAddress string
-	AmountUSD      float64
-	ConfirmedAt    time.Time
-	Confirmations  int
-	Finalized      bool
-	Metadata       map[string]any
+	// AmountBaseUnits is the exact integer amount in the token's base units
+	// and Amount the same value scaled by TokenDecimals, as a decimal string.
+	AmountBaseUnits string
+	TokenDecimals   int
+	Amount          string
+	AmountUSD       float64 // Deprecated: rounded; kept for one release for core-api compatibility
+	ConfirmedAt     time.Time
+	Confirmations   int
+	Finalized       bool
+	Reverted        bool   // set when a previously reported candidate was dropped by a reorg
+	FilterReason    string // set when the transfer looks like dust or address poisoning
+	Metadata        map[string]any
 }
 
 type RouteMatch struct {
@@ -33,6 +41,7 @@ type RouteResolver interface {
 
 type EventPublisher interface {
 	PublishFundingConfirmed(ctx context.Context, event FundingConfirmedEvent) error
+	PublishFundingReverted(ctx context.Context, event FundingRevertedEvent) error
 }
 
 type FundingConfirmedEvent struct {
@@ -43,9 +52,34 @@ type FundingConfirmedEvent struct {
 	LogIndex       int
 	TransferID     string
 	DepositAddress string
-	AmountUSD      float64
-	ConfirmedAt    time.Time
-	Metadata       map[string]any
+	// AmountBaseUnits is the exact integer amount in the token's base units
+	// and Amount the same value scaled by TokenDecimals, as a decimal string.
+	AmountBaseUnits string
+	TokenDecimals   int
+	Amount          string
+	AmountUSD       float64 // Deprecated: rounded; kept for one release for core-api compatibility
+	ConfirmedAt     time.Time
+	Metadata        map[string]any
+}
+
+// FundingRevertedEvent reports that a previously confirmed deposit is no longer
+// part of the canonical chain. EventID matches the original confirmed event.
+type FundingRevertedEvent struct {
+	EventID        string
+	Chain          string
+	Token          string
+	TxHash         string
+	LogIndex       int
+	TransferID     string
+	DepositAddress string
+	// AmountBaseUnits is the exact integer amount in the token's base units
+	// and Amount the same value scaled by TokenDecimals, as a decimal string.
+	AmountBaseUnits string
+	TokenDecimals   int
+	Amount          string
+	AmountUSD       float64 // Deprecated: rounded; kept for one release for core-api compatibility
+	Reason          string
+	Metadata        map[string]any
 }
 
 type ProcessResult string
@@ -54,6 +88,16 @@ const (
 	ProcessIgnored       ProcessResult = "ignored"
 	ProcessConfirmed     ProcessResult = "confirmed"
 	ProcessRouteNotFound ProcessResult = "route_not_found"
+	ProcessReverted      ProcessResult = "reverted"
+	ProcessUnverified    ProcessResult = "unverified"
+	ProcessRejected      ProcessResult = "rejected"
+	ProcessFiltered      ProcessResult = "filtered"
+	ProcessInternal      ProcessResult = "internal"
+	ProcessUnroutable    ProcessResult = "unroutable"
+
+	// ProcessRevertUndelivered means a revert was found but could not be sent
+	// because no reverted endpoint is configured.
+	ProcessRevertUndelivered ProcessResult = "revert_undelivered"
 )
 
 var ErrInvalidChain = errors.New("invalid chain for watcher")
@@ -63,6 +107,17 @@ type Watcher struct {
 	MinConfirmations int
 	Resolver         RouteResolver
 	Publisher        EventPublisher
+	Verifier         CandidateVerifier // optional check run before publishing
+
+	// InternalAddresses holds our own sweeper, treasury and hot-wallet
+	// addresses, lower-cased. Transfers paid from them are internal movements,
+	// not customer funding.
+	InternalAddresses map[string]bool
+
+	// RoutableTokens holds the token symbols core-api can route. Candidates in
+	// any other token, such as native ETH, are reported but never resolved.
+	// Nil routes every token.
+	RoutableTokens map[string]bool
 }
 
 func (w Watcher) ProcessCandidate(ctx context.Context, c FundingCandidate) (ProcessResult, error) {
@@ -75,6 +130,10 @@ func (w Watcher) ProcessCandidateWithMatch(ctx context.Context, c FundingCandida
 		return ProcessIgnored, "", "", ErrInvalidChain
 	}
 
+	if c.Reverted {
+		return w.processReverted(ctx, c)
+	}
+
 	// Use Finalized flag if available, otherwise fall back to confirmation count
 	if !c.Finalized && c.Confirmations < w.MinConfirmations {
 		return ProcessIgnored, "", "", nil
@@ -83,15 +142,21 @@ func (w Watcher) ProcessCandidateWithMatch(ctx context.Context, c FundingCandida
 		return ProcessIgnored, "", "", nil
 	}
 
-	match := RouteMatch{TransferID: c.TransferID, DepositAddress: c.DepositAddress}
-	found := match.TransferID != ""
+	// Dust and poisoning transfers are reported, but never resolved.
+	if c.FilterReason != "" {
+		return ProcessFiltered, "", c.DepositAddress, nil
+	}
+	if w.isInternalMovement(c) {
+		return ProcessInternal, "", c.DepositAddress, nil
+	}
+
+	if w.RoutableTokens != nil && !w.RoutableTokens[c.Token] {
+		return ProcessUnroutable, "", c.DepositAddress, nil
+	}
 
-	if !found {
-		var err error
-		match, found, err = w.Resolver.FindTransferByRoute(ctx, c.Chain, c.Token, c.DepositAddress)
-		if err != nil {
-			return ProcessIgnored, "", "", err
-		}
+	match, found, err := w.resolveMatch(ctx, c)
+	if err != nil {
+		return ProcessIgnored, "", "", err
 	}
 
 	if !found {
@@ -102,31 +167,47 @@ func (w Watcher) ProcessCandidateWithMatch(ctx context.Context, c FundingCandida
 	if depositAddress == "" {
 		depositAddress = match.DepositAddress
 	}
-	eventID := match.TransferID + ":" + c.TxHash
-	if c.LogIndex > 0 {
-		eventID = eventID + ":" + strconv.Itoa(c.LogIndex)
-	}
+	eventID := buildFundingEventID(match.TransferID, c)
 
-	metadata := c.Metadata
-	if metadata == nil {
-		metadata = map[string]any{}
+	metadata := make(map[string]any, len(c.Metadata))
+	for key, value := range c.Metadata {
+		metadata[key] = value
 	}
 	// Extract payer address from Transfer event "from" topic if available
 	if c.DepositAddress != "" && metadata["payerAddress"] == nil {
 		metadata["verificationSource"] = "base_watcher"
 	}
 
+	if w.Verifier != nil {
+		verification, err := w.Verifier.VerifyCandidate(ctx, c)
+		if err != nil {
+			return ProcessIgnored, "", "", err
+		}
+		for key, value := range verification.Metadata {
+			metadata[key] = value
+		}
+		if verification.Rejected {
+			return ProcessRejected, match.TransferID, depositAddress, nil
+		}
+		if !verification.Verified {
+			return ProcessUnverified, match.TransferID, depositAddress, nil
+		}
+	}
+
 	event := FundingConfirmedEvent{
-		EventID:        eventID,
-		Chain:          c.Chain,
-		Token:          c.Token,
-		TxHash:         c.TxHash,
-		LogIndex:       c.LogIndex,
-		TransferID:     match.TransferID,
-		DepositAddress: depositAddress,
-		AmountUSD:      c.AmountUSD,
-		ConfirmedAt:    c.ConfirmedAt,
-		Metadata:       metadata,
+		EventID:         eventID,
+		Chain:           c.Chain,
+		Token:           c.Token,
+		TxHash:          c.TxHash,
+		LogIndex:        c.LogIndex,
+		TransferID:      match.TransferID,
+		DepositAddress:  depositAddress,
+		AmountBaseUnits: c.AmountBaseUnits,
+		TokenDecimals:   c.TokenDecimals,
+		Amount:          c.Amount,
+		AmountUSD:       c.AmountUSD,
+		ConfirmedAt:     c.ConfirmedAt,
+		Metadata:        metadata,
 	}
 
 	if err := w.Publisher.PublishFundingConfirmed(ctx, event); err != nil {
@@ -135,3 +216,65 @@ func (w Watcher) ProcessCandidateWithMatch(ctx context.Context, c FundingCandida
 
 	return ProcessConfirmed, match.TransferID, depositAddress, nil
 }
+
+// isInternalMovement reports whether the candidate was paid from one of our
+// own addresses.
+func (w Watcher) isInternalMovement(c FundingCandidate) bool {
+	payer, _ := c.Metadata["payerAddress"].(string)
+	return payer != "" && w.InternalAddresses[strings.ToLower(payer)]
+}
+
+func (w Watcher) processReverted(ctx context.Context, c FundingCandidate) (ProcessResult, string, string, error) {
+	match, found, err := w.resolveMatch(ctx, c)
+	if err != nil {
+		return ProcessIgnored, "", "", err
+	}
+	if !found {
+		return ProcessRouteNotFound, "", c.DepositAddress, nil
+	}
+
+	depositAddress := c.DepositAddress
+	if depositAddress == "" {
+		depositAddress = match.DepositAddress
+	}
+
+	event := FundingRevertedEvent{
+		EventID:         buildFundingEventID(match.TransferID, c),
+		Chain:           c.Chain,
+		Token:           c.Token,
+		TxHash:          c.TxHash,
+		LogIndex:        c.LogIndex,
+		TransferID:      match.TransferID,
+		DepositAddress:  depositAddress,
+		AmountBaseUnits: c.AmountBaseUnits,
+		TokenDecimals:   c.TokenDecimals,
+		Amount:          c.Amount,
+		AmountUSD:       c.AmountUSD,
+		Reason:          "reorg",
+		Metadata:        c.Metadata,
+	}
+
+	if err := w.Publisher.PublishFundingReverted(ctx, event); err != nil {
+		if errors.Is(err, ErrRevertNotDelivered) {
+			return ProcessRevertUndelivered, match.TransferID, depositAddress, nil
+		}
+		return ProcessIgnored, "", "", err
+	}
+
+	return ProcessReverted, match.TransferID, depositAddress, nil
+}
+
+func (w Watcher) resolveMatch(ctx context.Context, c FundingCandidate) (RouteMatch, bool, error) {
+	if c.TransferID != "" {
+		return RouteMatch{TransferID: c.TransferID, DepositAddress: c.DepositAddress}, true, nil
+	}
+	return w.Resolver.FindTransferByRoute(ctx, c.Chain, c.Token, c.DepositAddress)
+}
+
+func buildFundingEventID(transferID string, c FundingCandidate) string {
+	eventID := transferID + ":" + c.TxHash
+	if c.LogIndex > 0 {
+		eventID = eventID + ":" + strconv.Itoa(c.LogIndex)
+	}
+	return eventID
+}
@@ -18,8 +18,9 @@ func (r resolverStub) FindTransferByRoute(_ context.Context, _ string, _ string,
 }
 
 type publisherStub struct {
-	err        error
-	calledWith []FundingConfirmedEvent
+	err          error
+	calledWith   []FundingConfirmedEvent
+	revertedWith []FundingRevertedEvent
 }
 
 func (p *publisherStub) PublishFundingConfirmed(_ context.Context, event FundingConfirmedEvent) error {
@@ -27,6 +28,11 @@ func (p *publisherStub) PublishFundingConfirmed(_ context.Context, event Funding
 	return p.err
 }
 
+func (p *publisherStub) PublishFundingReverted(_ context.Context, event FundingRevertedEvent) error {
+	p.revertedWith = append(p.revertedWith, event)
+	return p.err
+}
+
 func TestWatcher_IgnoresBeforeMinConfirmations(t *testing.T) {
 	pub := &publisherStub{}
 	w := Watcher{Chain: "base", MinConfirmations: 2, Resolver: resolverStub{found: true, match: RouteMatch{TransferID: "tr_1"}}, Publisher: pub}
@@ -161,3 +167,115 @@ func TestWatcher_PrePopulatedTransferIDSkipsResolver(t *testing.T) {
 		t.Fatalf("expected pre-populated transfer id")
 	}
 }
+
+func TestWatcher_PublishesRevertedWithOriginalEventID(t *testing.T) {
+	pub := &publisherStub{}
+	w := Watcher{Chain: "base", MinConfirmations: 5, Resolver: resolverStub{found: true, match: RouteMatch{TransferID: "tr_rev"}}, Publisher: pub}
+
+	result, err := w.ProcessCandidate(context.Background(), FundingCandidate{
+		Chain: "base", Token: "USDC", TxHash: "0xrev", LogIndex: 4,
+		DepositAddress: "dep_rev", AmountUSD: 50, Reverted: true,
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if result != ProcessReverted {
+		t.Fatalf("expected reverted, got %s", result)
+	}
+	if len(pub.calledWith) != 0 {
+		t.Fatalf("confirmed publisher should not be called")
+	}
+	if len(pub.revertedWith) != 1 {
+		t.Fatalf("expected one reverted event")
+	}
+	if pub.revertedWith[0].EventID != "tr_rev:0xrev:4" {
+		t.Fatalf("expected original event id, got %s", pub.revertedWith[0].EventID)
+	}
+}
+
+type verifierStub struct {
+	verification CandidateVerification
+	err          error
+}
+
+func (v verifierStub) VerifyCandidate(_ context.Context, _ FundingCandidate) (CandidateVerification, error) {
+	return v.verification, v.err
+}
+
+func TestWatcher_HoldsBackUnverifiedCandidates(t *testing.T) {
+	pub := &publisherStub{}
+	w := Watcher{
+		Chain:            "base",
+		MinConfirmations: 1,
+		Resolver:         resolverStub{found: true, match: RouteMatch{TransferID: "tr_q"}},
+		Publisher:        pub,
+		Verifier:         verifierStub{verification: CandidateVerification{Verified: false, Reason: "quorum_not_reached"}},
+	}
+
+	result, transferID, _, err := w.ProcessCandidateWithMatch(context.Background(), FundingCandidate{
+		Chain: "base", Token: "USDC", TxHash: "0xq", DepositAddress: "dep_q",
+		Confirmations: 5, ConfirmedAt: time.Date(2026, 2, 13, 12, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if result != ProcessUnverified || transferID != "tr_q" {
+		t.Fatalf("expected unverified for tr_q, got %s %s", result, transferID)
+	}
+	if len(pub.calledWith) != 0 {
+		t.Fatalf("unverified candidates must not be published")
+	}
+}
+
+func TestWatcher_InternalMovementIsNotPublished(t *testing.T) {
+	pub := &publisherStub{}
+	w := Watcher{
+		Chain:             "base",
+		MinConfirmations:  1,
+		Resolver:          resolverStub{err: errors.New("resolver must not be called")},
+		Publisher:         pub,
+		InternalAddresses: map[string]bool{"0x9999999999999999999999999999999999999999": true},
+	}
+
+	candidate := FundingCandidate{
+		Chain: "base", Token: "USDC", TxHash: "0x1", Confirmations: 0, ConfirmedAt: time.Date(2026, 2, 13, 12, 0, 0, 0, time.UTC),
+		Metadata: map[string]any{"payerAddress": "0x9999999999999999999999999999999999999999"},
+	}
+
+	// Like any other transfer, it is only classified once confirmed, so its
+	// event key is not marked while it can still be reorged.
+	result, err := w.ProcessCandidate(context.Background(), candidate)
+	if err != nil || result != ProcessIgnored {
+		t.Fatalf("expected an unconfirmed internal movement to be ignored, got %s (%v)", result, err)
+	}
+
+	candidate.Confirmations = 5
+	result, err = w.ProcessCandidate(context.Background(), candidate)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if result != ProcessInternal || len(pub.calledWith) != 0 {
+		t.Fatalf("expected an internal movement without publishing, got %s", result)
+	}
+}
+
+func TestWatcher_UnroutableTokenIsNotResolved(t *testing.T) {
+	pub := &publisherStub{}
+	w := Watcher{
+		Chain:            "base",
+		MinConfirmations: 1,
+		Resolver:         resolverStub{err: errors.New("resolver must not be called")},
+		Publisher:        pub,
+		RoutableTokens:   map[string]bool{"USDC": true, "USDT": true},
+	}
+
+	result, err := w.ProcessCandidate(context.Background(), FundingCandidate{
+		Chain: "base", Token: "ETH", TxHash: "0x1", Confirmations: 5, ConfirmedAt: time.Date(2026, 2, 13, 12, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if result != ProcessUnroutable || len(pub.calledWith) != 0 {
+		t.Fatalf("expected an unroutable candidate without publishing, got %s", result)
+	}
+}
@@ -0,0 +1,232 @@
+package internal
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// wsConn is a minimal RFC 6455 client connection, enough for JSON-RPC
+// subscriptions: text messages, fragmentation, ping/pong and close.
+type wsConn struct {
+	conn           net.Conn
+	reader         *bufio.Reader
+	writeMu        sync.Mutex
+	maxMessageSize int
+}
+
+const wsAcceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+var errWebsocketClosed = errors.New("websocket closed by peer")
+
+func dialWebsocket(ctx context.Context, rawURL string) (*wsConn, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse websocket url: %w", err)
+	}
+
+	host := parsed.Host
+	secure := false
+	switch parsed.Scheme {
+	case "ws":
+		if parsed.Port() == "" {
+			host = net.JoinHostPort(parsed.Hostname(), "80")
+		}
+	case "wss":
+		secure = true
+		if parsed.Port() == "" {
+			host = net.JoinHostPort(parsed.Hostname(), "443")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported websocket scheme %q", parsed.Scheme)
+	}
+
+	dialer := &net.Dialer{Timeout: 15 * time.Second}
+	conn, err := dialer.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return nil, err
+	}
+	if secure {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: parsed.Hostname(), MinVersion: tls.VersionTLS12})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+
+	ws, err := websocketHandshake(ctx, conn, parsed)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return ws, nil
+}
+
+func websocketHandshake(ctx context.Context, conn net.Conn, target *url.URL) (*wsConn, error) {
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	} else {
+		_ = conn.SetDeadline(time.Now().Add(15 * time.Second))
+	}
+	defer conn.SetDeadline(time.Time{})
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(nonce)
+
+	request := "GET " + target.RequestURI() + " HTTP/1.1\r\n" +
+		"Host: " + target.Host + "\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Key: " + key + "\r\n" +
+		"Sec-WebSocket-Version: 13\r\n\r\n"
+	if _, err := io.WriteString(conn, request); err != nil {
+		return nil, err
+	}
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, &http.Request{Method: http.MethodGet})
+	if err != nil {
+		return nil, fmt.Errorf("websocket handshake: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		return nil, fmt.Errorf("websocket handshake: %w", &rpcStatusError{StatusCode: resp.StatusCode})
+	}
+	if resp.Header.Get("Sec-WebSocket-Accept") != websocketAccept(key) {
+		return nil, fmt.Errorf("websocket handshake: invalid Sec-WebSocket-Accept")
+	}
+
+	return &wsConn{conn: conn, reader: reader, maxMessageSize: 16 << 20}, nil
+}
+
+func websocketAccept(key string) string {
+	sum := sha1.Sum([]byte(key + wsAcceptGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// writeText sends one masked text frame.
+func (c *wsConn) writeText(payload []byte) error {
+	return c.writeFrame(0x1, payload)
+}
+
+func (c *wsConn) writeFrame(opcode byte, payload []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	header := []byte{0x80 | opcode}
+	switch {
+	case len(payload) < 126:
+		header = append(header, 0x80|byte(len(payload)))
+	case len(payload) <= 0xffff:
+		header = append(header, 0x80|126, 0, 0)
+		binary.BigEndian.PutUint16(header[2:], uint16(len(payload)))
+	default:
+		header = append(header, 0x80|127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(header[2:], uint64(len(payload)))
+	}
+
+	mask := make([]byte, 4)
+	if _, err := rand.Read(mask); err != nil {
+		return err
+	}
+	masked := make([]byte, len(payload))
+	for i := range payload {
+		masked[i] = payload[i] ^ mask[i%4]
+	}
+
+	_, err := c.conn.Write(append(append(header, mask...), masked...))
+	return err
+}
+
+// readMessage returns the next complete data message, answering pings on the
+// way. idleTimeout bounds how long the peer may stay silent.
+func (c *wsConn) readMessage(idleTimeout time.Duration) ([]byte, error) {
+	message := make([]byte, 0)
+	for {
+		if idleTimeout > 0 {
+			_ = c.conn.SetReadDeadline(time.Now().Add(idleTimeout))
+		}
+
+		var head [2]byte
+		if _, err := io.ReadFull(c.reader, head[:]); err != nil {
+			return nil, err
+		}
+		fin := head[0]&0x80 != 0
+		opcode := head[0] & 0x0f
+		masked := head[1]&0x80 != 0
+
+		length := uint64(head[1] & 0x7f)
+		switch length {
+		case 126:
+			var ext [2]byte
+			if _, err := io.ReadFull(c.reader, ext[:]); err != nil {
+				return nil, err
+			}
+			length = uint64(binary.BigEndian.Uint16(ext[:]))
+		case 127:
+			var ext [8]byte
+			if _, err := io.ReadFull(c.reader, ext[:]); err != nil {
+				return nil, err
+			}
+			length = binary.BigEndian.Uint64(ext[:])
+		}
+		if length > uint64(c.maxMessageSize) || uint64(len(message))+length > uint64(c.maxMessageSize) {
+			return nil, fmt.Errorf("websocket message exceeds %d bytes", c.maxMessageSize)
+		}
+
+		var mask [4]byte
+		if masked {
+			if _, err := io.ReadFull(c.reader, mask[:]); err != nil {
+				return nil, err
+			}
+		}
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(c.reader, payload); err != nil {
+			return nil, err
+		}
+		if masked {
+			for i := range payload {
+				payload[i] ^= mask[i%4]
+			}
+		}
+
+		switch opcode {
+		case 0x8:
+			_ = c.writeFrame(0x8, nil)
+			return nil, errWebsocketClosed
+		case 0x9:
+			if err := c.writeFrame(0xA, payload); err != nil {
+				return nil, err
+			}
+			continue
+		case 0xA:
+			continue
+		}
+
+		message = append(message, payload...)
+		if fin {
+			return message, nil
+		}
+	}
+}
+
+func (c *wsConn) close() error {
+	_ = c.writeFrame(0x8, nil)
+	return c.conn.Close()
+}
@@ -8,6 +8,7 @@ import (
 	"os"
 	"os/signal"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
@@ -53,12 +54,51 @@ func envIntOrDefault(name string, fallback int) int {
 	return parsed
 }
 
+func envFloatOrDefault(name string, fallback float64) float64 {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return fallback
+	}
+	return parsed
+}
+
+// defaultSolanaMethodCosts are credits per call on Helius-style plans, where
+// archival lookups cost more than slot and account reads;
+// SOLANA_RPC_METHOD_COSTS overrides individual methods.
+var defaultSolanaMethodCosts = map[string]float64{
+	"getSlot":                 1,
+	"getBlockTime":            1,
+	"getAccountInfo":          1,
+	"getFirstAvailableBlock":  1,
+	"getSignaturesForAddress": 10,
+	"getTransaction":          10,
+}
+
+func envListOrDefault(name string, fallback []string) []string {
+	value := strings.TrimSpace(os.Getenv(name))
+	if value == "" {
+		return fallback
+	}
+
+	items := make([]string, 0)
+	for _, item := range strings.Split(value, ",") {
+		if trimmed := strings.TrimSpace(item); trimmed != "" {
+			items = append(items, trimmed)
+		}
+	}
+	return items
+}
+
 func main() {
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer cancel()
 
 	coreAPIURL := envOrDefault("CORE_API_URL", "http://localhost:3001")
-	rpcURL := os.Getenv("SOLANA_RPC_URL")
+	rpcURLs := envListOrDefault("SOLANA_RPC_URLS", envListOrDefault("SOLANA_RPC_URL", nil))
 	callbackURL := envOrDefault("CORE_API_FUNDING_CALLBACK_URL", "http://localhost:3001/internal/v1/funding-confirmed")
 	callbackSecret := envOrDefault("WATCHER_CALLBACK_SECRET", "dev-callback-secret-change-me")
 
@@ -76,26 +116,84 @@ func main() {
 	checkpointStore := internal.CoreAPICheckpointStore{Client: &client, WatcherName: "solana-watcher", Chain: "solana"}
 	dedupeStore := internal.CoreAPIDedupeStore{Client: &client, WatcherName: "solana-watcher"}
 
+	var rpcPool *internal.RPCPool
+	if len(rpcURLs) > 0 {
+		rpcPool = internal.NewRPCPool("solana-rpc", rpcURLs, slog.Default())
+		rpcPool.Cooldown = time.Duration(envIntOrDefault("SOLANA_RPC_PROVIDER_COOLDOWN_MS", 15000)) * time.Millisecond
+	}
+
+	methodCosts, err := internal.ParseMethodCosts(os.Getenv("SOLANA_RPC_METHOD_COSTS"), defaultSolanaMethodCosts)
+	if err != nil {
+		log.Fatalf("invalid SOLANA_RPC_METHOD_COSTS: %v", err)
+	}
+	rpcBudget := &internal.RPCBudget{
+		Name:           "solana-rpc",
+		Logger:         slog.Default(),
+		UnitsPerSecond: envFloatOrDefault("SOLANA_RPC_UNITS_PER_SECOND", 0),
+		Burst:          envFloatOrDefault("SOLANA_RPC_BURST_UNITS", 0),
+		MethodCosts:    methodCosts,
+		PeriodLimit:    envFloatOrDefault("SOLANA_RPC_MONTHLY_UNITS", 0),
+		MaxSlowdown:    envFloatOrDefault("SOLANA_RPC_MAX_SLOWDOWN", 8),
+	}
+	go rpcBudget.LogReport(ctx, time.Duration(envIntOrDefault("SOLANA_RPC_USAGE_REPORT_INTERVAL_MS", 300000))*time.Millisecond)
+
+	tokens, err := internal.NewTokenRegistry([]internal.TokenInfo{
+		{
+			Symbol:   "USDC",
+			Mint:     envFirstOrDefault([]string{"SOLANA_USDC_MINT", "NEXT_PUBLIC_SOLANA_USDC_MINT"}, defaultDevnetUSDCMint),
+			Decimals: envIntOrDefault("SOLANA_USDC_DECIMALS", 6),
+			Name:     "USD Coin",
+		},
+		{
+			Symbol:   "USDT",
+			Mint:     envFirstOrDefault([]string{"SOLANA_USDT_MINT", "NEXT_PUBLIC_SOLANA_USDT_MINT"}, defaultDevnetUSDTMint),
+			Decimals: envIntOrDefault("SOLANA_USDT_DECIMALS", 6),
+			Name:     "Tether USD",
+		},
+	})
+	if err != nil {
+		log.Fatalf("invalid solana token configuration: %v", err)
+	}
+
 	source := internal.SolanaRpcSource{
-		RPCURL:     rpcURL,
+		RPCPool:    rpcPool,
+		Budget:     rpcBudget,
 		HTTPClient:   &http.Client{Timeout: 60 * time.Second},
 		RouteStore: routeStore,
 		ProgramID:  envFirstOrDefault([]string{"SOLANA_PROGRAM_ID", "NEXT_PUBLIC_SOLANA_PROGRAM_ID"}, defaultDevnetProgramID),
 		Chain:      "solana",
 		Limit:      envIntOrDefault("SOLANA_SIGNATURE_LIMIT", 100),
-		TokenMints: map[string]string{
-			"USDC": envFirstOrDefault([]string{"SOLANA_USDC_MINT", "NEXT_PUBLIC_SOLANA_USDC_MINT"}, defaultDevnetUSDCMint),
-			"USDT": envFirstOrDefault([]string{"SOLANA_USDT_MINT", "NEXT_PUBLIC_SOLANA_USDT_MINT"}, defaultDevnetUSDTMint),
-		},
+		MaxSignaturePages: envIntOrDefault("SOLANA_SIGNATURE_MAX_PAGES", 10),
+		MaxBatchSize: envIntOrDefault("SOLANA_RPC_BATCH_SIZE", 50),
+		FetchWorkers: envIntOrDefault("SOLANA_FETCH_WORKERS", 4),
+		FetchTimeout: time.Duration(envIntOrDefault("SOLANA_FETCH_TIMEOUT_MS", 20000)) * time.Millisecond,
+		Tokens:     tokens,
 		TreasuryATAs: map[string]string{
 			"USDC": envFirstOrDefault([]string{"SOLANA_USDC_TREASURY_ATA", "NEXT_PUBLIC_SOLANA_USDC_TREASURY_ATA"}, defaultDevnetUSDCTreasuryATA),
 			"USDT": envFirstOrDefault([]string{"SOLANA_USDT_TREASURY_ATA", "NEXT_PUBLIC_SOLANA_USDT_TREASURY_ATA"}, defaultDevnetUSDTTreasuryATA),
 		},
 	}
+	if err := source.VerifyTokenDecimals(ctx); err != nil {
+		log.Fatalf("verify solana token decimals: %v", err)
+	}
+
+	// Transfers from our own wallets and token accounts into deposit addresses
+	// are not funding; the treasury token accounts are always included.
+	internalAddresses := make(map[string]bool)
+	internalList := envListOrDefault("SOLANA_INTERNAL_ADDRESSES", nil)
+	for _, address := range source.TreasuryATAs {
+		internalList = append(internalList, address)
+	}
+	for _, address := range internalList {
+		if address = strings.TrimSpace(address); address != "" {
+			internalAddresses[address] = true
+		}
+	}
 
 	watcher := internal.Watcher{
-		Chain:    "solana",
-		Resolver: routeResolver,
+		Chain:             "solana",
+		Resolver:          routeResolver,
+		InternalAddresses: internalAddresses,
 		Publisher: internal.CallbackPublisher{
 			Endpoint:   callbackURL,
 			Secret:     callbackSecret,
@@ -105,14 +203,33 @@ func main() {
 		},
 	}
 
+	var candidateSource internal.CandidateSource = source
+	if wsURL := os.Getenv("SOLANA_WS_URL"); wsURL != "" {
+		wsSource := internal.NewSolanaWebsocketSource(wsURL, source, slog.Default())
+		wsSource.Start(ctx)
+		candidateSource = wsSource
+		slog.Info("solana-watcher websocket push mode enabled")
+	}
+
+	var startAt time.Time
+	if raw := os.Getenv("SOLANA_START_FROM"); raw != "" {
+		startAt, err = internal.ParseStartTime(raw)
+		if err != nil {
+			log.Fatalf("invalid SOLANA_START_FROM: %v", err)
+		}
+	}
+
 	runner := internal.Runner{
 		Name:            "solana-watcher",
 		PollInterval:    time.Duration(envIntOrDefault("SOLANA_POLL_INTERVAL_MS", 5000)) * time.Millisecond,
-		Source:          source,
+		Source:          candidateSource,
 		Watcher:         watcher,
 		CheckpointStore: checkpointStore,
 		DedupeStore:     dedupeStore,
 		Logger:          slog.Default(),
+		Budget:          rpcBudget,
+		StartAt:         startAt,
+		StartMarker:     internal.CoreAPICheckpointStore{Client: &client, WatcherName: "solana-watcher-start", Chain: "solana"},
 	}
 
 	if err := runner.Run(ctx); err != nil {
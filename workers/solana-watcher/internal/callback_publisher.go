@@ -14,16 +14,19 @@ import (
 )
 
 type callbackPayload struct {
-	EventID        string         `json:"eventId"`
-	Chain          string         `json:"chain"`
-	Token          string         `json:"token"`
-	TxHash         string         `json:"txHash"`
-	LogIndex       int            `json:"logIndex"`
-	TransferID     string         `json:"transferId,omitempty"`
-	DepositAddress string         `json:"depositAddress"`
-	AmountUSD      float64        `json:"amountUsd"`
-	ConfirmedAt    string         `json:"confirmedAt"`
-	Metadata       map[string]any `json:"metadata,omitempty"`
+	EventID         string         `json:"eventId"`
+	Chain           string         `json:"chain"`
+	Token           string         `json:"token"`
+	TxHash          string         `json:"txHash"`
+	LogIndex        int            `json:"logIndex"`
+	TransferID      string         `json:"transferId,omitempty"`
+	DepositAddress  string         `json:"depositAddress"`
+	AmountBaseUnits string         `json:"amountBaseUnits,omitempty"`
+	TokenDecimals   int            `json:"tokenDecimals"`
+	Amount          string         `json:"amount,omitempty"`
+	AmountUSD       float64        `json:"amountUsd"` // Deprecated: use amountBaseUnits and tokenDecimals
+	ConfirmedAt     string         `json:"confirmedAt"`
+	Metadata        map[string]any `json:"metadata,omitempty"`
 }
 
 type CallbackPublisher struct {
@@ -57,16 +60,19 @@ func (p CallbackPublisher) PublishFundingConfirmed(ctx context.Context, event Fu
 	}
 
 	payload := callbackPayload{
-		EventID:        event.EventID,
-		Chain:          event.Chain,
-		Token:          event.Token,
-		TxHash:         event.TxHash,
-		LogIndex:       event.LogIndex,
-		TransferID:     event.TransferID,
-		DepositAddress: event.DepositAddress,
-		AmountUSD:      event.AmountUSD,
-		ConfirmedAt:    event.ConfirmedAt.UTC().Format(time.RFC3339Nano),
-		Metadata:       event.Metadata,
+		EventID:         event.EventID,
+		Chain:           event.Chain,
+		Token:           event.Token,
+		TxHash:          event.TxHash,
+		LogIndex:        event.LogIndex,
+		TransferID:      event.TransferID,
+		DepositAddress:  event.DepositAddress,
+		AmountBaseUnits: event.AmountBaseUnits,
+		TokenDecimals:   event.TokenDecimals,
+		Amount:          event.Amount,
+		AmountUSD:       event.AmountUSD,
+		ConfirmedAt:     event.ConfirmedAt.UTC().Format(time.RFC3339Nano),
+		Metadata:        event.Metadata,
 	}
 
 	body, err := json.Marshal(payload)
@@ -3,11 +3,23 @@ package internal
 import (
 	"context"
 	"fmt"
+	"strings"
+	"time"
 )
 
 type ActiveRoute struct {
 	Token          string `json:"token"`
 	DepositAddress string `json:"depositAddress"`
+	CreatedAt      string `json:"createdAt,omitempty"` // RFC3339; empty from core APIs that predate it
+}
+
+// createdAt returns when the route was created, or the zero time if unknown.
+func (r ActiveRoute) createdAt() time.Time {
+	parsed, err := time.Parse(time.RFC3339, strings.TrimSpace(r.CreatedAt))
+	if err != nil {
+		return time.Time{}
+	}
+	return parsed
 }
 
 type RouteStore interface {
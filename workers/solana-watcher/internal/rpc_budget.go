@@ -0,0 +1,290 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RPCBudget meters RPC traffic in provider compute units. A token bucket
+// refilled at UnitsPerSecond paces requests, and each method is charged its
+// weight from MethodCosts. Usage is also counted against PeriodLimit units per
+// Period (a provider's monthly plan); when spending runs ahead of the period's
+// pace, Slowdown rises so the watcher polls less often and batches less,
+// instead of exhausting the plan and getting cut off.
+type RPCBudget struct {
+	Name           string // log prefix, e.g. "solana-rpc"
+	Logger         *slog.Logger
+	UnitsPerSecond float64            // token bucket refill rate; zero disables pacing
+	Burst          float64            // bucket capacity (default: one second of UnitsPerSecond)
+	MethodCosts    map[string]float64 // compute units per call of a method
+	DefaultCost    float64            // cost of methods missing from MethodCosts (default: 1)
+	PeriodLimit    float64            // units allowed per Period; zero disables the period cap
+	Period         time.Duration      // default: 30 days
+	MaxSlowdown    float64            // cap for Slowdown (default: 8)
+
+	mu          sync.Mutex
+	tokens      float64
+	refilledAt  time.Time
+	periodStart time.Time
+	periodUsed  float64
+	usage       map[string]*RPCMethodUsage
+	now         func() time.Time
+	sleep       func(ctx context.Context, d time.Duration) error
+}
+
+// RPCMethodUsage is the traffic one method has sent since the budget started.
+type RPCMethodUsage struct {
+	Method string
+	Calls  int64
+	Units  float64
+}
+
+func (b *RPCBudget) logger() *slog.Logger {
+	if b.Logger != nil {
+		return b.Logger
+	}
+	return slog.Default()
+}
+
+func (b *RPCBudget) clock() time.Time {
+	if b.now != nil {
+		return b.now()
+	}
+	return time.Now()
+}
+
+func (b *RPCBudget) effectiveBurst() float64 {
+	if b.Burst > 0 {
+		return b.Burst
+	}
+	return b.UnitsPerSecond
+}
+
+func (b *RPCBudget) effectivePeriod() time.Duration {
+	if b.Period > 0 {
+		return b.Period
+	}
+	return 30 * 24 * time.Hour
+}
+
+func (b *RPCBudget) effectiveMaxSlowdown() float64 {
+	if b.MaxSlowdown > 1 {
+		return b.MaxSlowdown
+	}
+	return 8
+}
+
+func (b *RPCBudget) cost(method string) float64 {
+	if cost, ok := b.MethodCosts[method]; ok {
+		return cost
+	}
+	if b.DefaultCost > 0 {
+		return b.DefaultCost
+	}
+	return 1
+}
+
+// acquire charges the given methods, one call each, and blocks until the
+// bucket has paid off their cost. A batch larger than the bucket goes through
+// and leaves the bucket in debt, which the following calls wait out. A nil
+// budget never blocks.
+func (b *RPCBudget) acquire(ctx context.Context, methods ...string) error {
+	if b == nil || len(methods) == 0 {
+		return nil
+	}
+
+	b.mu.Lock()
+	now := b.clock()
+	b.rollPeriod(now)
+
+	total := 0.0
+	for _, method := range methods {
+		cost := b.cost(method)
+		total += cost
+		if b.usage == nil {
+			b.usage = make(map[string]*RPCMethodUsage)
+		}
+		usage, ok := b.usage[method]
+		if !ok {
+			usage = &RPCMethodUsage{Method: method}
+			b.usage[method] = usage
+		}
+		usage.Calls++
+		usage.Units += cost
+	}
+	b.periodUsed += total
+
+	var wait time.Duration
+	if b.UnitsPerSecond > 0 {
+		if b.refilledAt.IsZero() {
+			b.tokens = b.effectiveBurst()
+		} else {
+			b.tokens += now.Sub(b.refilledAt).Seconds() * b.UnitsPerSecond
+			if b.tokens > b.effectiveBurst() {
+				b.tokens = b.effectiveBurst()
+			}
+		}
+		b.refilledAt = now
+		b.tokens -= total
+		if b.tokens < 0 {
+			wait = time.Duration(-b.tokens / b.UnitsPerSecond * float64(time.Second))
+		}
+	}
+	b.mu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+	if b.sleep != nil {
+		return b.sleep(ctx, wait)
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(wait):
+		return nil
+	}
+}
+
+// rollPeriod starts a new period once the current one is over. Callers hold
+// b.mu.
+func (b *RPCBudget) rollPeriod(now time.Time) {
+	if b.periodStart.IsZero() {
+		b.periodStart = now
+		return
+	}
+	if now.Sub(b.periodStart) < b.effectivePeriod() {
+		return
+	}
+	b.logger().Info(b.Name+": rpc budget period ended",
+		"unitsUsed", b.periodUsed,
+		"periodLimit", b.PeriodLimit,
+	)
+	b.periodStart = now
+	b.periodUsed = 0
+}
+
+// Slowdown is the factor by which callers should stretch poll intervals and
+// shrink batches. It is 1 while the period's units are spent no faster than
+// the period elapses, and grows with the overspend up to MaxSlowdown, which is
+// also returned once the period's limit is reached. A nil budget returns 1.
+func (b *RPCBudget) Slowdown() float64 {
+	if b == nil || b.PeriodLimit <= 0 {
+		return 1
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.clock()
+	b.rollPeriod(now)
+
+	maxSlowdown := b.effectiveMaxSlowdown()
+	if b.periodUsed >= b.PeriodLimit {
+		return maxSlowdown
+	}
+	// Judge the pace on at least a tenth of the period, so the first minutes
+	// of a period do not look like a huge overspend.
+	elapsed := float64(now.Sub(b.periodStart)) / float64(b.effectivePeriod())
+	if elapsed < 0.1 {
+		elapsed = 0.1
+	}
+	pace := (b.periodUsed / b.PeriodLimit) / elapsed
+	if pace <= 1 {
+		return 1
+	}
+	if pace > maxSlowdown {
+		return maxSlowdown
+	}
+	return pace
+}
+
+// Report returns per-method usage, most expensive first.
+func (b *RPCBudget) Report() []RPCMethodUsage {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	report := make([]RPCMethodUsage, 0, len(b.usage))
+	for _, usage := range b.usage {
+		report = append(report, *usage)
+	}
+	sort.Slice(report, func(i, j int) bool {
+		if report[i].Units != report[j].Units {
+			return report[i].Units > report[j].Units
+		}
+		return report[i].Method < report[j].Method
+	})
+	return report
+}
+
+// LogReport logs the usage report and period spend every interval until ctx
+// is done. A non-positive interval disables the report.
+func (b *RPCBudget) LogReport(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		b.mu.Lock()
+		periodUsed := b.periodUsed
+		b.mu.Unlock()
+		for _, usage := range b.Report() {
+			b.logger().Info(b.Name+": rpc usage",
+				"method", usage.Method,
+				"calls", usage.Calls,
+				"units", usage.Units,
+			)
+		}
+		b.logger().Info(b.Name+": rpc budget",
+			"periodUnitsUsed", periodUsed,
+			"periodLimit", b.PeriodLimit,
+			"slowdown", b.Slowdown(),
+		)
+	}
+}
+
+// scaleBatch shrinks a batch size by the current slowdown, to at least 1.
+func (b *RPCBudget) scaleBatch(size int) int {
+	scaled := int(float64(size) / b.Slowdown())
+	if scaled < 1 {
+		return 1
+	}
+	return scaled
+}
+
+// ParseMethodCosts parses "method:units" pairs separated by commas, e.g.
+// "getTransaction:10,getSlot:1", over the given defaults.
+func ParseMethodCosts(spec string, defaults map[string]float64) (map[string]float64, error) {
+	costs := make(map[string]float64, len(defaults))
+	for method, cost := range defaults {
+		costs[method] = cost
+	}
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		method, rawCost, ok := strings.Cut(entry, ":")
+		cost, err := strconv.ParseFloat(strings.TrimSpace(rawCost), 64)
+		if !ok || strings.TrimSpace(method) == "" || err != nil || cost < 0 {
+			return nil, fmt.Errorf("invalid method cost %q (expected method:units)", entry)
+		}
+		costs[strings.TrimSpace(method)] = cost
+	}
+	return costs, nil
+}
@@ -0,0 +1,74 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRPCClient_BudgetMetersCallsAndShrinksBatches(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	budget := &RPCBudget{
+		Name:        "test-rpc",
+		MethodCosts: map[string]float64{"getSlot": 1, "getTransaction": 10},
+		PeriodLimit: 100,
+		Period:      10 * time.Hour,
+		now:         func() time.Time { return now },
+	}
+
+	var batchSizes []int
+	client := rpcClient{
+		URL:          "https://rpc.internal",
+		MaxBatchSize: 8,
+		Budget:       budget,
+		HTTPClient: &http.Client{
+			Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+				var reqs []rpcRequest
+				if err := json.NewDecoder(r.Body).Decode(&reqs); err != nil {
+					return nil, err
+				}
+				batchSizes = append(batchSizes, len(reqs))
+				items := make([]string, len(reqs))
+				for i, req := range reqs {
+					items[i] = fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"result":null}`, req.ID)
+				}
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       io.NopCloser(strings.NewReader("[" + strings.Join(items, ",") + "]")),
+					Header:     make(http.Header),
+				}, nil
+			}),
+		},
+	}
+
+	send := func() {
+		calls := make([]*rpcBatchCall, 8)
+		for i := range calls {
+			calls[i] = &rpcBatchCall{Method: "getTransaction", Params: []interface{}{}}
+		}
+		if err := client.batch(context.Background(), calls); err != nil {
+			t.Fatalf("unexpected batch error: %v", err)
+		}
+	}
+
+	// 80 of 100 credits spent in the first tenth of the period is 8x ahead of
+	// plan, so the next batch is sent in chunks of one.
+	send()
+	send()
+	if len(batchSizes) != 9 || batchSizes[0] != 8 || batchSizes[1] != 1 {
+		t.Fatalf("expected a full batch, then single-item chunks, got %v", batchSizes)
+	}
+
+	report := budget.Report()
+	if len(report) != 1 || report[0].Method != "getTransaction" || report[0].Calls != 16 || report[0].Units != 160 {
+		t.Fatalf("unexpected usage report: %+v", report)
+	}
+	if slowdown := budget.Slowdown(); slowdown != 8 {
+		t.Fatalf("expected the maximum slowdown past the limit, got %v", slowdown)
+	}
+}
@@ -0,0 +1,62 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestRPCClient_BatchMatchesResponsesByID(t *testing.T) {
+	var requestCount int
+	client := rpcClient{
+		URL: "https://rpc.internal",
+		HTTPClient: &http.Client{
+			Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+				requestCount++
+				var reqs []rpcRequest
+				if err := json.NewDecoder(r.Body).Decode(&reqs); err != nil {
+					return nil, err
+				}
+				if len(reqs) != 3 {
+					t.Fatalf("expected 3 batched requests, got %d", len(reqs))
+				}
+				// Answer out of order, fail the second item and omit none.
+				body := `[
+					{"jsonrpc":"2.0","id":3,"result":3},
+					{"jsonrpc":"2.0","id":1,"result":1},
+					{"jsonrpc":"2.0","id":2,"error":{"code":-32000,"message":"slot skipped"}}
+				]`
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       io.NopCloser(strings.NewReader(body)),
+					Header:     make(http.Header),
+				}, nil
+			}),
+		},
+	}
+
+	outs := make([]int64, 3)
+	calls := make([]*rpcBatchCall, 3)
+	for i := range calls {
+		calls[i] = &rpcBatchCall{Method: "getSlot", Params: []interface{}{}, Out: &outs[i]}
+	}
+
+	if err := client.batch(context.Background(), calls); err != nil {
+		t.Fatalf("unexpected batch error: %v", err)
+	}
+	if requestCount != 1 {
+		t.Fatalf("expected a single round trip, got %d", requestCount)
+	}
+	if calls[0].Err != nil || outs[0] != 1 {
+		t.Fatalf("unexpected first result: %v %d", calls[0].Err, outs[0])
+	}
+	if calls[1].Err == nil || !strings.Contains(calls[1].Err.Error(), "slot skipped") {
+		t.Fatalf("expected per-item error for second call, got %v", calls[1].Err)
+	}
+	if calls[2].Err != nil || outs[2] != 3 {
+		t.Fatalf("unexpected third result: %v %d", calls[2].Err, outs[2])
+	}
+}
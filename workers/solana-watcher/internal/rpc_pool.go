@@ -0,0 +1,307 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RPCPool spreads JSON-RPC traffic over an ordered list of providers. Each
+// provider carries a latency and error-rate estimate; requests go to a
+// weighted pick among healthy providers and fail over to the next one when a
+// provider is down, rate limited or rejects the credentials. A failing
+// provider is put in an exponentially growing cooldown. Requests made with a
+// context from Pin stay on one provider, so a poll sees a single chain head.
+type RPCPool struct {
+	Name        string // log prefix, e.g. "solana-rpc"
+	Logger      *slog.Logger
+	Cooldown    time.Duration // first cooldown after a failure (default: 15s)
+	MaxCooldown time.Duration // cap for repeated failures (default: 5m)
+
+	mu        sync.Mutex
+	providers []*rpcProvider
+	random    func() float64
+	now       func() time.Time
+}
+
+type rpcProvider struct {
+	url                 string
+	priority            float64
+	latency             time.Duration // moving average of successful requests
+	errorRate           float64       // moving average, 0 (healthy) to 1 (always failing)
+	consecutiveFailures int
+	cooldownUntil       time.Time
+}
+
+const rpcPoolSmoothing = 0.2
+
+type rpcPinKey struct {
+	pool *RPCPool
+}
+
+// rpcPin holds the provider the requests of one poll go to.
+type rpcPin struct {
+	mu       sync.Mutex
+	provider *rpcProvider
+}
+
+// Pin returns a context whose requests all go to the first provider that
+// answers one of them. The pin only moves when that provider fails over.
+func (p *RPCPool) Pin(ctx context.Context) context.Context {
+	if p == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, rpcPinKey{pool: p}, &rpcPin{})
+}
+
+// NewRPCPool builds a pool from provider URLs in order of preference.
+func NewRPCPool(name string, urls []string, logger *slog.Logger) *RPCPool {
+	pool := &RPCPool{Name: name, Logger: logger}
+	for i, raw := range urls {
+		trimmed := strings.TrimSpace(raw)
+		if trimmed == "" {
+			continue
+		}
+		pool.providers = append(pool.providers, &rpcProvider{
+			url:      trimmed,
+			priority: 1 / float64(i+1),
+		})
+	}
+	return pool
+}
+
+// Len returns the number of configured providers.
+func (p *RPCPool) Len() int {
+	return len(p.providers)
+}
+
+func (p *RPCPool) logger() *slog.Logger {
+	if p.Logger != nil {
+		return p.Logger
+	}
+	return slog.Default()
+}
+
+func (p *RPCPool) clock() time.Time {
+	if p.now != nil {
+		return p.now()
+	}
+	return time.Now()
+}
+
+func (p *RPCPool) effectiveCooldown() time.Duration {
+	if p.Cooldown > 0 {
+		return p.Cooldown
+	}
+	return 15 * time.Second
+}
+
+func (p *RPCPool) effectiveMaxCooldown() time.Duration {
+	if p.MaxCooldown > 0 {
+		return p.MaxCooldown
+	}
+	return 5 * time.Minute
+}
+
+func (p *RPCPool) post(ctx context.Context, client *http.Client, reqBody []byte) ([]byte, error) {
+	pin, _ := ctx.Value(rpcPinKey{pool: p}).(*rpcPin)
+	order := p.attemptOrder(pin)
+	if len(order) == 0 {
+		return nil, fmt.Errorf("%s: no rpc providers configured", p.Name)
+	}
+
+	var lastErr error
+	for i, provider := range order {
+		started := p.clock()
+		raw, err := postRPC(ctx, client, provider.url, reqBody)
+		if err == nil {
+			// Rate limits and unhealthy nodes often answer with status 200.
+			err = providerErrorResponse(raw)
+		}
+		if err == nil {
+			p.recordSuccess(provider, p.clock().Sub(started))
+			if pin != nil {
+				pin.mu.Lock()
+				pin.provider = provider
+				pin.mu.Unlock()
+			}
+			return raw, nil
+		}
+		if ctx.Err() != nil || !isProviderFailure(err) {
+			return nil, err
+		}
+
+		lastErr = err
+		cooldown := p.recordFailure(provider)
+		if i+1 < len(order) {
+			p.logger().Warn(p.Name+": rpc provider failed, failing over",
+				"provider", redactRPCURL(provider.url),
+				"nextProvider", redactRPCURL(order[i+1].url),
+				"cooldown", cooldown.String(),
+				"error", err,
+			)
+		}
+	}
+
+	p.logger().Error(p.Name+": all rpc providers failed",
+		"providerCount", len(order),
+		"error", lastErr,
+	)
+	return nil, lastErr
+}
+
+// attemptOrder returns the providers to try for one request: the pinned
+// provider if it is healthy, otherwise a weighted random pick among healthy
+// providers first, then the remaining healthy providers by score, then
+// providers still in cooldown ordered by expiry.
+func (p *RPCPool) attemptOrder(pin *rpcPin) []*rpcProvider {
+	var pinned *rpcProvider
+	if pin != nil {
+		pin.mu.Lock()
+		pinned = pin.provider
+		pin.mu.Unlock()
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.clock()
+	healthy := make([]*rpcProvider, 0, len(p.providers))
+	cooling := make([]*rpcProvider, 0)
+	for _, provider := range p.providers {
+		if now.Before(provider.cooldownUntil) {
+			cooling = append(cooling, provider)
+			continue
+		}
+		healthy = append(healthy, provider)
+	}
+
+	sort.SliceStable(healthy, func(i, j int) bool { return healthy[i].score() > healthy[j].score() })
+	sort.SliceStable(cooling, func(i, j int) bool { return cooling[i].cooldownUntil.Before(cooling[j].cooldownUntil) })
+
+	for i, provider := range healthy {
+		if provider == pinned {
+			healthy[0], healthy[i] = healthy[i], healthy[0]
+			return append(healthy, cooling...)
+		}
+	}
+
+	if len(healthy) > 1 {
+		total := 0.0
+		for _, provider := range healthy {
+			total += provider.score()
+		}
+		random := p.random
+		if random == nil {
+			random = rand.Float64
+		}
+		target := random() * total
+		for i, provider := range healthy {
+			target -= provider.score()
+			if target <= 0 {
+				healthy[0], healthy[i] = healthy[i], healthy[0]
+				break
+			}
+		}
+	}
+
+	return append(healthy, cooling...)
+}
+
+func (p *RPCPool) recordSuccess(provider *rpcProvider, latency time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if provider.latency == 0 {
+		provider.latency = latency
+	} else {
+		provider.latency += time.Duration(rpcPoolSmoothing * float64(latency-provider.latency))
+	}
+	provider.errorRate -= rpcPoolSmoothing * provider.errorRate
+	provider.consecutiveFailures = 0
+	provider.cooldownUntil = time.Time{}
+}
+
+func (p *RPCPool) recordFailure(provider *rpcProvider) time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	provider.errorRate += rpcPoolSmoothing * (1 - provider.errorRate)
+	provider.consecutiveFailures++
+
+	cooldown := p.effectiveCooldown()
+	for i := 1; i < provider.consecutiveFailures && cooldown < p.effectiveMaxCooldown(); i++ {
+		cooldown *= 2
+	}
+	if cooldown > p.effectiveMaxCooldown() {
+		cooldown = p.effectiveMaxCooldown()
+	}
+	provider.cooldownUntil = p.clock().Add(cooldown)
+	return cooldown
+}
+
+// score favours providers listed first, with few recent errors and low latency.
+func (r *rpcProvider) score() float64 {
+	latencyPenalty := 1 + float64(r.latency)/float64(250*time.Millisecond)
+	return r.priority * (1 - r.errorRate*0.9) / latencyPenalty
+}
+
+// isProviderFailure reports whether an error says the provider, not the
+// request, is at fault and another provider should be tried.
+func isProviderFailure(err error) bool {
+	var statusErr *rpcStatusError
+	if errors.As(err, &statusErr) {
+		switch {
+		case statusErr.StatusCode == http.StatusUnauthorized,
+			statusErr.StatusCode == http.StatusForbidden,
+			statusErr.StatusCode == http.StatusRequestTimeout,
+			statusErr.StatusCode == http.StatusTooManyRequests,
+			statusErr.StatusCode >= 500:
+			return true
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// providerErrorCodes are JSON-RPC errors that blame the provider: an unhealthy
+// or lagging node (-32005) and provider rate limits (-32029, -32429).
+var providerErrorCodes = map[int]bool{-32005: true, -32029: true, -32429: true}
+
+// providerErrorResponse returns the error of a response, or of a batch
+// response item, that blames the provider, and nil otherwise.
+func providerErrorResponse(raw []byte) error {
+	var responses []rpcResponse
+	if err := json.Unmarshal(raw, &responses); err != nil {
+		var single rpcResponse
+		if json.Unmarshal(raw, &single) != nil {
+			return nil
+		}
+		responses = []rpcResponse{single}
+	}
+	for _, resp := range responses {
+		if resp.Error != nil && providerErrorCodes[resp.Error.Code] {
+			return fmt.Errorf("rpc error %d: %s", resp.Error.Code, resp.Error.Message)
+		}
+	}
+	return nil
+}
+
+// redactRPCURL strips paths and query strings, which often carry API keys.
+func redactRPCURL(raw string) string {
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Host == "" {
+		return "invalid-url"
+	}
+	return parsed.Scheme + "://" + parsed.Host
+}
@@ -0,0 +1,130 @@
+package internal
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestPool(urls []string) *RPCPool {
+	pool := NewRPCPool("test-rpc", urls, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	pool.random = func() float64 { return 0 }
+	return pool
+}
+
+func statusTransport(statusByHost map[string]int, hits map[string]int) http.RoundTripper {
+	return roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		hits[r.URL.Host]++
+		status := statusByHost[r.URL.Host]
+		if status == 0 {
+			status = http.StatusOK
+		}
+		return &http.Response{
+			StatusCode: status,
+			Body:       io.NopCloser(strings.NewReader(`{"jsonrpc":"2.0","id":1,"result":"0x10"}`)),
+			Header:     make(http.Header),
+		}, nil
+	})
+}
+
+func TestRPCPool_FailsOverAndCoolsDownFailingProvider(t *testing.T) {
+	hits := map[string]int{}
+	client := &http.Client{Transport: statusTransport(map[string]int{"primary.rpc": http.StatusServiceUnavailable}, hits)}
+	pool := newTestPool([]string{"https://primary.rpc/key", "https://backup.rpc/key"})
+	rpc := rpcClient{Pool: pool, HTTPClient: client}
+
+	var out string
+	if err := rpc.call(context.Background(), "getSlot", []interface{}{}, &out); err != nil {
+		t.Fatalf("expected failover to succeed, got %v", err)
+	}
+	if out != "0x10" || hits["primary.rpc"] != 1 || hits["backup.rpc"] != 1 {
+		t.Fatalf("unexpected result %s with hits %v", out, hits)
+	}
+
+	if err := rpc.call(context.Background(), "getSlot", []interface{}{}, &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if hits["primary.rpc"] != 1 {
+		t.Fatalf("expected primary to be skipped during cooldown, got %d hits", hits["primary.rpc"])
+	}
+}
+
+func TestRPCPool_RetriesProviderAfterCooldown(t *testing.T) {
+	hits := map[string]int{}
+	statuses := map[string]int{"primary.rpc": http.StatusTooManyRequests}
+	client := &http.Client{Transport: statusTransport(statuses, hits)}
+	pool := newTestPool([]string{"https://primary.rpc", "https://backup.rpc"})
+	now := time.Date(2026, 2, 13, 12, 0, 0, 0, time.UTC)
+	pool.now = func() time.Time { return now }
+	rpc := rpcClient{Pool: pool, HTTPClient: client}
+
+	if err := rpc.call(context.Background(), "getSlot", []interface{}{}, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	delete(statuses, "primary.rpc")
+	now = now.Add(time.Minute)
+	if err := rpc.call(context.Background(), "getSlot", []interface{}{}, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if hits["primary.rpc"] != 2 {
+		t.Fatalf("expected primary to be retried after cooldown, got %d hits", hits["primary.rpc"])
+	}
+}
+
+func TestRPCPool_PinnedContextStaysOnOneProvider(t *testing.T) {
+	hits := map[string]int{}
+	client := &http.Client{Transport: statusTransport(map[string]int{}, hits)}
+	pool := newTestPool([]string{"https://primary.rpc", "https://backup.rpc"})
+	picks := []float64{0.99, 0}
+	pool.random = func() float64 {
+		pick := picks[0]
+		picks = picks[1:]
+		return pick
+	}
+	rpc := rpcClient{Pool: pool, HTTPClient: client}
+
+	ctx := pool.Pin(context.Background())
+	for i := 0; i < 3; i++ {
+		if err := rpc.call(ctx, "getSlot", []interface{}{}, nil); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if hits["backup.rpc"] != 3 || hits["primary.rpc"] != 0 {
+		t.Fatalf("expected every pinned request on the first pick, got %v", hits)
+	}
+
+	// A new poll picks again.
+	if err := rpc.call(pool.Pin(context.Background()), "getSlot", []interface{}{}, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if hits["primary.rpc"] != 1 {
+		t.Fatalf("expected a fresh pick for a new pin, got %v", hits)
+	}
+}
+
+func TestRPCPool_FailsOverOnUnhealthyNodeWithStatusOK(t *testing.T) {
+	hits := map[string]int{}
+	client := &http.Client{Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		hits[r.URL.Host]++
+		body := `{"jsonrpc":"2.0","id":1,"result":42}`
+		if r.URL.Host == "primary.rpc" {
+			body = `{"jsonrpc":"2.0","id":1,"error":{"code":-32005,"message":"Node is behind by 120 slots"}}`
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body)), Header: make(http.Header)}, nil
+	})}
+	pool := newTestPool([]string{"https://primary.rpc", "https://backup.rpc"})
+	rpc := rpcClient{Pool: pool, HTTPClient: client}
+
+	var out int64
+	if err := rpc.call(context.Background(), "getSlot", []interface{}{}, &out); err != nil {
+		t.Fatalf("expected failover to succeed, got %v", err)
+	}
+	if out != 42 || hits["backup.rpc"] != 1 {
+		t.Fatalf("unexpected result %d with hits %v", out, hits)
+	}
+}
@@ -12,6 +12,18 @@ type CandidateSource interface {
 	Poll(ctx context.Context, cursor string) ([]FundingCandidate, string, error)
 }
 
+// WakingSource is implemented by push-mode sources. The runner polls as soon
+// as Wake fires instead of waiting for the next tick.
+type WakingSource interface {
+	Wake() <-chan struct{}
+}
+
+// TimestampSource is implemented by sources that can find the cursor at which
+// scanning reaches a point in time, which lets the runner start from a date.
+type TimestampSource interface {
+	CursorAt(ctx context.Context, at time.Time) (string, error)
+}
+
 type CheckpointStore interface {
 	GetCursor(ctx context.Context) (string, error)
 	SaveCursor(ctx context.Context, cursor string) error
@@ -30,6 +42,17 @@ type Runner struct {
 	CheckpointStore CheckpointStore
 	DedupeStore     DedupeStore
 	Logger          *slog.Logger
+
+	// Budget, when set, stretches PollInterval by its Slowdown while RPC
+	// spending runs ahead of plan.
+	Budget *RPCBudget
+
+	// StartAt, when set, replaces the checkpoint on start with the source's
+	// cursor for that time, to backfill from a date. StartMarker records the
+	// StartAt last applied, so the checkpoint is only replaced once per value
+	// and restarts continue from where the watcher got to.
+	StartAt     time.Time
+	StartMarker CheckpointStore
 }
 
 func (r Runner) Run(ctx context.Context) error {
@@ -43,6 +66,12 @@ func (r Runner) Run(ctx context.Context) error {
 		r.PollInterval = 5 * time.Second
 	}
 
+	if !r.StartAt.IsZero() {
+		if err := r.rewindToStart(ctx); err != nil {
+			return err
+		}
+	}
+
 	cursor, err := r.CheckpointStore.GetCursor(ctx)
 	if err != nil {
 		return fmt.Errorf("load cursor: %w", err)
@@ -61,30 +90,93 @@ func (r Runner) Run(ctx context.Context) error {
 		)
 	}
 
-	ticker := time.NewTicker(r.PollInterval)
+	interval := r.pollInterval()
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
+	var wake <-chan struct{}
+	if waking, ok := r.Source.(WakingSource); ok {
+		wake = waking.Wake()
+	}
+
 	for {
 		select {
 		case <-ctx.Done():
 			r.Logger.Info("watcher shutting down", "watcher", r.Name)
 			return nil
 		case <-ticker.C:
-			if err := r.runOnce(ctx, cursor); err != nil {
-				r.Logger.Error("poll failed",
-					"watcher", r.Name,
-					"cursor", cursor,
-					"error", err,
-				)
-				continue
-			}
+		case <-wake:
+		}
 
-			nextCursor, err := r.CheckpointStore.GetCursor(ctx)
-			if err == nil {
-				cursor = nextCursor
-			}
+		if err := r.runOnce(ctx, cursor); err != nil {
+			r.Logger.Error("poll failed",
+				"watcher", r.Name,
+				"cursor", cursor,
+				"error", err,
+			)
+			continue
+		}
+
+		nextCursor, err := r.CheckpointStore.GetCursor(ctx)
+		if err == nil {
+			cursor = nextCursor
 		}
+
+		if next := r.pollInterval(); next != interval {
+			r.Logger.Info("poll interval changed by rpc budget",
+				"watcher", r.Name,
+				"pollInterval", next.String(),
+			)
+			interval = next
+			ticker.Reset(interval)
+		}
+	}
+}
+
+// pollInterval is PollInterval stretched by the RPC budget's slowdown.
+func (r Runner) pollInterval() time.Duration {
+	return time.Duration(float64(r.PollInterval) * r.Budget.Slowdown())
+}
+
+// rewindToStart saves the source's cursor for StartAt as the checkpoint,
+// unless StartMarker shows this StartAt was already applied.
+func (r Runner) rewindToStart(ctx context.Context) error {
+	source, ok := r.Source.(TimestampSource)
+	if !ok {
+		return fmt.Errorf("source cannot start from a timestamp")
+	}
+	if r.StartMarker == nil {
+		return fmt.Errorf("start time needs a start marker store")
+	}
+	startAt := r.StartAt.UTC().Format(time.RFC3339)
+	applied, err := r.StartMarker.GetCursor(ctx)
+	if err != nil {
+		return fmt.Errorf("load start marker: %w", err)
+	}
+	if applied == startAt {
+		r.Logger.Info("start time already applied, keeping checkpoint",
+			"watcher", r.Name,
+			"startAt", startAt,
+		)
+		return nil
+	}
+
+	cursor, err := source.CursorAt(ctx, r.StartAt)
+	if err != nil {
+		return fmt.Errorf("find cursor for %s: %w", r.StartAt.Format(time.RFC3339), err)
+	}
+	if err := r.CheckpointStore.SaveCursor(ctx, cursor); err != nil {
+		return fmt.Errorf("save start cursor: %w", err)
 	}
+	if err := r.StartMarker.SaveCursor(ctx, startAt); err != nil {
+		return fmt.Errorf("save start marker: %w", err)
+	}
+	r.Logger.Warn("checkpoint rewound to start time",
+		"watcher", r.Name,
+		"startAt", r.StartAt.Format(time.RFC3339),
+		"cursor", cursor,
+	)
+	return nil
 }
 
 func (r Runner) runOnce(ctx context.Context, currentCursor string) error {
@@ -102,6 +194,7 @@ func (r Runner) runOnce(ctx context.Context, currentCursor string) error {
 
 	confirmedCount := 0
 	skippedCount := 0
+	internalCount := 0
 
 	for _, candidate := range candidates {
 		eventKey := buildEventKey(candidate)
@@ -139,7 +232,8 @@ func (r Runner) runOnce(ctx context.Context, currentCursor string) error {
 			"txHash", candidate.TxHash,
 			"depositAddress", candidate.DepositAddress,
 			"resolvedDepositAddress", resolvedDepositAddress,
-			"amountUSD", candidate.AmountUSD,
+			"amount", candidate.Amount,
+			"amountBaseUnits", candidate.AmountBaseUnits,
 			"result", string(result),
 		)
 
@@ -167,6 +261,19 @@ func (r Runner) runOnce(ctx context.Context, currentCursor string) error {
 			)
 		}
 
+		if result == ProcessInternal {
+			internalCount++
+			r.Logger.Info("route resolution outcome",
+				"watcher", r.Name,
+				"chain", candidate.Chain,
+				"txHash", candidate.TxHash,
+				"depositAddress", candidate.DepositAddress,
+				"payerAddress", candidate.Metadata["payerAddress"],
+				"amount", candidate.Amount,
+				"outcome", "internal_movement",
+			)
+		}
+
 		if result == ProcessConfirmed {
 			confirmedCount++
 			if err := r.DedupeStore.Mark(ctx, eventKey); err != nil {
@@ -181,7 +288,8 @@ func (r Runner) runOnce(ctx context.Context, currentCursor string) error {
 			"total", len(candidates),
 			"confirmed", confirmedCount,
 			"skipped", skippedCount,
-			"unresolved", len(candidates)-confirmedCount-skippedCount,
+			"internal", internalCount,
+			"unresolved", len(candidates)-confirmedCount-skippedCount-internalCount,
 		)
 	}
 
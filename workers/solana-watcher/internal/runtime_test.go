@@ -124,3 +124,51 @@ func TestRunner_PropagatesSourceError(t *testing.T) {
 		t.Fatalf("runner should continue until context cancel, got %v", err)
 	}
 }
+
+// timestampSourceStub finds the same start cursor for every time.
+type timestampSourceStub struct {
+	sourceStub
+	cursor string
+}
+
+func (s timestampSourceStub) CursorAt(_ context.Context, _ time.Time) (string, error) {
+	return s.cursor, nil
+}
+
+func TestRunner_RewindsToStartOncePerStartTime(t *testing.T) {
+	checkpoint := &checkpointStub{cursor: "100"}
+	marker := &checkpointStub{}
+	runner := Runner{
+		Name:            "solana-watcher-test",
+		Source:          timestampSourceStub{cursor: "350000"},
+		CheckpointStore: checkpoint,
+		Logger:          slog.New(slog.NewTextHandler(io.Discard, nil)),
+		StartAt:         time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC),
+		StartMarker:     marker,
+	}
+
+	if err := runner.rewindToStart(context.Background()); err != nil {
+		t.Fatalf("unexpected rewind error: %v", err)
+	}
+	if checkpoint.cursor != "350000" || marker.cursor != "2026-09-01T00:00:00Z" {
+		t.Fatalf("expected the start cursor and marker saved, got %s and %s", checkpoint.cursor, marker.cursor)
+	}
+
+	// A restart with the same start time keeps the progress made since.
+	checkpoint.cursor = "3500009"
+	if err := runner.rewindToStart(context.Background()); err != nil {
+		t.Fatalf("unexpected rewind error: %v", err)
+	}
+	if checkpoint.cursor != "3500009" {
+		t.Fatalf("expected the checkpoint kept on restart, got %s", checkpoint.cursor)
+	}
+
+	// A new start time rewinds again.
+	runner.StartAt = time.Date(2026, 8, 1, 0, 0, 0, 0, time.UTC)
+	if err := runner.rewindToStart(context.Background()); err != nil {
+		t.Fatalf("unexpected rewind error: %v", err)
+	}
+	if checkpoint.cursor != "350000" || marker.cursor != "2026-08-01T00:00:00Z" {
+		t.Fatalf("expected a rewind for the new start time, got %s and %s", checkpoint.cursor, marker.cursor)
+	}
+}
@@ -0,0 +1,80 @@
+package internal
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// solanaCursor is the checkpoint persisted by SolanaRpcSource: the last
+// scanned slot and, for each watched address, the newest signature already
+// scanned, so a poll only fetches the history that came after it. Resume
+// holds where an address's walk back stopped when MaxSignaturePages was
+// reached, so the next poll continues from there instead of starting over.
+//
+// A cursor without signatures is encoded as a bare slot number, which keeps
+// checkpoints written by older watcher versions (and CursorAt) readable.
+type solanaCursor struct {
+	Slot       int64                      `json:"slot"`
+	Signatures map[string]string          `json:"signatures,omitempty"` // address -> newest scanned signature
+	Resume     map[string]signatureResume `json:"resume,omitempty"`     // address -> unscanned history
+}
+
+// signatureResume is the unscanned part of an address's history: everything
+// older than Before down to the bound the capped walk was heading for.
+type signatureResume struct {
+	Before    string `json:"before"`
+	Until     string `json:"until,omitempty"`
+	AfterSlot int64  `json:"afterSlot,omitempty"`
+	Since     int64  `json:"since,omitempty"` // unix seconds
+}
+
+func newSignatureResume(before string, bound signatureBound) signatureResume {
+	resume := signatureResume{Before: before, Until: bound.Until, AfterSlot: bound.AfterSlot}
+	if !bound.Since.IsZero() {
+		resume.Since = bound.Since.Unix()
+	}
+	return resume
+}
+
+func (r signatureResume) bound() signatureBound {
+	bound := signatureBound{Before: r.Before, Until: r.Until, AfterSlot: r.AfterSlot}
+	if r.Since > 0 {
+		bound.Since = time.Unix(r.Since, 0)
+	}
+	return bound
+}
+
+func parseSolanaCursor(raw string) solanaCursor {
+	trimmed := strings.TrimSpace(raw)
+	if slot, err := strconv.ParseInt(trimmed, 10, 64); err == nil {
+		return solanaCursor{Slot: slot}
+	}
+
+	var cursor solanaCursor
+	if err := json.Unmarshal([]byte(trimmed), &cursor); err != nil {
+		return solanaCursor{}
+	}
+	return cursor
+}
+
+func (c solanaCursor) String() string {
+	if len(c.Signatures) == 0 && len(c.Resume) == 0 {
+		return strconv.FormatInt(c.Slot, 10)
+	}
+
+	encoded, err := json.Marshal(c)
+	if err != nil {
+		return strconv.FormatInt(c.Slot, 10)
+	}
+	return string(encoded)
+}
+
+// carry keeps address's signature from previous for an address that was not
+// scanned because nothing changed.
+func (c *solanaCursor) carry(address string, previous solanaCursor) {
+	if signature := previous.Signatures[address]; signature != "" {
+		c.Signatures[address] = signature
+	}
+}
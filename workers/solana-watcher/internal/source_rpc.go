@@ -7,23 +7,61 @@ import (
 	"encoding/binary"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
 	"math/big"
 	"net/http"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
 type SolanaRpcSource struct {
 	RPCURL       string
+	RPCPool      *RPCPool   // when set, requests are spread over its providers instead of RPCURL
+	Budget       *RPCBudget // when set, requests are paced and metered in compute units
 	HTTPClient   *http.Client
 	RouteStore   RouteStore
-	TokenMints   map[string]string // token -> mint pubkey (base58)
+	Tokens       *TokenRegistry
 	TreasuryATAs map[string]string // token -> treasury ATA (base58)
 	ProgramID    string
 	Chain        string
-	Limit        int
+	Limit        int // signatures per getSignaturesForAddress page (default: 100)
+	MaxBatchSize int // requests per JSON-RPC batch (default: 50)
+
+	// MaxSignaturePages caps how many pages of one address's history a poll
+	// walks back (default: 10). When the cap is reached, the cursor is held
+	// before the unscanned history and records where the walk stopped, so the
+	// next poll continues from there.
+	MaxSignaturePages int
+
+	FetchWorkers int           // transaction batches in flight per poll (default: 4)
+	FetchTimeout time.Duration // deadline per transaction batch (default: 20s)
+
+	live *wsActivity // set by SolanaWebsocketSource; nil when polling only
+}
+
+func (s SolanaRpcSource) effectiveMaxSignaturePages() int {
+	if s.MaxSignaturePages > 0 {
+		return s.MaxSignaturePages
+	}
+	return 10
+}
+
+func (s SolanaRpcSource) effectiveFetchWorkers() int {
+	if s.FetchWorkers > 0 {
+		return s.FetchWorkers
+	}
+	return 4
+}
+
+func (s SolanaRpcSource) effectiveFetchTimeout() time.Duration {
+	if s.FetchTimeout > 0 {
+		return s.FetchTimeout
+	}
+	return 20 * time.Second
 }
 
 type rpcRequest struct {
@@ -34,6 +72,7 @@ type rpcRequest struct {
 }
 
 type rpcResponse struct {
+	ID     int             `json:"id"`
 	Result json.RawMessage `json:"result"`
 	Error  *struct {
 		Code    int    `json:"code"`
@@ -80,34 +119,38 @@ type transactionResult struct {
 var paymentAcceptedEventDiscriminator = []byte{30, 234, 73, 123, 52, 141, 189, 63}
 
 func (s SolanaRpcSource) Poll(ctx context.Context, cursor string) ([]FundingCandidate, string, error) {
-	if s.RPCURL == "" {
+	if s.RPCURL == "" && s.RPCPool == nil {
 		return nil, cursor, fmt.Errorf("solana rpc url is required")
 	}
+	// Every slot of one poll is read from the same provider.
+	ctx = s.RPCPool.Pin(ctx)
 
 	limit := s.Limit
 	if limit <= 0 {
 		limit = 100
 	}
 
+	startCoverage := s.live.beginPoll()
 	latestSlot, err := s.getSlot(ctx)
 	if err != nil {
 		return nil, cursor, fmt.Errorf("get slot: %w", err)
 	}
+	startCoverage(latestSlot)
 
-	current, err := strconv.ParseInt(cursor, 10, 64)
-	if err != nil {
-		current = 0
-	}
-	if latestSlot <= current {
-		return nil, strconv.FormatInt(current, 10), nil
+	previous := parseSolanaCursor(cursor)
+	if latestSlot <= previous.Slot {
+		return nil, previous.String(), nil
 	}
 
 	candidates := make([]FundingCandidate, 0)
 	seenTxKeys := map[string]bool{} // dedup across both modes
+	// Every address's history is scanned up to next.Slot; addresses no longer
+	// watched drop out of next.Signatures.
+	next := solanaCursor{Slot: latestSlot, Signatures: make(map[string]string), Resume: make(map[string]signatureResume)}
 
 	// Mode 1: Program payment events (wallet-pay via the Anchor program)
 	if strings.TrimSpace(s.ProgramID) != "" && len(s.TreasuryATAs) > 0 {
-		programCandidates, err := s.pollProgramPayments(ctx, current, limit)
+		programCandidates, err := s.pollProgramPayments(ctx, previous, &next, limit)
 		if err != nil {
 			return nil, cursor, err
 		}
@@ -119,7 +162,7 @@ func (s SolanaRpcSource) Poll(ctx context.Context, cursor string) ([]FundingCand
 	}
 
 	// Mode 2: Legacy route address scanning (QR/manual deposits — plain SPL transfers)
-	legacyCandidates, err := s.pollLegacyRouteAddresses(ctx, current, limit)
+	legacyCandidates, err := s.pollLegacyRouteAddresses(ctx, previous, &next, limit)
 	if err != nil {
 		return nil, cursor, err
 	}
@@ -131,19 +174,36 @@ func (s SolanaRpcSource) Poll(ctx context.Context, cursor string) ([]FundingCand
 		candidates = append(candidates, c)
 	}
 
-	return candidates, strconv.FormatInt(latestSlot, 10), nil
+	if next.Slot < latestSlot {
+		slog.Warn("solana-rpc: signature history not fully scanned, holding cursor",
+			"cursor", next.Slot,
+			"latestSlot", latestSlot,
+			"lagSlots", latestSlot-next.Slot,
+		)
+	}
+
+	return candidates, next.String(), nil
 }
 
-func (s SolanaRpcSource) pollProgramPayments(ctx context.Context, current int64, limit int) ([]FundingCandidate, error) {
+func (s SolanaRpcSource) pollProgramPayments(ctx context.Context, previous solanaCursor, next *solanaCursor, limit int) ([]FundingCandidate, error) {
 	candidates := make([]FundingCandidate, 0)
 	seenSignatures := map[string]bool{}
+	pending := make([]signatureItem, 0)
+	latestSlot := next.Slot
 
 	for token, treasuryATA := range s.TreasuryATAs {
 		if treasuryATA == "" {
 			continue
 		}
+		if _, resuming := previous.Resume[treasuryATA]; !resuming && s.live.unchanged(treasuryATA, previous.Slot) {
+			next.carry(treasuryATA, previous)
+			continue
+		}
 
-		sigs, err := s.getSignaturesForAddress(ctx, treasuryATA, limit)
+		sigs, err := s.scanAddress(ctx, treasuryATA, signatureBound{
+			Until:     previous.Signatures[treasuryATA],
+			AfterSlot: previous.Slot,
+		}, latestSlot, limit, previous, next)
 		if err != nil {
 			if isInvalidRouteAddressError(err) {
 				continue
@@ -152,40 +212,46 @@ func (s SolanaRpcSource) pollProgramPayments(ctx context.Context, current int64,
 		}
 
 		for _, sig := range sigs {
-			if sig.Err != nil || sig.Slot <= current {
+			if sig.Err != nil {
 				continue
 			}
 			if seenSignatures[sig.Signature] {
 				continue
 			}
 			seenSignatures[sig.Signature] = true
+			pending = append(pending, sig)
+		}
+	}
 
-			confirmedAt, err := s.resolveSignatureConfirmedAt(ctx, sig)
-			if err != nil {
-				continue
-			}
+	// Program log notifications may arrive before the treasury ATA's own.
+	for _, sig := range s.live.programSignatures(previous.Slot, latestSlot) {
+		if !seenSignatures[sig.Signature] {
+			seenSignatures[sig.Signature] = true
+			pending = append(pending, sig)
+		}
+	}
 
-			tx, err := s.getTransaction(ctx, sig.Signature)
-			if err != nil {
-				continue
-			}
+	fetched, err := s.fetchSignatureTransactions(ctx, pending)
+	if err != nil {
+		return nil, err
+	}
 
-			events := extractProgramPaymentCandidates(tx, programPaymentParseInput{
-				Chain:               s.Chain,
-				TxHash:              sig.Signature,
-				ProgramID:           s.ProgramID,
-				TokenMints:          s.TokenMints,
-				TreasuryATAs:        s.TreasuryATAs,
-				FallbackConfirmedAt: confirmedAt,
-			})
-			candidates = append(candidates, events...)
-		}
+	for _, item := range fetched {
+		events := extractProgramPaymentCandidates(item.Tx, programPaymentParseInput{
+			Chain:               s.Chain,
+			TxHash:              item.Signature.Signature,
+			ProgramID:           s.ProgramID,
+			Tokens:              s.Tokens,
+			TreasuryATAs:        s.TreasuryATAs,
+			FallbackConfirmedAt: item.ConfirmedAt,
+		})
+		candidates = append(candidates, events...)
 	}
 
 	return candidates, nil
 }
 
-func (s SolanaRpcSource) pollLegacyRouteAddresses(ctx context.Context, current int64, limit int) ([]FundingCandidate, error) {
+func (s SolanaRpcSource) pollLegacyRouteAddresses(ctx context.Context, previous solanaCursor, next *solanaCursor, limit int) ([]FundingCandidate, error) {
 	if s.RouteStore == nil {
 		return nil, nil
 	}
@@ -195,14 +261,25 @@ func (s SolanaRpcSource) pollLegacyRouteAddresses(ctx context.Context, current i
 		return nil, err
 	}
 
-	candidates := make([]FundingCandidate, 0)
+	pendingRoutes := make([]ActiveRoute, 0)
+	pending := make([]signatureItem, 0)
+	latestSlot := next.Slot
 	for _, route := range routes {
-		expectedMint := s.TokenMints[strings.ToUpper(route.Token)]
-		if expectedMint == "" {
+		if _, ok := s.Tokens.Token(route.Token); !ok {
+			continue
+		}
+		if _, resuming := previous.Resume[route.DepositAddress]; !resuming && s.live.unchanged(route.DepositAddress, previous.Slot) {
+			next.carry(route.DepositAddress, previous)
 			continue
 		}
 
-		sigs, err := s.getSignaturesForAddress(ctx, route.DepositAddress, limit)
+		// A route seen for the first time is scanned back to its creation
+		// when the core API reports it, instead of only since the last slot.
+		bound := signatureBound{Until: previous.Signatures[route.DepositAddress], AfterSlot: previous.Slot}
+		if bound.Until == "" {
+			bound.Since = route.createdAt()
+		}
+		sigs, err := s.scanAddress(ctx, route.DepositAddress, bound, latestSlot, limit, previous, next)
 		if err != nil {
 			if isInvalidRouteAddressError(err) {
 				continue
@@ -211,39 +288,282 @@ func (s SolanaRpcSource) pollLegacyRouteAddresses(ctx context.Context, current i
 		}
 
 		for _, sig := range sigs {
-			if sig.Err != nil || sig.Slot <= current {
+			if sig.Err != nil {
 				continue
 			}
+			pendingRoutes = append(pendingRoutes, route)
+			pending = append(pending, sig)
+		}
+	}
 
-			confirmedAt, err := s.resolveSignatureConfirmedAt(ctx, sig)
-			if err != nil {
-				continue
+	fetched, err := s.fetchSignatureTransactions(ctx, pending)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make([]FundingCandidate, 0)
+	for _, item := range fetched {
+		route := pendingRoutes[item.Index]
+		token, _ := s.Tokens.Token(route.Token)
+
+		credit, ok := extractTokenCredit(item.Tx, route.DepositAddress, token)
+		if !ok {
+			continue
+		}
+
+		candidate := FundingCandidate{
+			Chain:          s.Chain,
+			Token:          strings.ToUpper(route.Token),
+			TxHash:         item.Signature.Signature,
+			LogIndex:       0,
+			DepositAddress: route.DepositAddress,
+			ConfirmedAt:    item.ConfirmedAt,
+			Finalized:      true,
+		}
+		if owner, account, ok := extractTokenDebit(item.Tx, token); ok {
+			candidate.Metadata = map[string]any{
+				"payerAddress":      owner,
+				"payerTokenAccount": account,
 			}
+		}
+		candidate.setAmount(token, credit)
+		candidates = append(candidates, candidate)
+	}
 
-			tx, err := s.getTransaction(ctx, sig.Signature)
-			if err != nil {
-				continue
+	return candidates, nil
+}
+
+// signatureBound is where a scan of an address's history stops: at the newest
+// signature already scanned when there is one, otherwise at the first
+// signature older than Since, or at AfterSlot when Since is not known. Before,
+// when set, is where the scan starts instead of the newest signature.
+type signatureBound struct {
+	Before    string
+	Until     string
+	AfterSlot int64
+	Since     time.Time
+}
+
+// scanAddress returns the new signatures of address up to slot upTo and
+// records in next the address's newest scanned signature, where to resume its
+// history if the page cap was reached, and how far it is fully scanned.
+//
+// An address with unscanned history in previous continues that first; its
+// newer history is only scanned once the gap is closed.
+func (s SolanaRpcSource) scanAddress(ctx context.Context, address string, bound signatureBound, upTo int64, limit int, previous solanaCursor, next *solanaCursor) ([]signatureItem, error) {
+	out := make([]signatureItem, 0)
+	if resume, ok := previous.Resume[address]; ok {
+		gap, err := s.signaturesSince(ctx, address, resume.bound(), upTo, limit)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, gap.Sigs...)
+		if gap.Resume != "" {
+			next.carry(address, previous)
+			next.Resume[address] = newSignatureResume(gap.Resume, resume.bound())
+			next.Slot = min(next.Slot, gap.ScannedTo)
+			return out, nil
+		}
+	}
+
+	scan, err := s.signaturesSince(ctx, address, bound, upTo, limit)
+	if err != nil {
+		return nil, err
+	}
+	out = append(out, scan.Sigs...)
+	if scan.Newest != "" {
+		next.Signatures[address] = scan.Newest
+	}
+	if scan.Resume != "" {
+		next.Resume[address] = newSignatureResume(scan.Resume, bound)
+	}
+	next.Slot = min(next.Slot, scan.ScannedTo)
+	return out, nil
+}
+
+// signatureScan is the result of walking back an address's history.
+type signatureScan struct {
+	Sigs   []signatureItem
+	Newest string // newest signature scanned, or the bound's Until if none
+
+	// Resume is the oldest signature seen when MaxSignaturePages was reached
+	// first; the history before it is still unscanned. ScannedTo is the slot
+	// up to which the history is fully scanned.
+	Resume    string
+	ScannedTo int64
+}
+
+// signaturesSince returns the signatures of address newer than bound and not
+// after slot upTo, newest first, walking back page by page with before until
+// it reaches bound or the start of the address's history.
+//
+// The history is fully scanned up to upTo, or, when MaxSignaturePages was
+// reached first, up to the slot before the oldest one seen, since the rest of
+// that slot's signatures may be on the next page.
+func (s SolanaRpcSource) signaturesSince(ctx context.Context, address string, bound signatureBound, upTo int64, limit int) (signatureScan, error) {
+	scan := signatureScan{Sigs: make([]signatureItem, 0), Newest: bound.Until, ScannedTo: upTo}
+	before := bound.Before
+	oldest := upTo
+	pages := s.effectiveMaxSignaturePages()
+
+	done := func() signatureScan {
+		if len(scan.Sigs) > 0 {
+			scan.Newest = scan.Sigs[0].Signature
+		}
+		return scan
+	}
+
+	for page := 0; page < pages; page++ {
+		sigs, err := s.getSignaturesForAddress(ctx, address, limit, before, bound.Until)
+		if err != nil {
+			return signatureScan{}, err
+		}
+		for _, sig := range sigs {
+			if bound.Until == "" && bound.reached(sig) {
+				return done(), nil
 			}
+			// Newer than the finalized slot polled; the next poll covers it.
+			if sig.Slot <= upTo {
+				scan.Sigs = append(scan.Sigs, sig)
+			}
+		}
+		if len(sigs) < limit {
+			return done(), nil
+		}
+		before = sigs[len(sigs)-1].Signature
+		oldest = sigs[len(sigs)-1].Slot
+	}
+
+	scan.Resume = before
+	scan.ScannedTo = min(upTo, oldest-1)
+	slog.Warn("solana-rpc: signature page cap reached",
+		"address", address,
+		"pages", pages,
+		"scannedTo", scan.ScannedTo,
+		"lagSlots", upTo-scan.ScannedTo,
+	)
+	return done(), nil
+}
+
+// reached reports whether sig is at or before the bound when no signature is
+// known for the address.
+func (b signatureBound) reached(sig signatureItem) bool {
+	if !b.Since.IsZero() && sig.BlockTime != nil {
+		return time.Unix(*sig.BlockTime, 0).Before(b.Since)
+	}
+	return sig.Slot <= b.AfterSlot
+}
+
+// fetchedSignature is a signature whose transaction and confirmation time were
+// resolved. Index points back into the slice passed to fetchSignatureTransactions.
+type fetchedSignature struct {
+	Index       int
+	Signature   signatureItem
+	ConfirmedAt time.Time
+	Tx          transactionResult
+}
+
+// fetchSignatureTransactions resolves block times and transactions for many
+// signatures. The lookups are sent as JSON-RPC batches of at most
+// MaxBatchSize requests, FetchWorkers batches at a time, each with its own
+// FetchTimeout deadline. Results keep the order of sigs. If any lookup fails
+// the whole fetch fails, so the poll is retried instead of the cursor moving
+// past a transaction that was never seen.
+func (s SolanaRpcSource) fetchSignatureTransactions(ctx context.Context, sigs []signatureItem) ([]fetchedSignature, error) {
+	if len(sigs) == 0 {
+		return nil, nil
+	}
+
+	blockTimes := make([]*int64, len(sigs))
+	txs := make([]*transactionResult, len(sigs))
+	blockTimeCalls := make([]*rpcBatchCall, len(sigs))
+	txCalls := make([]*rpcBatchCall, len(sigs))
+
+	// A signature's calls always share a batch.
+	chunks := make([][]*rpcBatchCall, 0)
+	chunk := make([]*rpcBatchCall, 0)
+	maxBatchSize := s.rpc().effectiveMaxBatchSize()
+	for i, sig := range sigs {
+		calls := make([]*rpcBatchCall, 0, 2)
+		if sig.BlockTime == nil || *sig.BlockTime <= 0 {
+			blockTimeCalls[i] = &rpcBatchCall{Method: "getBlockTime", Params: []interface{}{sig.Slot}, Out: &blockTimes[i]}
+			calls = append(calls, blockTimeCalls[i])
+		}
+		txCalls[i] = &rpcBatchCall{Method: "getTransaction", Params: getTransactionParams(sig.Signature), Out: &txs[i]}
+		calls = append(calls, txCalls[i])
+
+		if len(chunk) > 0 && len(chunk)+len(calls) > maxBatchSize {
+			chunks = append(chunks, chunk)
+			chunk = make([]*rpcBatchCall, 0)
+		}
+		chunk = append(chunk, calls...)
+	}
+	chunks = append(chunks, chunk)
+
+	chunkErrs := make([]error, len(chunks))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(s.effectiveFetchWorkers(), len(chunks)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range next {
+				chunkCtx, cancel := context.WithTimeout(ctx, s.effectiveFetchTimeout())
+				chunkErrs[index] = s.rpc().batch(chunkCtx, chunks[index])
+				cancel()
+			}
+		}()
+	}
+	for index := range chunks {
+		next <- index
+	}
+	close(next)
+	wg.Wait()
+
+	if err := errors.Join(chunkErrs...); err != nil {
+		return nil, fmt.Errorf("fetch transactions: %w", err)
+	}
 
-			amountUSD, ok := extractTokenCreditUSD(tx, route.DepositAddress, expectedMint)
-			if !ok || amountUSD <= 0 {
+	out := make([]fetchedSignature, 0, len(sigs))
+	failed := make([]error, 0)
+	for i, sig := range sigs {
+		var confirmedAt time.Time
+		if call := blockTimeCalls[i]; call != nil {
+			if call.Err != nil {
+				failed = append(failed, fmt.Errorf("get block time for %s: %w", sig.Signature, call.Err))
 				continue
 			}
+			if blockTimes[i] == nil || *blockTimes[i] <= 0 {
+				failed = append(failed, fmt.Errorf("block time unavailable for slot %d (%s)", sig.Slot, sig.Signature))
+				continue
+			}
+			confirmedAt = time.Unix(*blockTimes[i], 0).UTC()
+		} else {
+			confirmedAt = time.Unix(*sig.BlockTime, 0).UTC()
+		}
 
-			candidates = append(candidates, FundingCandidate{
-				Chain:          s.Chain,
-				Token:          strings.ToUpper(route.Token),
-				TxHash:         sig.Signature,
-				LogIndex:       0,
-				DepositAddress: route.DepositAddress,
-				AmountUSD:      amountUSD,
-				ConfirmedAt:    confirmedAt,
-				Finalized:      true,
-			})
+		if txCalls[i].Err != nil {
+			failed = append(failed, fmt.Errorf("get transaction %s: %w", sig.Signature, txCalls[i].Err))
+			continue
 		}
+		// A null result means the provider has not got the transaction yet.
+		if txs[i] == nil {
+			failed = append(failed, fmt.Errorf("transaction %s unavailable", sig.Signature))
+			continue
+		}
+
+		out = append(out, fetchedSignature{
+			Index:       i,
+			Signature:   sig,
+			ConfirmedAt: confirmedAt,
+			Tx:          *txs[i],
+		})
 	}
 
-	return candidates, nil
+	if len(failed) > 0 {
+		return nil, fmt.Errorf("fetch transactions: %d of %d lookups failed, first: %w", len(failed), len(sigs), failed[0])
+	}
+	return out, nil
 }
 
 func isInvalidRouteAddressError(err error) bool {
@@ -258,11 +578,16 @@ type programPaymentParseInput struct {
 	Chain               string
 	TxHash              string
 	ProgramID           string
-	TokenMints          map[string]string
+	Tokens              *TokenRegistry
 	TreasuryATAs        map[string]string
 	FallbackConfirmedAt time.Time
 }
 
+// extractProgramPaymentCandidates decodes the PaymentAccepted events the
+// configured program logged in tx. "Program data:" lines are only trusted
+// while the program is the innermost frame of the invoke stack; an event
+// logged by any other program, for example one it invoked or one invoked
+// next to it, is reported as spoofed and flagged on the genuine candidates.
 func extractProgramPaymentCandidates(tx transactionResult, in programPaymentParseInput) []FundingCandidate {
 	if tx.Meta.Err != nil {
 		return nil
@@ -275,40 +600,28 @@ func extractProgramPaymentCandidates(tx transactionResult, in programPaymentPars
 	if len(logs) == 0 {
 		return nil
 	}
-	programSeen := false
-	for _, line := range logs {
-		if strings.Contains(line, "Program "+in.ProgramID+" ") {
-			programSeen = true
-			break
-		}
-	}
-	if !programSeen {
-		return nil
-	}
-
-	mintToToken := make(map[string]string, len(in.TokenMints))
-	for token, mint := range in.TokenMints {
-		if mint == "" {
-			continue
-		}
-		mintToToken[mint] = strings.ToUpper(token)
-	}
 
 	candidates := make([]FundingCandidate, 0)
 	eventIndex := 0
-	for _, line := range logs {
-		const prefix = "Program data: "
-		idx := strings.Index(line, prefix)
-		if idx < 0 {
+	spoofed := 0
+	for _, data := range programDataLines(logs) {
+		raw, err := base64.StdEncoding.DecodeString(data.Encoded)
+		if err != nil {
 			continue
 		}
-
-		encoded := strings.TrimSpace(line[idx+len(prefix):])
-		raw, err := base64.StdEncoding.DecodeString(encoded)
-		if err != nil {
+		if data.Program != in.ProgramID {
+			if len(raw) >= 8 && bytes.Equal(raw[:8], paymentAcceptedEventDiscriminator) {
+				spoofed++
+				slog.Warn("solana-rpc: ignoring PaymentAccepted event logged by another program",
+					"txHash", in.TxHash,
+					"emittedBy", data.Program,
+					"depth", data.Depth,
+					"programId", in.ProgramID,
+				)
+			}
 			continue
 		}
-		candidate, ok := decodePaymentAcceptedEvent(raw, eventIndex, mintToToken, in)
+		candidate, ok := decodePaymentAcceptedEvent(raw, eventIndex, in)
 		if !ok {
 			continue
 		}
@@ -316,10 +629,66 @@ func extractProgramPaymentCandidates(tx transactionResult, in programPaymentPars
 		eventIndex++
 	}
 
+	if spoofed > 0 {
+		for i := range candidates {
+			candidates[i].Metadata["spoofedEventCount"] = spoofed
+		}
+	}
 	return candidates
 }
 
-func decodePaymentAcceptedEvent(raw []byte, eventIndex int, mintToToken map[string]string, in programPaymentParseInput) (FundingCandidate, bool) {
+// programDataLine is a "Program data:" log line with the program that was
+// executing when it was logged and that program's invoke depth.
+type programDataLine struct {
+	Program string
+	Depth   int
+	Encoded string
+}
+
+// programDataLines replays the runtime's invoke/success/failed log lines to
+// attribute every "Program data:" line to the innermost executing program.
+// Lines logged with no program executing are attributed to "".
+func programDataLines(logs []string) []programDataLine {
+	const dataPrefix = "Program data: "
+
+	stack := make([]string, 0, 4)
+	out := make([]programDataLine, 0)
+	for _, line := range logs {
+		if strings.HasPrefix(line, dataPrefix) {
+			program := ""
+			if len(stack) > 0 {
+				program = stack[len(stack)-1]
+			}
+			out = append(out, programDataLine{Program: program, Depth: len(stack), Encoded: strings.TrimSpace(line[len(dataPrefix):])})
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 3 || fields[0] != "Program" {
+			continue
+		}
+		switch {
+		case fields[2] == "invoke" && len(fields) == 4:
+			// "Program <id> invoke [n]": the depth resynchronises the stack if
+			// a success or failure line was missing.
+			depth, err := strconv.Atoi(strings.Trim(fields[3], "[]"))
+			if err != nil || depth < 1 {
+				continue
+			}
+			if depth-1 < len(stack) {
+				stack = stack[:depth-1]
+			}
+			stack = append(stack, fields[1])
+		case fields[2] == "success" || strings.HasPrefix(fields[2], "failed"):
+			if len(stack) > 0 && stack[len(stack)-1] == fields[1] {
+				stack = stack[:len(stack)-1]
+			}
+		}
+	}
+	return out
+}
+
+func decodePaymentAcceptedEvent(raw []byte, eventIndex int, in programPaymentParseInput) (FundingCandidate, bool) {
 	// discriminator + u64 + pubkey + pubkey + u64 + [32] + i64 = 128 bytes
 	if len(raw) < 128 {
 		return FundingCandidate{}, false
@@ -345,22 +714,16 @@ func decodePaymentAcceptedEvent(raw []byte, eventIndex int, mintToToken map[stri
 		return FundingCandidate{}, false
 	}
 
-	mint := base58Encode(mintBytes)
-	token, ok := mintToToken[mint]
+	token, ok := in.Tokens.TokenByMint(base58Encode(mintBytes))
 	if !ok {
 		return FundingCandidate{}, false
 	}
 
-	treasuryATA := in.TreasuryATAs[token]
+	treasuryATA := in.TreasuryATAs[token.Symbol]
 	if treasuryATA == "" {
 		return FundingCandidate{}, false
 	}
 
-	amountUSD := float64(amountBaseUnits) / 1_000_000.0
-	if amountUSD <= 0 {
-		return FundingCandidate{}, false
-	}
-
 	confirmedAt := in.FallbackConfirmedAt
 	if eventTimestampSeconds > 0 {
 		confirmedAt = time.Unix(eventTimestampSeconds, 0).UTC()
@@ -369,14 +732,13 @@ func decodePaymentAcceptedEvent(raw []byte, eventIndex int, mintToToken map[stri
 		return FundingCandidate{}, false
 	}
 
-	return FundingCandidate{
+	candidate := FundingCandidate{
 		Chain:          in.Chain,
-		Token:          token,
+		Token:          token.Symbol,
 		TxHash:         in.TxHash,
 		LogIndex:       eventIndex,
 		ReferenceHash:  hex.EncodeToString(externalRefHash),
 		DepositAddress: treasuryATA,
-		AmountUSD:      amountUSD,
 		ConfirmedAt:    confirmedAt,
 		Finalized:      true,
 		Metadata: map[string]any{
@@ -385,7 +747,9 @@ func decodePaymentAcceptedEvent(raw []byte, eventIndex int, mintToToken map[stri
 			"referenceHash":      hex.EncodeToString(externalRefHash),
 			"verificationSource": "solana_watcher_fallback",
 		},
-	}, true
+	}
+	candidate.setAmount(token, new(big.Int).SetUint64(amountBaseUnits))
+	return candidate, true
 }
 
 const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
@@ -431,12 +795,22 @@ func (s SolanaRpcSource) getSlot(ctx context.Context) (int64, error) {
 	return out, nil
 }
 
-func (s SolanaRpcSource) getSignaturesForAddress(ctx context.Context, address string, limit int) ([]signatureItem, error) {
+// getSignaturesForAddress returns up to limit signatures of address, newest
+// first, below the before signature and above the until signature when they
+// are set.
+func (s SolanaRpcSource) getSignaturesForAddress(ctx context.Context, address string, limit int, before string, until string) ([]signatureItem, error) {
+	options := map[string]interface{}{"limit": limit, "commitment": "finalized"}
+	if before != "" {
+		options["before"] = before
+	}
+	if until != "" {
+		options["until"] = until
+	}
 	var out []signatureItem
 	if err := s.rpcCall(
 		ctx,
 		"getSignaturesForAddress",
-		[]interface{}{address, map[string]interface{}{"limit": limit, "commitment": "finalized"}},
+		[]interface{}{address, options},
 		&out,
 	); err != nil {
 		return nil, err
@@ -460,81 +834,28 @@ func (s SolanaRpcSource) getBlockTime(ctx context.Context, slot int64) (int64, e
 	return *out, nil
 }
 
-func (s SolanaRpcSource) resolveSignatureConfirmedAt(ctx context.Context, sig signatureItem) (time.Time, error) {
-	if sig.BlockTime != nil && *sig.BlockTime > 0 {
-		return time.Unix(*sig.BlockTime, 0).UTC(), nil
-	}
-
-	blockTime, err := s.getBlockTime(ctx, sig.Slot)
-	if err != nil {
-		return time.Time{}, err
-	}
-	if blockTime <= 0 {
-		return time.Time{}, fmt.Errorf("invalid block time for slot %d", sig.Slot)
-	}
-	return time.Unix(blockTime, 0).UTC(), nil
+func getTransactionParams(signature string) []interface{} {
+	return []interface{}{signature, map[string]interface{}{"encoding": "jsonParsed", "maxSupportedTransactionVersion": 0, "commitment": "finalized"}}
 }
 
-func (s SolanaRpcSource) getTransaction(ctx context.Context, signature string) (transactionResult, error) {
-	var out transactionResult
-	if err := s.rpcCall(
-		ctx,
-		"getTransaction",
-		[]interface{}{signature, map[string]interface{}{"encoding": "jsonParsed", "maxSupportedTransactionVersion": 0, "commitment": "finalized"}},
-		&out,
-	); err != nil {
-		return transactionResult{}, err
+func (s SolanaRpcSource) rpc() rpcClient {
+	return rpcClient{
+		URL:            s.RPCURL,
+		Pool:           s.RPCPool,
+		HTTPClient:     s.HTTPClient,
+		DefaultTimeout: 60 * time.Second,
+		MaxBatchSize:   s.MaxBatchSize,
+		Budget:         s.Budget,
 	}
-	return out, nil
 }
 
 func (s SolanaRpcSource) rpcCall(ctx context.Context, method string, params interface{}, out interface{}) error {
-	client := s.HTTPClient
-	if client == nil {
-		client = &http.Client{Timeout: 60 * time.Second}
-	}
-
-	reqBody, err := json.Marshal(rpcRequest{
-		JSONRPC: "2.0",
-		ID:      1,
-		Method:  method,
-		Params:  params,
-	})
-	if err != nil {
-		return err
-	}
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.RPCURL, bytes.NewReader(reqBody))
-	if err != nil {
-		return err
-	}
-	req.Header.Set("content-type", "application/json")
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return fmt.Errorf("rpc status %d", resp.StatusCode)
-	}
-
-	var rpcResp rpcResponse
-	if err := json.NewDecoder(resp.Body).Decode(&rpcResp); err != nil {
-		return err
-	}
-	if rpcResp.Error != nil {
-		return fmt.Errorf("rpc error %d: %s", rpcResp.Error.Code, rpcResp.Error.Message)
-	}
-
-	if out == nil {
-		return nil
-	}
-	return json.Unmarshal(rpcResp.Result, out)
+	return s.rpc().call(ctx, method, params, out)
 }
 
-func extractTokenCreditUSD(tx transactionResult, depositAddress string, mint string) (float64, bool) {
+// extractTokenCredit returns the base units of token credited to
+// depositAddress by tx.
+func extractTokenCredit(tx transactionResult, depositAddress string, token TokenInfo) (*big.Int, bool) {
 	// Find the account index for the deposit address
 	targetIndex := -1
 	for i, key := range tx.Transaction.Message.AccountKeys {
@@ -545,10 +866,10 @@ func extractTokenCreditUSD(tx transactionResult, depositAddress string, mint str
 	}
 
 	if targetIndex == -1 {
-		return 0, false
+		return nil, false
 	}
 
-	targetMint := strings.ToLower(strings.TrimSpace(mint))
+	targetMint := strings.ToLower(strings.TrimSpace(token.Mint))
 
 	// Get pre balance
 	preValue := big.NewInt(0)
@@ -575,16 +896,56 @@ func extractTokenCreditUSD(tx transactionResult, depositAddress string, mint str
 	}
 
 	if !foundPost {
-		return 0, false
+		return nil, false
 	}
 
 	delta := new(big.Int).Sub(postValue, preValue)
 	if delta.Sign() <= 0 {
-		return 0, false
+		return nil, false
 	}
 
-	f := new(big.Float).SetInt(delta)
-	usd := new(big.Float).Quo(f, big.NewFloat(1_000_000))
-	result, _ := usd.Float64()
-	return result, true
+	return delta, true
+}
+
+// extractTokenDebit returns the owner and address of the token account that
+// tx debited most for token, which is the payer of a plain SPL transfer.
+func extractTokenDebit(tx transactionResult, token TokenInfo) (string, string, bool) {
+	targetMint := strings.ToLower(strings.TrimSpace(token.Mint))
+
+	pre := make(map[int]tokenBalance)
+	for _, balance := range tx.Meta.PreTokenBalances {
+		if strings.ToLower(strings.TrimSpace(balance.Mint)) == targetMint {
+			pre[balance.AccountIndex] = balance
+		}
+	}
+	post := make(map[int]*big.Int)
+	for _, balance := range tx.Meta.PostTokenBalances {
+		if strings.ToLower(strings.TrimSpace(balance.Mint)) == targetMint {
+			if value, ok := new(big.Int).SetString(balance.UITokenAmount.Amount, 10); ok {
+				post[balance.AccountIndex] = value
+			}
+		}
+	}
+
+	var debited tokenBalance
+	largest := big.NewInt(0)
+	for index, balance := range pre {
+		before, ok := new(big.Int).SetString(balance.UITokenAmount.Amount, 10)
+		if !ok {
+			continue
+		}
+		after, ok := post[index]
+		if !ok {
+			after = big.NewInt(0) // the account was closed
+		}
+		if debit := new(big.Int).Sub(before, after); debit.Cmp(largest) > 0 {
+			largest, debited = debit, balance
+		}
+	}
+
+	keys := tx.Transaction.Message.AccountKeys
+	if largest.Sign() == 0 || debited.AccountIndex >= len(keys) {
+		return "", "", false
+	}
+	return debited.Owner, keys[debited.AccountIndex].Pubkey, true
 }
@@ -0,0 +1,338 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// signatureHistoryServer serves getSignaturesForAddress for one signature per
+// slot from 101 to newest, newest first, honouring limit, before and until.
+// Slot n's block time is 1_770_000_000 + n.
+func signatureHistoryServer(newest int64, pages *int) *http.Client {
+	return &http.Client{Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		var req struct {
+			Params []json.RawMessage `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return nil, err
+		}
+		var options struct {
+			Limit  int    `json:"limit"`
+			Before string `json:"before"`
+			Until  string `json:"until"`
+		}
+		_ = json.Unmarshal(req.Params[1], &options)
+		*pages++
+
+		slot := newest
+		if options.Before != "" {
+			fmt.Sscanf(options.Before, "sig_%d", &slot)
+			slot--
+		}
+		until := int64(100)
+		if options.Until != "" {
+			fmt.Sscanf(options.Until, "sig_%d", &until)
+		}
+		items := make([]string, 0, options.Limit)
+		for ; slot > until && len(items) < options.Limit; slot-- {
+			items = append(items, fmt.Sprintf(`{"signature":"sig_%d","slot":%d,"blockTime":%d}`, slot, slot, 1_770_000_000+slot))
+		}
+		body := `{"jsonrpc":"2.0","id":1,"result":[` + strings.Join(items, ",") + `]}`
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(body)),
+			Header:     make(http.Header),
+		}, nil
+	})}
+}
+
+func TestSolanaRpcSource_SignaturesSincePaginatesToCursor(t *testing.T) {
+	pages := 0
+	source := SolanaRpcSource{RPCURL: "https://rpc.internal", HTTPClient: signatureHistoryServer(420, &pages)}
+
+	scan, err := source.signaturesSince(context.Background(), "treasury", signatureBound{AfterSlot: 150}, 400, 100)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Slots 151-400: signatures above the polled slot are left for the next poll.
+	if sigs := scan.Sigs; len(sigs) != 250 || sigs[0].Slot != 400 || sigs[len(sigs)-1].Slot != 151 {
+		t.Fatalf("expected slots 400 down to 151, got %d signatures", len(sigs))
+	}
+	if scan.Newest != "sig_400" || scan.ScannedTo != 400 || scan.Resume != "" || pages != 3 {
+		t.Fatalf("expected history scanned to sig_400 at 400 in 3 pages, got %+v in %d", scan, pages)
+	}
+}
+
+func TestSolanaRpcSource_SignaturesSinceHoldsCursorAtPageCap(t *testing.T) {
+	pages := 0
+	source := SolanaRpcSource{RPCURL: "https://rpc.internal", HTTPClient: signatureHistoryServer(400, &pages), MaxSignaturePages: 2}
+
+	scan, err := source.signaturesSince(context.Background(), "treasury", signatureBound{Until: "sig_150"}, 400, 100)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(scan.Sigs) != 200 || pages != 2 {
+		t.Fatalf("expected 200 signatures from 2 pages, got %d from %d", len(scan.Sigs), pages)
+	}
+	// Slot 201 was the oldest seen; its remaining signatures may be unscanned,
+	// so the history is only scanned to slot 200 and resumes before sig_201.
+	if scan.ScannedTo != 200 || scan.Newest != "sig_400" || scan.Resume != "sig_201" {
+		t.Fatalf("expected history scanned to slot 200 resuming before sig_201, got %+v", scan)
+	}
+
+	pages = 0
+	scan, err = source.signaturesSince(context.Background(), "treasury", signatureBound{}, 400, 1000)
+	if err != nil || len(scan.Sigs) != 300 || scan.ScannedTo != 400 || scan.Resume != "" || pages != 1 {
+		t.Fatalf("expected the short page to end the history, got %d signatures to %d in %d pages (%v)", len(scan.Sigs), scan.ScannedTo, pages, err)
+	}
+}
+
+func TestSolanaRpcSource_SignaturesSinceStopsAtUntilAndCreation(t *testing.T) {
+	pages := 0
+	source := SolanaRpcSource{RPCURL: "https://rpc.internal", HTTPClient: signatureHistoryServer(400, &pages)}
+
+	scan, err := source.signaturesSince(context.Background(), "route", signatureBound{Until: "sig_390", AfterSlot: 100}, 400, 100)
+	if err != nil || len(scan.Sigs) != 10 || scan.Newest != "sig_400" || pages != 1 {
+		t.Fatalf("expected only the 10 signatures after sig_390, got %d to %s in %d pages (%v)", len(scan.Sigs), scan.Newest, pages, err)
+	}
+
+	scan, err = source.signaturesSince(context.Background(), "route", signatureBound{Until: "sig_400"}, 400, 100)
+	if err != nil || len(scan.Sigs) != 0 || scan.Newest != "sig_400" {
+		t.Fatalf("expected no new signatures and the same newest signature, got %d and %s (%v)", len(scan.Sigs), scan.Newest, err)
+	}
+
+	// A new route created at slot 300's time is scanned back to its creation,
+	// even though the global slot is more recent.
+	since := time.Unix(1_770_000_000+300, 0)
+	scan, err = source.signaturesSince(context.Background(), "route", signatureBound{AfterSlot: 390, Since: since}, 400, 100)
+	if sigs := scan.Sigs; err != nil || len(sigs) != 101 || sigs[len(sigs)-1].Slot != 300 {
+		t.Fatalf("expected slots 400 down to 300, got %d signatures (%v)", len(sigs), err)
+	}
+}
+
+func TestSolanaRpcSource_ScanAddressResumesAfterPageCap(t *testing.T) {
+	pages := 0
+	newestSlot := int64(400)
+	history := signatureHistoryServer(newestSlot, &pages)
+	source := SolanaRpcSource{RPCURL: "https://rpc.internal", HTTPClient: history, MaxSignaturePages: 2}
+
+	poll := func(cursor string, upTo int64) ([]signatureItem, solanaCursor) {
+		t.Helper()
+		previous := parseSolanaCursor(cursor)
+		next := solanaCursor{Slot: upTo, Signatures: map[string]string{}, Resume: map[string]signatureResume{}}
+		bound := signatureBound{Until: previous.Signatures["treasury"], AfterSlot: previous.Slot}
+		sigs, err := source.scanAddress(context.Background(), "treasury", bound, upTo, 100, previous, &next)
+		if err != nil {
+			t.Fatalf("unexpected scan error: %v", err)
+		}
+		return sigs, next
+	}
+
+	// 300 new signatures but only 2 pages per poll: the walk stops before
+	// sig_201 and the cursor stays below the unscanned slots.
+	sigs, first := poll("100", 400)
+	if len(sigs) != 200 || first.Slot != 200 || first.Signatures["treasury"] != "sig_400" || first.Resume["treasury"].Before != "sig_201" {
+		t.Fatalf("expected a capped first poll resuming before sig_201, got %d signatures and %s", len(sigs), first.String())
+	}
+
+	// The next poll continues the gap instead of rescanning from the top, and
+	// then picks up what arrived since.
+	history.Transport = signatureHistoryServer(410, &pages).Transport
+	pages = 0
+	sigs, second := poll(first.String(), 410)
+	if len(sigs) != 110 || sigs[0].Slot != 200 || sigs[99].Slot != 101 || sigs[100].Slot != 410 || sigs[109].Slot != 401 {
+		t.Fatalf("expected slots 200-101 then 410-401, got %d signatures", len(sigs))
+	}
+	if second.Slot != 410 || second.Signatures["treasury"] != "sig_410" || len(second.Resume) != 0 || pages != 3 {
+		t.Fatalf("expected the history caught up to sig_410 in 3 pages, got %s in %d", second.String(), pages)
+	}
+}
+
+func TestSolanaCursor_RoundTripsAndReadsBareSlots(t *testing.T) {
+	if cursor := parseSolanaCursor("1234"); cursor.Slot != 1234 || len(cursor.Signatures) != 0 {
+		t.Fatalf("expected a bare slot cursor, got %+v", cursor)
+	}
+	if encoded := (solanaCursor{Slot: 1234}).String(); encoded != "1234" {
+		t.Fatalf("expected cursor without signatures to stay a bare slot, got %s", encoded)
+	}
+
+	cursor := solanaCursor{
+		Slot:       99,
+		Signatures: map[string]string{"treasury": "sig_99"},
+		Resume:     map[string]signatureResume{"route": {Before: "sig_50", AfterSlot: 10, Since: 1_770_000_000}},
+	}
+	decoded := parseSolanaCursor(cursor.String())
+	if decoded.Slot != 99 || decoded.Signatures["treasury"] != "sig_99" || decoded.Resume["route"] != cursor.Resume["route"] {
+		t.Fatalf("expected cursor to round trip, got %+v from %s", decoded, cursor.String())
+	}
+}
+
+// transactionBatchServer answers batched getTransaction requests, tracking how
+// many batches are in flight at once, failing the signatures in failing and
+// returning a null result for those in missing.
+func transactionBatchServer(failing map[string]bool, missing map[string]bool, inFlight *int32, maxInFlight *int32) *http.Client {
+	return &http.Client{Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		current := atomic.AddInt32(inFlight, 1)
+		defer atomic.AddInt32(inFlight, -1)
+		for {
+			seen := atomic.LoadInt32(maxInFlight)
+			if current <= seen || atomic.CompareAndSwapInt32(maxInFlight, seen, current) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+
+		var reqs []struct {
+			ID     int               `json:"id"`
+			Params []json.RawMessage `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&reqs); err != nil {
+			return nil, err
+		}
+		items := make([]string, 0, len(reqs))
+		for _, req := range reqs {
+			var signature string
+			_ = json.Unmarshal(req.Params[0], &signature)
+			if failing[signature] {
+				items = append(items, fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"error":{"code":-32603,"message":"internal error"}}`, req.ID))
+				continue
+			}
+			if missing[signature] {
+				items = append(items, fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"result":null}`, req.ID))
+				continue
+			}
+			items = append(items, fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"result":{"meta":{"logMessages":[%q]}}}`, req.ID, signature))
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader("[" + strings.Join(items, ",") + "]")),
+			Header:     make(http.Header),
+		}, nil
+	})}
+}
+
+func TestSolanaRpcSource_FetchSignatureTransactionsInParallelAndInOrder(t *testing.T) {
+	blockTime := int64(1_770_000_000)
+	sigs := make([]signatureItem, 20)
+	for i := range sigs {
+		sigs[i] = signatureItem{Signature: fmt.Sprintf("sig_%d", i), Slot: int64(i), BlockTime: &blockTime}
+	}
+
+	var inFlight, maxInFlight int32
+	source := SolanaRpcSource{
+		RPCURL:       "https://rpc.internal",
+		HTTPClient:   transactionBatchServer(nil, nil, &inFlight, &maxInFlight),
+		MaxBatchSize: 2,
+		FetchWorkers: 3,
+	}
+	fetched, err := source.fetchSignatureTransactions(context.Background(), sigs)
+	if err != nil {
+		t.Fatalf("unexpected fetch error: %v", err)
+	}
+	if len(fetched) != len(sigs) {
+		t.Fatalf("expected %d transactions, got %d", len(sigs), len(fetched))
+	}
+	for i, item := range fetched {
+		if item.Index != i || item.Tx.Meta.LogMessages[0] != sigs[i].Signature {
+			t.Fatalf("expected results in signature order, got %s at %d", item.Tx.Meta.LogMessages[0], i)
+		}
+	}
+	if maxInFlight < 2 || maxInFlight > 3 {
+		t.Fatalf("expected between 2 and 3 batches in flight, got %d", maxInFlight)
+	}
+
+	source.HTTPClient = transactionBatchServer(map[string]bool{"sig_7": true}, nil, &inFlight, &maxInFlight)
+	_, err = source.fetchSignatureTransactions(context.Background(), sigs)
+	if err == nil || !strings.Contains(err.Error(), "1 of 20 lookups failed") || !strings.Contains(err.Error(), "sig_7") {
+		t.Fatalf("expected the failed lookup to be reported, got %v", err)
+	}
+
+	// A transaction the provider does not have yet fails the fetch instead of
+	// being read as an empty one.
+	source.HTTPClient = transactionBatchServer(nil, map[string]bool{"sig_3": true, "sig_12": true}, &inFlight, &maxInFlight)
+	_, err = source.fetchSignatureTransactions(context.Background(), sigs)
+	if err == nil || !strings.Contains(err.Error(), "2 of 20 lookups failed") || !strings.Contains(err.Error(), "sig_3 unavailable") {
+		t.Fatalf("expected the null transactions to be reported, got %v", err)
+	}
+}
+
+// paymentAcceptedData encodes a PaymentAccepted event for mint as a
+// "Program data:" payload.
+func paymentAcceptedData(mint []byte, amount uint64) string {
+	raw := append([]byte{}, paymentAcceptedEventDiscriminator...)
+	raw = binary.LittleEndian.AppendUint64(raw, 42)     // payment id
+	raw = append(raw, bytes.Repeat([]byte{7}, 32)...)   // payer
+	raw = append(raw, mint...)                          // mint
+	raw = binary.LittleEndian.AppendUint64(raw, amount) // amount
+	raw = append(raw, bytes.Repeat([]byte{9}, 32)...)   // external reference hash
+	raw = binary.LittleEndian.AppendUint64(raw, 1_770_000_000)
+	return "Program data: " + base64.StdEncoding.EncodeToString(raw)
+}
+
+func TestExtractProgramPaymentCandidates_OnlyTrustsTheProgramsOwnFrames(t *testing.T) {
+	mint := bytes.Repeat([]byte{1}, 32)
+	tokens, err := NewTokenRegistry([]TokenInfo{{Symbol: "USDC", Mint: base58Encode(mint), Decimals: 6}})
+	if err != nil {
+		t.Fatalf("unexpected registry error: %v", err)
+	}
+	in := programPaymentParseInput{
+		Chain:        "solana",
+		TxHash:       "sig_pay",
+		ProgramID:    "PayProgram",
+		Tokens:       tokens,
+		TreasuryATAs: map[string]string{"USDC": "treasury"},
+	}
+
+	tx := transactionResult{}
+	tx.Meta.LogMessages = []string{
+		"Program ComputeBudget111111111111111111111111111111 invoke [1]",
+		"Program ComputeBudget111111111111111111111111111111 success",
+		// An impostor invoked alongside the program, and one the program
+		// itself invokes, both log a forged event.
+		"Program Impostor invoke [1]",
+		paymentAcceptedData(mint, 999_000_000),
+		"Program Impostor success",
+		"Program PayProgram invoke [1]",
+		"Program log: Instruction: Pay",
+		"Program TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA invoke [2]",
+		"Program TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA success",
+		"Program Impostor invoke [2]",
+		paymentAcceptedData(mint, 888_000_000),
+		"Program Impostor success",
+		paymentAcceptedData(mint, 5_000_000),
+		"Program PayProgram consumed 20000 of 200000 compute units",
+		"Program PayProgram success",
+	}
+
+	candidates := extractProgramPaymentCandidates(tx, in)
+	if len(candidates) != 1 {
+		t.Fatalf("expected only the program's own event, got %d candidates", len(candidates))
+	}
+	if candidates[0].AmountBaseUnits != "5000000" || candidates[0].LogIndex != 0 {
+		t.Fatalf("unexpected candidate: %+v", candidates[0])
+	}
+	if candidates[0].Metadata["spoofedEventCount"] != 2 {
+		t.Fatalf("expected the forged events flagged, got %v", candidates[0].Metadata)
+	}
+
+	// A program that merely mentions the program ID is not enough.
+	tx.Meta.LogMessages = []string{
+		"Program Impostor invoke [1]",
+		"Program log: Program PayProgram invoke [1]",
+		paymentAcceptedData(mint, 999_000_000),
+		"Program Impostor success",
+	}
+	if candidates := extractProgramPaymentCandidates(tx, in); len(candidates) != 0 {
+		t.Fatalf("expected no candidates from an impostor, got %+v", candidates)
+	}
+}
@@ -0,0 +1,451 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+)
+
+// SolanaWebsocketSource is a push-mode CandidateSource. It keeps a
+// logsSubscribe subscription to the program and accountSubscribe
+// subscriptions to the treasury ATAs and route token accounts open, all at
+// finalized commitment, and wakes the runner on every notification.
+//
+// Polling and checkpoints stay in the wrapped SolanaRpcSource. Program log
+// notifications hand their signatures straight to the program payment parser,
+// and an address whose account subscription has seen no change since the
+// cursor is not scanned at all. Any address not fully covered by a live
+// subscription (before it is subscribed, after a reconnect or after a change)
+// is scanned over HTTP, so a dropped connection never leaves a gap.
+type SolanaWebsocketSource struct {
+	WSURL             string
+	Source            SolanaRpcSource
+	Logger            *slog.Logger
+	ReconnectDelay    time.Duration // first delay after a dropped connection (default: 1s)
+	MaxReconnectDelay time.Duration // cap for repeated failures (default: 30s)
+	IdleTimeout       time.Duration // reconnect when no message arrives for this long (default: 60s)
+
+	activity *wsActivity
+	wake     chan struct{}
+}
+
+// NewSolanaWebsocketSource wraps an HTTP source with WebSocket subscriptions.
+func NewSolanaWebsocketSource(wsURL string, source SolanaRpcSource, logger *slog.Logger) *SolanaWebsocketSource {
+	return &SolanaWebsocketSource{
+		WSURL:    wsURL,
+		Source:   source,
+		Logger:   logger,
+		activity: newWsActivity(),
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// Wake fires whenever a finalized notification arrives.
+func (s *SolanaWebsocketSource) Wake() <-chan struct{} {
+	return s.wake
+}
+
+func (s *SolanaWebsocketSource) Poll(ctx context.Context, cursor string) ([]FundingCandidate, string, error) {
+	source := s.Source
+	source.live = s.activity
+	return source.Poll(ctx, cursor)
+}
+
+func (s *SolanaWebsocketSource) CursorAt(ctx context.Context, at time.Time) (string, error) {
+	return s.Source.CursorAt(ctx, at)
+}
+
+// Start keeps the subscriptions alive in the background until ctx is done.
+func (s *SolanaWebsocketSource) Start(ctx context.Context) {
+	go s.run(ctx)
+}
+
+func (s *SolanaWebsocketSource) logger() *slog.Logger {
+	if s.Logger != nil {
+		return s.Logger
+	}
+	return slog.Default()
+}
+
+func (s *SolanaWebsocketSource) effectiveReconnectDelay() time.Duration {
+	if s.ReconnectDelay > 0 {
+		return s.ReconnectDelay
+	}
+	return time.Second
+}
+
+func (s *SolanaWebsocketSource) effectiveMaxReconnectDelay() time.Duration {
+	if s.MaxReconnectDelay > 0 {
+		return s.MaxReconnectDelay
+	}
+	return 30 * time.Second
+}
+
+func (s *SolanaWebsocketSource) effectiveIdleTimeout() time.Duration {
+	if s.IdleTimeout > 0 {
+		return s.IdleTimeout
+	}
+	return 60 * time.Second
+}
+
+func (s *SolanaWebsocketSource) run(ctx context.Context) {
+	delay := s.effectiveReconnectDelay()
+	for {
+		subscribed, err := s.session(ctx)
+		covered := s.activity.reset()
+		if ctx.Err() != nil {
+			return
+		}
+		if subscribed {
+			delay = s.effectiveReconnectDelay()
+		}
+
+		s.logger().Warn("solana-ws: subscription dropped, reconnecting; missed slots are backfilled over http",
+			"endpoint", redactRPCURL(s.WSURL),
+			"coveredAddresses", covered,
+			"reconnectIn", delay.String(),
+			"error", err,
+		)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay *= 2
+		if delay > s.effectiveMaxReconnectDelay() {
+			delay = s.effectiveMaxReconnectDelay()
+		}
+	}
+}
+
+type wsMessage struct {
+	ID     *int            `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+	Method string `json:"method"`
+	Params struct {
+		Subscription int64 `json:"subscription"`
+		Result       struct {
+			Context struct {
+				Slot int64 `json:"slot"`
+			} `json:"context"`
+			Value json.RawMessage `json:"value"`
+		} `json:"result"`
+	} `json:"params"`
+}
+
+// session runs one connection until it fails. It reports whether the program
+// logs subscription was established. Account subscriptions are requested as
+// polls come across addresses that are not yet subscribed.
+func (s *SolanaWebsocketSource) session(ctx context.Context) (bool, error) {
+	conn, err := dialWebsocket(ctx, s.WSURL)
+	if err != nil {
+		return false, err
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-done:
+		}
+		conn.close()
+	}()
+
+	send := func(method string, params []interface{}, address string) error {
+		encoded, err := json.Marshal(rpcRequest{
+			JSONRPC: "2.0",
+			ID:      s.activity.request(address),
+			Method:  method,
+			Params:  params,
+		})
+		if err != nil {
+			return err
+		}
+		return conn.writeText(encoded)
+	}
+
+	logsSubscribed := s.Source.ProgramID == ""
+	if !logsSubscribed {
+		if err := send("logsSubscribe", []interface{}{
+			map[string]interface{}{"mentions": []string{s.Source.ProgramID}},
+			map[string]interface{}{"commitment": "finalized"},
+		}, wsProgramLogsKey); err != nil {
+			return false, err
+		}
+	}
+
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-s.activity.wanted:
+			}
+			for _, address := range s.activity.takeWanted() {
+				if err := send("accountSubscribe", []interface{}{
+					address,
+					map[string]interface{}{"encoding": "base64", "commitment": "finalized"},
+				}, address); err != nil {
+					conn.close()
+					return
+				}
+			}
+		}
+	}()
+
+	for {
+		raw, err := conn.readMessage(s.effectiveIdleTimeout())
+		if err != nil {
+			return logsSubscribed, err
+		}
+
+		var msg wsMessage
+		if err := json.Unmarshal(raw, &msg); err != nil {
+			return logsSubscribed, fmt.Errorf("decode websocket message: %w", err)
+		}
+
+		if msg.ID != nil {
+			if msg.Error != nil {
+				address := s.activity.fail(*msg.ID)
+				if address == wsProgramLogsKey {
+					return false, fmt.Errorf("logsSubscribe: rpc error %d: %s", msg.Error.Code, msg.Error.Message)
+				}
+				s.logger().Warn("solana-ws: account subscription rejected, address stays on http polling",
+					"address", address,
+					"error", msg.Error.Message,
+				)
+				continue
+			}
+			var subscription int64
+			if err := json.Unmarshal(msg.Result, &subscription); err != nil {
+				return logsSubscribed, fmt.Errorf("decode subscription id: %w", err)
+			}
+			if address := s.activity.confirm(*msg.ID, subscription); address == wsProgramLogsKey {
+				logsSubscribed = true
+				s.logger().Info("solana-ws: subscribed",
+					"endpoint", redactRPCURL(s.WSURL),
+					"programId", s.Source.ProgramID,
+				)
+			}
+			continue
+		}
+
+		switch msg.Method {
+		case "logsNotification":
+			var value struct {
+				Signature string `json:"signature"`
+				Err       any    `json:"err"`
+			}
+			if err := json.Unmarshal(msg.Params.Result.Value, &value); err != nil {
+				return logsSubscribed, fmt.Errorf("decode logs notification: %w", err)
+			}
+			if value.Err != nil || value.Signature == "" {
+				continue
+			}
+			s.activity.addProgramSignature(signatureItem{Signature: value.Signature, Slot: msg.Params.Result.Context.Slot})
+		case "accountNotification":
+			s.activity.addAccountChange(msg.Params.Subscription, msg.Params.Result.Context.Slot)
+		default:
+			continue
+		}
+		select {
+		case s.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// wsProgramLogsKey stands for the program logs subscription wherever
+// wsActivity keys subscriptions by address.
+const wsProgramLogsKey = "logs"
+
+// maxWsProgramSignatures bounds the program signatures buffered between polls;
+// the buffer is dropped when it is exceeded, leaving them to the HTTP scan.
+const maxWsProgramSignatures = 10000
+
+// wsActivity is what the subscriptions saw. A subscription only counts once a
+// poll that started after it was confirmed has read the finalized slot: every
+// later slot is covered, so the address can be skipped from then on until a
+// change is notified.
+type wsActivity struct {
+	mu            sync.Mutex
+	generation    int // bumped by reset, so polls spanning one cover nothing
+	nextID        int
+	requests      map[int]string   // request id -> address
+	subscriptions map[int64]string // subscription id -> address
+	confirmed     []string         // subscribed, waiting for a poll to cover them
+	coveredAfter  map[string]int64 // address -> slot after which every change is notified
+	changed       map[string]int64 // address -> newest slot a change was notified in
+	requested     map[string]bool  // addresses queued, requested or subscribed
+	failed        map[string]bool  // addresses the node refused to subscribe
+	programSigs   map[string]signatureItem
+	pendingWanted map[string]bool
+	wanted        chan struct{}
+}
+
+func newWsActivity() *wsActivity {
+	a := &wsActivity{wanted: make(chan struct{}, 1)}
+	a.reset()
+	return a
+}
+
+// reset drops every subscription and returns how many addresses were covered.
+func (a *wsActivity) reset() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	covered := len(a.coveredAfter)
+	a.generation++
+	a.requests = map[int]string{}
+	a.subscriptions = map[int64]string{}
+	a.confirmed = nil
+	a.coveredAfter = map[string]int64{}
+	a.changed = map[string]int64{}
+	a.requested = map[string]bool{}
+	a.failed = map[string]bool{}
+	a.programSigs = map[string]signatureItem{}
+	a.pendingWanted = map[string]bool{}
+	return covered
+}
+
+// request registers a subscription request for address and returns its id.
+func (a *wsActivity) request(address string) int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.nextID++
+	a.requests[a.nextID] = address
+	return a.nextID
+}
+
+func (a *wsActivity) confirm(requestID int, subscription int64) string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	address := a.requests[requestID]
+	delete(a.requests, requestID)
+	if address != "" {
+		a.subscriptions[subscription] = address
+		a.confirmed = append(a.confirmed, address)
+	}
+	return address
+}
+
+func (a *wsActivity) fail(requestID int) string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	address := a.requests[requestID]
+	delete(a.requests, requestID)
+	a.failed[address] = true
+	return address
+}
+
+func (a *wsActivity) takeWanted() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	out := make([]string, 0, len(a.pendingWanted))
+	for address := range a.pendingWanted {
+		out = append(out, address)
+	}
+	a.pendingWanted = map[string]bool{}
+	return out
+}
+
+func (a *wsActivity) addAccountChange(subscription int64, slot int64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if address, ok := a.subscriptions[subscription]; ok && slot > a.changed[address] {
+		a.changed[address] = slot
+	}
+}
+
+func (a *wsActivity) addProgramSignature(sig signatureItem) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if len(a.programSigs) >= maxWsProgramSignatures {
+		a.programSigs = map[string]signatureItem{}
+	}
+	a.programSigs[sig.Signature] = sig
+}
+
+// beginPoll is called before a poll reads the finalized slot. The returned
+// function, given that slot, starts coverage of every subscription confirmed
+// before the read. A nil activity returns a no-op.
+func (a *wsActivity) beginPoll() func(latestSlot int64) {
+	if a == nil {
+		return func(int64) {}
+	}
+	a.mu.Lock()
+	confirmed := a.confirmed
+	generation := a.generation
+	a.confirmed = nil
+	a.mu.Unlock()
+
+	return func(latestSlot int64) {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		if a.generation != generation {
+			return
+		}
+		for _, address := range confirmed {
+			if _, ok := a.coveredAfter[address]; !ok {
+				a.coveredAfter[address] = latestSlot
+			}
+		}
+	}
+}
+
+// unchanged reports whether the subscription proves that address had no
+// finalized change after slot. Addresses without a subscription are queued
+// for one. A nil activity reports false.
+func (a *wsActivity) unchanged(address string, slot int64) bool {
+	if a == nil {
+		return false
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	coveredAfter, covered := a.coveredAfter[address]
+	if !covered {
+		if !a.failed[address] && !a.requested[address] {
+			a.requested[address] = true
+			a.pendingWanted[address] = true
+			select {
+			case a.wanted <- struct{}{}:
+			default:
+			}
+		}
+		return false
+	}
+	return coveredAfter <= slot && a.changed[address] <= slot
+}
+
+// programSignatures returns the notified program signatures in slots
+// (after, upTo] and forgets those at or before after, which a previous poll
+// already handled.
+func (a *wsActivity) programSignatures(after int64, upTo int64) []signatureItem {
+	if a == nil {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	out := make([]signatureItem, 0)
+	for signature, sig := range a.programSigs {
+		switch {
+		case sig.Slot <= after:
+			delete(a.programSigs, signature)
+		case sig.Slot <= upTo:
+			out = append(out, sig)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Slot > out[j].Slot })
+	return out
+}
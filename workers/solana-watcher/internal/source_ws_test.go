@@ -0,0 +1,336 @@
+package internal
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeWSPeer is the server side of one WebSocket connection.
+type fakeWSPeer struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	mu     sync.Mutex
+}
+
+func (p *fakeWSPeer) send(v any) error {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	header := []byte{0x81}
+	switch {
+	case len(payload) < 126:
+		header = append(header, byte(len(payload)))
+	default:
+		header = append(header, 126, 0, 0)
+		binary.BigEndian.PutUint16(header[2:], uint16(len(payload)))
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, err = p.conn.Write(append(header, payload...))
+	return err
+}
+
+func (p *fakeWSPeer) readJSON(v any) error {
+	var head [2]byte
+	if _, err := io.ReadFull(p.reader, head[:]); err != nil {
+		return err
+	}
+	length := int(head[1] & 0x7f)
+	if length == 126 {
+		var ext [2]byte
+		if _, err := io.ReadFull(p.reader, ext[:]); err != nil {
+			return err
+		}
+		length = int(binary.BigEndian.Uint16(ext[:]))
+	}
+	var mask [4]byte
+	if _, err := io.ReadFull(p.reader, mask[:]); err != nil {
+		return err
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(p.reader, payload); err != nil {
+		return err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return json.Unmarshal(payload, v)
+}
+
+// fakeSolanaWS accepts every subscription on every connection, numbering
+// them from 1 per connection, and lets the test notify or drop the latest one.
+type fakeSolanaWS struct {
+	mu            sync.Mutex
+	peer          *fakeWSPeer
+	subscriptions map[string]int64 // address or "logs" -> subscription id
+	drop          chan struct{}
+}
+
+func serveFakeSolanaWS(t *testing.T) (*fakeSolanaWS, *httptest.Server) {
+	t.Helper()
+	fake := &fakeSolanaWS{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accept := websocketAccept(r.Header.Get("Sec-WebSocket-Key"))
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: " + accept + "\r\n\r\n")
+		_ = rw.Flush()
+
+		peer := &fakeWSPeer{conn: conn, reader: rw.Reader}
+		drop := make(chan struct{})
+		fake.mu.Lock()
+		fake.peer, fake.subscriptions, fake.drop = peer, map[string]int64{}, drop
+		fake.mu.Unlock()
+
+		go func() {
+			<-drop
+			conn.Close()
+		}()
+		for {
+			var req struct {
+				ID     int               `json:"id"`
+				Method string            `json:"method"`
+				Params []json.RawMessage `json:"params"`
+			}
+			if err := peer.readJSON(&req); err != nil {
+				return
+			}
+			key := wsProgramLogsKey
+			if req.Method == "accountSubscribe" {
+				_ = json.Unmarshal(req.Params[0], &key)
+			}
+			fake.mu.Lock()
+			id := int64(len(fake.subscriptions) + 1)
+			fake.subscriptions[key] = id
+			fake.mu.Unlock()
+			_ = peer.send(map[string]any{"jsonrpc": "2.0", "id": req.ID, "result": id})
+		}
+	}))
+	t.Cleanup(server.Close)
+	return fake, server
+}
+
+func (f *fakeSolanaWS) notify(t *testing.T, method string, key string, slot int64, value any) {
+	t.Helper()
+	f.mu.Lock()
+	peer, id := f.peer, f.subscriptions[key]
+	f.mu.Unlock()
+	err := peer.send(map[string]any{
+		"jsonrpc": "2.0",
+		"method":  method,
+		"params": map[string]any{
+			"subscription": id,
+			"result":       map[string]any{"context": map[string]any{"slot": slot}, "value": value},
+		},
+	})
+	if err != nil {
+		t.Fatalf("notify: %v", err)
+	}
+}
+
+func (f *fakeSolanaWS) dropConnection() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	close(f.drop)
+}
+
+// fakeSolanaRPC answers getSlot with a slot 10 higher on every call starting
+// at 1000, getSignaturesForAddress with one signature per address until it is
+// passed as until, and batches with empty transactions and a fixed block time.
+type fakeSolanaRPC struct {
+	mu           sync.Mutex
+	slot         int64
+	scans        map[string]int
+	transactions map[string]int
+}
+
+func (f *fakeSolanaRPC) client() *http.Client {
+	f.slot, f.scans, f.transactions = 990, map[string]int{}, map[string]int{}
+	return &http.Client{Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		raw, err := io.ReadAll(r.Body)
+		if err != nil {
+			return nil, err
+		}
+		type request struct {
+			ID     int               `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		body := ""
+		if strings.HasPrefix(strings.TrimSpace(string(raw)), "[") {
+			var reqs []request
+			if err := json.Unmarshal(raw, &reqs); err != nil {
+				return nil, err
+			}
+			items := make([]string, 0, len(reqs))
+			for _, req := range reqs {
+				if req.Method == "getBlockTime" {
+					items = append(items, fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"result":1770000000}`, req.ID))
+					continue
+				}
+				var signature string
+				_ = json.Unmarshal(req.Params[0], &signature)
+				f.transactions[signature]++
+				items = append(items, fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"result":{"meta":{}}}`, req.ID))
+			}
+			body = "[" + strings.Join(items, ",") + "]"
+		} else {
+			var req request
+			if err := json.Unmarshal(raw, &req); err != nil {
+				return nil, err
+			}
+			switch req.Method {
+			case "getSlot":
+				f.slot += 10
+				body = fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"result":%d}`, f.slot)
+			case "getSignaturesForAddress":
+				var address string
+				_ = json.Unmarshal(req.Params[0], &address)
+				var options struct {
+					Until string `json:"until"`
+				}
+				_ = json.Unmarshal(req.Params[1], &options)
+				f.scans[address]++
+				result := "[]"
+				if signature := "sig_" + address; options.Until != signature {
+					result = fmt.Sprintf(`[{"signature":%q,"slot":%d,"blockTime":1770000000}]`, signature, f.slot)
+				}
+				body = `{"jsonrpc":"2.0","id":1,"result":` + result + `}`
+			}
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(body)),
+			Header:     make(http.Header),
+		}, nil
+	})}
+}
+
+func (f *fakeSolanaRPC) counts() (map[string]int, map[string]int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	scans, transactions := map[string]int{}, map[string]int{}
+	for k, v := range f.scans {
+		scans[k] = v
+	}
+	for k, v := range f.transactions {
+		transactions[k] = v
+	}
+	return scans, transactions
+}
+
+type routeStoreStub struct {
+	routes []ActiveRoute
+}
+
+func (r routeStoreStub) ListActiveRoutes(_ context.Context, _ string) ([]ActiveRoute, error) {
+	return r.routes, nil
+}
+
+func (a *wsActivity) waitFor(t *testing.T, what string, ready func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		a.mu.Lock()
+		ok := ready()
+		a.mu.Unlock()
+		if ok {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestSolanaWebsocketSource_SkipsUnchangedAddressesAndBackfillsAfterReconnect(t *testing.T) {
+	tokens, err := NewTokenRegistry([]TokenInfo{{Symbol: "USDC", Mint: testUSDCMint, Decimals: 6}})
+	if err != nil {
+		t.Fatalf("unexpected registry error: %v", err)
+	}
+	rpc := &fakeSolanaRPC{}
+	fake, server := serveFakeSolanaWS(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	source := NewSolanaWebsocketSource("ws"+strings.TrimPrefix(server.URL, "http"), SolanaRpcSource{
+		RPCURL:       "https://rpc.internal",
+		HTTPClient:   rpc.client(),
+		RouteStore:   routeStoreStub{routes: []ActiveRoute{{Token: "USDC", DepositAddress: "route"}}},
+		Tokens:       tokens,
+		TreasuryATAs: map[string]string{"USDC": "treasury"},
+		ProgramID:    "program",
+		Chain:        "solana",
+	}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	source.ReconnectDelay = 10 * time.Millisecond
+	source.Start(ctx)
+	activity := source.activity
+
+	poll := func(cursor string) string {
+		t.Helper()
+		_, next, err := source.Poll(ctx, cursor)
+		if err != nil {
+			t.Fatalf("unexpected poll error: %v", err)
+		}
+		return next
+	}
+
+	// Nothing is subscribed yet, so both addresses are scanned over HTTP and
+	// queued for subscriptions.
+	cursor := poll("990")
+	activity.waitFor(t, "account subscriptions", func() bool {
+		return activity.requested["route"] && activity.requested["treasury"] && len(activity.pendingWanted) == 0 && len(activity.requests) == 0
+	})
+	// Coverage starts at this poll's slot, so it still scans.
+	cursor = poll(cursor)
+	cursor = poll(cursor)
+	if scans, _ := rpc.counts(); scans["route"] != 2 || scans["treasury"] != 2 {
+		t.Fatalf("expected unchanged covered addresses to be skipped, got %v", scans)
+	}
+	if decoded := parseSolanaCursor(cursor); decoded.Signatures["route"] != "sig_route" || decoded.Signatures["treasury"] != "sig_treasury" {
+		t.Fatalf("expected skipped addresses to keep their signatures, got %s", cursor)
+	}
+
+	// A finalized change of the route and a program log are picked up.
+	slot := parseSolanaCursor(cursor).Slot + 5
+	fake.notify(t, "accountNotification", "route", slot, map[string]any{"lamports": 1})
+	fake.notify(t, "logsNotification", wsProgramLogsKey, slot, map[string]any{"signature": "sig_logs", "err": nil})
+	activity.waitFor(t, "notifications", func() bool { return len(activity.programSigs) == 1 && activity.changed["route"] == slot })
+	select {
+	case <-source.Wake():
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected a wake after the notifications")
+	}
+	cursor = poll(cursor)
+	if scans, transactions := rpc.counts(); scans["route"] != 3 || scans["treasury"] != 2 || transactions["sig_logs"] != 1 {
+		t.Fatalf("expected the changed route rescanned and the logged signature fetched, got %v %v", scans, transactions)
+	}
+
+	// After a dropped connection everything is scanned over HTTP again.
+	fake.dropConnection()
+	activity.waitFor(t, "reset", func() bool { return len(activity.coveredAfter) == 0 })
+	poll(cursor)
+	if scans, _ := rpc.counts(); scans["route"] != 4 || scans["treasury"] != 3 {
+		t.Fatalf("expected a full http backfill after reconnect, got %v", scans)
+	}
+}
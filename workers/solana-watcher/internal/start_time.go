@@ -0,0 +1,118 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxSkippedSlotProbe bounds how many consecutive slots CursorAt walks past
+// when a probed slot was skipped by its leader.
+const maxSkippedSlotProbe = 64
+
+// ParseStartTime parses a backfill start time such as "2026-09-01T00:00Z".
+// Seconds and the time of day may be omitted; a bare date is midnight UTC.
+func ParseStartTime(raw string) (time.Time, error) {
+	trimmed := strings.TrimSpace(raw)
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04Z07:00", "2006-01-02"} {
+		if parsed, err := time.Parse(layout, trimmed); err == nil {
+			return parsed.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid start time %q (expected e.g. 2026-09-01T00:00Z)", raw)
+}
+
+// CursorAt returns a cursor from which the next poll picks up the first slot
+// produced at or after at. Slot times only grow, so the slot is found by
+// binary search with getBlockTime between the first block the provider still
+// serves and the latest finalized slot. Skipped slots have no time and are
+// stepped over.
+func (s SolanaRpcSource) CursorAt(ctx context.Context, at time.Time) (string, error) {
+	ctx = s.RPCPool.Pin(ctx)
+	latestSlot, err := s.getSlot(ctx)
+	if err != nil {
+		return "", fmt.Errorf("get slot: %w", err)
+	}
+	firstSlot, err := s.getFirstAvailableBlock(ctx)
+	if err != nil {
+		return "", fmt.Errorf("get first available block: %w", err)
+	}
+
+	target := at.Unix()
+	latestTime, produced, err := s.blockTimeFrom(ctx, latestSlot, -1, firstSlot)
+	if err != nil {
+		return "", err
+	}
+	if produced < firstSlot {
+		return "", fmt.Errorf("no produced slot between %d and %d", firstSlot, latestSlot)
+	}
+	if latestTime < target {
+		return "", fmt.Errorf("start time %s is after the latest slot %d (%s)", at.Format(time.RFC3339), latestSlot, time.Unix(latestTime, 0).UTC().Format(time.RFC3339))
+	}
+
+	// Find the first slot in [low, high] whose time is not before at.
+	low, high := firstSlot, latestSlot
+	for low < high {
+		mid := low + (high-low)/2
+		midTime, produced, err := s.blockTimeFrom(ctx, mid, 1, high)
+		if err != nil {
+			return "", err
+		}
+		// Slots from mid up to produced were skipped, so the first slot at or
+		// after at is either at most mid or after produced.
+		if produced <= high && midTime < target {
+			low = produced + 1
+		} else {
+			high = mid
+		}
+	}
+
+	// The cursor is the last slot already scanned.
+	if low == 0 {
+		return "0", nil
+	}
+	return strconv.FormatInt(low-1, 10), nil
+}
+
+// blockTimeFrom returns the time of the first produced slot from slot on,
+// walking in step (1 or -1) past skipped slots, together with that slot. When
+// the walk passes bound, the first slot beyond it is returned with no time.
+func (s SolanaRpcSource) blockTimeFrom(ctx context.Context, slot int64, step int64, bound int64) (int64, int64, error) {
+	start := slot
+	for i := 0; i < maxSkippedSlotProbe; i++ {
+		if (step > 0 && slot > bound) || (step < 0 && slot < bound) {
+			return 0, slot, nil
+		}
+		blockTime, err := s.getBlockTime(ctx, slot)
+		if err == nil {
+			return blockTime, slot, nil
+		}
+		if !isSkippedSlotError(err) {
+			return 0, 0, fmt.Errorf("get block time for slot %d: %w", slot, err)
+		}
+		slot += step
+	}
+	return 0, 0, fmt.Errorf("no produced slot within %d slots of %d", maxSkippedSlotProbe, start)
+}
+
+func (s SolanaRpcSource) getFirstAvailableBlock(ctx context.Context) (int64, error) {
+	var out int64
+	if err := s.rpcCall(ctx, "getFirstAvailableBlock", []interface{}{}, &out); err != nil {
+		return 0, err
+	}
+	return out, nil
+}
+
+// isSkippedSlotError reports whether getBlockTime failed because no block was
+// produced in the slot.
+func isSkippedSlotError(err error) bool {
+	if err == nil {
+		return false
+	}
+	message := strings.ToLower(err.Error())
+	return strings.Contains(message, "rpc error -32007") ||
+		strings.Contains(message, "skipped") ||
+		strings.Contains(message, "block time unavailable")
+}
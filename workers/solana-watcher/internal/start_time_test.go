@@ -0,0 +1,79 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// slotTimeServer answers getSlot, getFirstAvailableBlock and getBlockTime for
+// slots 1000-2000, with slot n produced at 1_770_000_000 + n/2 seconds and
+// the slots in skipped missing.
+func slotTimeServer(skipped map[int64]bool) *http.Client {
+	return &http.Client{Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		var req struct {
+			ID     int               `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return nil, err
+		}
+		body := ""
+		switch req.Method {
+		case "getSlot":
+			body = `{"jsonrpc":"2.0","id":1,"result":2000}`
+		case "getFirstAvailableBlock":
+			body = `{"jsonrpc":"2.0","id":1,"result":1000}`
+		case "getBlockTime":
+			var slot int64
+			_ = json.Unmarshal(req.Params[0], &slot)
+			if skipped[slot] {
+				body = fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"error":{"code":-32007,"message":"Slot %d was skipped, or missing due to ledger jump to recent snapshot"}}`, slot)
+			} else {
+				body = fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"result":%d}`, 1_770_000_000+slot/2)
+			}
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(body)),
+			Header:     make(http.Header),
+		}, nil
+	})}
+}
+
+func TestSolanaRpcSource_CursorAtFindsFirstSlotAtTime(t *testing.T) {
+	source := SolanaRpcSource{RPCURL: "https://rpc.internal"}
+
+	source.HTTPClient = slotTimeServer(nil)
+	cursor, err := source.CursorAt(context.Background(), time.Unix(1_770_000_000+700, 0))
+	if err != nil {
+		t.Fatalf("unexpected cursor error: %v", err)
+	}
+	// Slots 1400 and 1401 share the second; the cursor is the slot before.
+	if cursor != "1399" {
+		t.Fatalf("expected cursor 1399, got %s", cursor)
+	}
+
+	skipped := map[int64]bool{}
+	for slot := int64(1390); slot < 1410; slot++ {
+		skipped[slot] = true
+	}
+	source.HTTPClient = slotTimeServer(skipped)
+	cursor, err = source.CursorAt(context.Background(), time.Unix(1_770_000_000+700, 0))
+	if err != nil {
+		t.Fatalf("unexpected cursor error with skipped slots: %v", err)
+	}
+	if cursor != "1389" {
+		t.Fatalf("expected the cursor at the last produced slot before the time, got %s", cursor)
+	}
+
+	if _, err := source.CursorAt(context.Background(), time.Unix(1_770_000_000+5000, 0)); err == nil {
+		t.Fatalf("expected a time after the latest slot to fail")
+	}
+}
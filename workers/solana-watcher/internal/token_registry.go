@@ -0,0 +1,186 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"sort"
+	"strings"
+)
+
+// TokenInfo describes one SPL token a source watches.
+type TokenInfo struct {
+	Symbol   string // e.g. "USDC"; candidates carry it as Token
+	Mint     string // mint pubkey (base58)
+	Decimals int    // 0 means "read from chain" in VerifyTokenDecimals
+	Name     string // display name, e.g. "USD Coin"
+}
+
+// TokenRegistry holds the tokens of a source, keyed by symbol and mint.
+// Every conversion from base units goes through it, so mints with other than
+// 6 decimals are credited correctly.
+type TokenRegistry struct {
+	bySymbol map[string]*TokenInfo
+	byMint   map[string]*TokenInfo
+}
+
+// NewTokenRegistry builds a registry. Tokens without a mint are skipped so
+// optional tokens can stay unconfigured. Mints are base58 and kept as given.
+func NewTokenRegistry(tokens []TokenInfo) (*TokenRegistry, error) {
+	registry := &TokenRegistry{
+		bySymbol: make(map[string]*TokenInfo),
+		byMint:   make(map[string]*TokenInfo),
+	}
+	for _, token := range tokens {
+		token.Symbol = strings.ToUpper(strings.TrimSpace(token.Symbol))
+		token.Mint = strings.TrimSpace(token.Mint)
+		if token.Mint == "" {
+			continue
+		}
+		if token.Symbol == "" {
+			return nil, fmt.Errorf("token %s has no symbol", token.Mint)
+		}
+		if token.Decimals < 0 || token.Decimals > 18 {
+			return nil, fmt.Errorf("token %s has invalid decimals %d", token.Symbol, token.Decimals)
+		}
+		if _, exists := registry.bySymbol[token.Symbol]; exists {
+			return nil, fmt.Errorf("duplicate token symbol %s", token.Symbol)
+		}
+		if _, exists := registry.byMint[token.Mint]; exists {
+			return nil, fmt.Errorf("duplicate token mint %s", token.Mint)
+		}
+
+		info := token
+		registry.bySymbol[info.Symbol] = &info
+		registry.byMint[info.Mint] = &info
+	}
+	return registry, nil
+}
+
+// Token returns the token with the given symbol.
+func (r *TokenRegistry) Token(symbol string) (TokenInfo, bool) {
+	if r == nil {
+		return TokenInfo{}, false
+	}
+	info, ok := r.bySymbol[strings.ToUpper(symbol)]
+	if !ok {
+		return TokenInfo{}, false
+	}
+	return *info, true
+}
+
+// TokenByMint returns the token with the given mint.
+func (r *TokenRegistry) TokenByMint(mint string) (TokenInfo, bool) {
+	if r == nil {
+		return TokenInfo{}, false
+	}
+	info, ok := r.byMint[strings.TrimSpace(mint)]
+	if !ok {
+		return TokenInfo{}, false
+	}
+	return *info, true
+}
+
+// Tokens returns all tokens ordered by symbol.
+func (r *TokenRegistry) Tokens() []TokenInfo {
+	if r == nil {
+		return nil
+	}
+	tokens := make([]TokenInfo, 0, len(r.bySymbol))
+	for _, info := range r.bySymbol {
+		tokens = append(tokens, *info)
+	}
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].Symbol < tokens[j].Symbol })
+	return tokens
+}
+
+// AmountUSD converts base units to whole tokens. The watched tokens are
+// USD stablecoins, so one token counts as one USD.
+func (t TokenInfo) AmountUSD(baseUnits *big.Int) float64 {
+	divisor := new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(t.Decimals)), nil))
+	usd, _ := new(big.Float).Quo(new(big.Float).SetInt(baseUnits), divisor).Float64()
+	return usd
+}
+
+// formatBaseUnits renders an integer base-unit amount as an exact decimal
+// string, e.g. 2500000 with 6 decimals is "2.5".
+func formatBaseUnits(baseUnits *big.Int, decimals int) string {
+	digits := new(big.Int).Abs(baseUnits).String()
+	sign := ""
+	if baseUnits.Sign() < 0 {
+		sign = "-"
+	}
+	if decimals <= 0 {
+		return sign + digits
+	}
+	if len(digits) <= decimals {
+		digits = strings.Repeat("0", decimals-len(digits)+1) + digits
+	}
+	whole, fraction := digits[:len(digits)-decimals], strings.TrimRight(digits[len(digits)-decimals:], "0")
+	if fraction == "" {
+		return sign + whole
+	}
+	return sign + whole + "." + fraction
+}
+
+// setAmount records an exact base-unit amount on the candidate, together with
+// the token decimals and the exact decimal string. AmountUSD is derived from
+// it for consumers that still read the float.
+func (c *FundingCandidate) setAmount(token TokenInfo, baseUnits *big.Int) {
+	c.AmountBaseUnits = baseUnits.String()
+	c.TokenDecimals = token.Decimals
+	c.Amount = formatBaseUnits(baseUnits, token.Decimals)
+	c.AmountUSD = token.AmountUSD(baseUnits)
+}
+
+type mintAccountInfo struct {
+	Value *struct {
+		Owner string `json:"owner"`
+		Data  struct {
+			Parsed struct {
+				Type string `json:"type"`
+				Info struct {
+					Decimals *int `json:"decimals"`
+				} `json:"info"`
+			} `json:"parsed"`
+		} `json:"data"`
+	} `json:"value"`
+}
+
+// VerifyTokenDecimals reads every mint account. Tokens configured without
+// decimals take the on-chain value; a configured value that disagrees with the
+// mint is an error, so a wrong divisor never reaches a payout.
+func (s SolanaRpcSource) VerifyTokenDecimals(ctx context.Context) error {
+	for _, token := range s.Tokens.Tokens() {
+		var account mintAccountInfo
+		params := []interface{}{token.Mint, map[string]string{"encoding": "jsonParsed"}}
+		if err := s.rpcCall(ctx, "getAccountInfo", params, &account); err != nil {
+			return fmt.Errorf("getAccountInfo for %s mint: %w", token.Symbol, err)
+		}
+		if account.Value == nil {
+			return fmt.Errorf("token %s mint %s not found", token.Symbol, token.Mint)
+		}
+		parsed := account.Value.Data.Parsed
+		if parsed.Type != "mint" || parsed.Info.Decimals == nil {
+			return fmt.Errorf("token %s account %s is not a mint", token.Symbol, token.Mint)
+		}
+		onChain := *parsed.Info.Decimals
+
+		info := s.Tokens.bySymbol[token.Symbol]
+		switch {
+		case info.Decimals == 0:
+			info.Decimals = onChain
+		case info.Decimals != onChain:
+			return fmt.Errorf("token %s configured with %d decimals but mint reports %d", token.Symbol, info.Decimals, onChain)
+		}
+
+		slog.Info("solana-rpc: token verified",
+			"token", token.Symbol,
+			"name", info.Name,
+			"mint", token.Mint,
+			"decimals", info.Decimals,
+		)
+	}
+	return nil
+}
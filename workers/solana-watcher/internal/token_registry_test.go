@@ -0,0 +1,102 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+const (
+	testUSDCMint  = "EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v"
+	testPYUSDMint = "2b1kV6DkPAnxd5ixfnxCpjxmKwqjjaYmCZfHsFu24GXo"
+)
+
+func mintAccountServer(t *testing.T, decimals map[string]int) *http.Client {
+	t.Helper()
+	return &http.Client{
+		Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			var req struct {
+				ID     int               `json:"id"`
+				Method string            `json:"method"`
+				Params []json.RawMessage `json:"params"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				return nil, err
+			}
+			var mint string
+			_ = json.Unmarshal(req.Params[0], &mint)
+			result := `{"value":null}`
+			if value, ok := decimals[mint]; ok {
+				result = fmt.Sprintf(`{"value":{"owner":"TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA","data":{"parsed":{"type":"mint","info":{"decimals":%d}}}}}`, value)
+			}
+			body := fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"result":%s}`, req.ID, result)
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader(body)),
+				Header:     make(http.Header),
+			}, nil
+		}),
+	}
+}
+
+func TestSolanaRpcSource_VerifyTokenDecimalsReadsMint(t *testing.T) {
+	tokens, err := NewTokenRegistry([]TokenInfo{
+		{Symbol: "usdc", Mint: testUSDCMint, Decimals: 6},
+		{Symbol: "PYUSD", Mint: testPYUSDMint},
+		{Symbol: "USDT"}, // unconfigured, skipped
+	})
+	if err != nil {
+		t.Fatalf("unexpected registry error: %v", err)
+	}
+	source := SolanaRpcSource{
+		RPCURL:     "https://rpc.internal",
+		HTTPClient: mintAccountServer(t, map[string]int{testUSDCMint: 6, testPYUSDMint: 9}),
+		Tokens:     tokens,
+	}
+
+	if err := source.VerifyTokenDecimals(context.Background()); err != nil {
+		t.Fatalf("unexpected verify error: %v", err)
+	}
+	pyusd, ok := tokens.TokenByMint(testPYUSDMint)
+	if !ok || pyusd.Decimals != 9 || pyusd.Symbol != "PYUSD" {
+		t.Fatalf("expected PYUSD with decimals from the mint, got %+v", pyusd)
+	}
+	if len(tokens.Tokens()) != 2 {
+		t.Fatalf("expected tokens without a mint to be skipped, got %+v", tokens.Tokens())
+	}
+
+	tx := transactionResult{}
+	tx.Transaction.Message.AccountKeys = []accountKey{{Pubkey: "payer"}, {Pubkey: "deposit"}}
+	tx.Meta.PreTokenBalances = []tokenBalance{{AccountIndex: 1, Mint: testPYUSDMint, UITokenAmount: tokenAmount{Amount: "1000000000"}}}
+	tx.Meta.PostTokenBalances = []tokenBalance{{AccountIndex: 1, Mint: testPYUSDMint, UITokenAmount: tokenAmount{Amount: "3500000000"}}}
+	credit, ok := extractTokenCredit(tx, "deposit", pyusd)
+	if !ok || credit.String() != "2500000000" {
+		t.Fatalf("expected 2500000000 base units credited, got %v %v", credit, ok)
+	}
+	var candidate FundingCandidate
+	candidate.setAmount(pyusd, credit)
+	if candidate.Amount != "2.5" || candidate.TokenDecimals != 9 || candidate.AmountUSD != 2.5 {
+		t.Fatalf("unexpected candidate amount: %+v", candidate)
+	}
+}
+
+func TestSolanaRpcSource_VerifyTokenDecimalsMismatch(t *testing.T) {
+	tokens, err := NewTokenRegistry([]TokenInfo{{Symbol: "USDC", Mint: testUSDCMint, Decimals: 6}})
+	if err != nil {
+		t.Fatalf("unexpected registry error: %v", err)
+	}
+	source := SolanaRpcSource{
+		RPCURL:     "https://rpc.internal",
+		HTTPClient: mintAccountServer(t, map[string]int{testUSDCMint: 9}),
+		Tokens:     tokens,
+	}
+
+	err = source.VerifyTokenDecimals(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "configured with 6 decimals but mint reports 9") {
+		t.Fatalf("expected decimals mismatch error, got %v", err)
+	}
+}
@@ -15,10 +15,15 @@ type FundingCandidate struct {
 	TransferID     string
 	ReferenceHash  string
 	DepositAddress string
-	AmountUSD      float64
-	ConfirmedAt    time.Time
-	Metadata       map[string]any
-	Finalized      bool
+	// AmountBaseUnits is the exact integer amount in the token's base units
+	// and Amount the same value scaled by TokenDecimals, as a decimal string.
+	AmountBaseUnits string
+	TokenDecimals   int
+	Amount          string
+	AmountUSD       float64 // Deprecated: rounded; kept for one release for core-api compatibility
+	ConfirmedAt     time.Time
+	Metadata        map[string]any
+	Finalized       bool
 }
 
 type RouteMatch struct {
@@ -43,9 +48,14 @@ type FundingConfirmedEvent struct {
 	LogIndex       int
 	TransferID     string
 	DepositAddress string
-	AmountUSD      float64
-	ConfirmedAt    time.Time
-	Metadata       map[string]any
+	// AmountBaseUnits is the exact integer amount in the token's base units
+	// and Amount the same value scaled by TokenDecimals, as a decimal string.
+	AmountBaseUnits string
+	TokenDecimals   int
+	Amount          string
+	AmountUSD       float64 // Deprecated: rounded; kept for one release for core-api compatibility
+	ConfirmedAt     time.Time
+	Metadata        map[string]any
 }
 
 type ProcessResult string
@@ -54,6 +64,7 @@ const (
 	ProcessIgnored       ProcessResult = "ignored"
 	ProcessConfirmed     ProcessResult = "confirmed"
 	ProcessRouteNotFound ProcessResult = "route_not_found"
+	ProcessInternal      ProcessResult = "internal"
 )
 
 var ErrInvalidChain = errors.New("invalid chain for watcher")
@@ -62,6 +73,11 @@ type Watcher struct {
 	Chain     string
 	Resolver  RouteResolver
 	Publisher EventPublisher
+
+	// InternalAddresses holds our own sweeper, treasury and hot-wallet
+	// wallets and token accounts (base58). Transfers paid from them are
+	// internal movements, not customer funding.
+	InternalAddresses map[string]bool
 }
 
 func (w Watcher) ProcessCandidate(ctx context.Context, c FundingCandidate) (ProcessResult, error) {
@@ -80,6 +96,9 @@ func (w Watcher) ProcessCandidateWithMatch(ctx context.Context, c FundingCandida
 	if c.ConfirmedAt.IsZero() {
 		return ProcessIgnored, "", "", nil
 	}
+	if w.isInternalMovement(c) {
+		return ProcessInternal, "", c.DepositAddress, nil
+	}
 
 	match := RouteMatch{TransferID: c.TransferID, DepositAddress: c.DepositAddress}
 	found := match.TransferID != ""
@@ -110,16 +129,19 @@ func (w Watcher) ProcessCandidateWithMatch(ctx context.Context, c FundingCandida
 	}
 
 	event := FundingConfirmedEvent{
-		EventID:        eventID,
-		Chain:          c.Chain,
-		Token:          c.Token,
-		TxHash:         c.TxHash,
-		LogIndex:       c.LogIndex,
-		TransferID:     match.TransferID,
-		DepositAddress: depositAddress,
-		AmountUSD:      c.AmountUSD,
-		ConfirmedAt:    c.ConfirmedAt,
-		Metadata:       c.Metadata,
+		EventID:         eventID,
+		Chain:           c.Chain,
+		Token:           c.Token,
+		TxHash:          c.TxHash,
+		LogIndex:        c.LogIndex,
+		TransferID:      match.TransferID,
+		DepositAddress:  depositAddress,
+		AmountBaseUnits: c.AmountBaseUnits,
+		TokenDecimals:   c.TokenDecimals,
+		Amount:          c.Amount,
+		AmountUSD:       c.AmountUSD,
+		ConfirmedAt:     c.ConfirmedAt,
+		Metadata:        c.Metadata,
 	}
 
 	if err := w.Publisher.PublishFundingConfirmed(ctx, event); err != nil {
@@ -128,3 +150,14 @@ func (w Watcher) ProcessCandidateWithMatch(ctx context.Context, c FundingCandida
 
 	return ProcessConfirmed, match.TransferID, depositAddress, nil
 }
+
+// isInternalMovement reports whether the candidate was paid from one of our
+// own wallets or token accounts.
+func (w Watcher) isInternalMovement(c FundingCandidate) bool {
+	for _, key := range []string{"payerAddress", "payerTokenAccount"} {
+		if address, _ := c.Metadata[key].(string); address != "" && w.InternalAddresses[address] {
+			return true
+		}
+	}
+	return false
+}
@@ -130,3 +130,45 @@ func TestWatcher_ResolvesSolanaPaymentByReferenceHash(t *testing.T) {
 		t.Fatalf("expected resolved deposit address")
 	}
 }
+
+func TestWatcher_InternalMovementIsNotPublished(t *testing.T) {
+	usdc := TokenInfo{Symbol: "USDC", Mint: testUSDCMint, Decimals: 6}
+	tx := transactionResult{}
+	tx.Transaction.Message.AccountKeys = []accountKey{{Pubkey: "sweeperAta"}, {Pubkey: "deposit"}}
+	tx.Meta.PreTokenBalances = []tokenBalance{
+		{AccountIndex: 0, Owner: "sweeperWallet", Mint: testUSDCMint, UITokenAmount: tokenAmount{Amount: "9000000"}},
+		{AccountIndex: 1, Owner: "depositOwner", Mint: testUSDCMint, UITokenAmount: tokenAmount{Amount: "0"}},
+	}
+	tx.Meta.PostTokenBalances = []tokenBalance{
+		{AccountIndex: 1, Owner: "depositOwner", Mint: testUSDCMint, UITokenAmount: tokenAmount{Amount: "9000000"}},
+	}
+	owner, account, ok := extractTokenDebit(tx, usdc)
+	if !ok || owner != "sweeperWallet" || account != "sweeperAta" {
+		t.Fatalf("expected the closed sweeper account as payer, got %q %q %v", owner, account, ok)
+	}
+
+	pub := &publisherStub{}
+	w := Watcher{
+		Chain:             "solana",
+		Resolver:          resolverStub{found: true, match: RouteMatch{TransferID: "tr_1"}},
+		Publisher:         pub,
+		InternalAddresses: map[string]bool{"sweeperWallet": true},
+	}
+	candidate := FundingCandidate{
+		Chain: "solana", Token: "USDC", TxHash: "sig_sweep", DepositAddress: "deposit",
+		Finalized: true, ConfirmedAt: time.Date(2026, 2, 13, 12, 0, 0, 0, time.UTC),
+		Metadata: map[string]any{"payerAddress": owner, "payerTokenAccount": account},
+	}
+	result, err := w.ProcessCandidate(context.Background(), candidate)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if result != ProcessInternal || len(pub.calledWith) != 0 {
+		t.Fatalf("expected internal movement without publish, got %s and %d events", result, len(pub.calledWith))
+	}
+
+	w.InternalAddresses = map[string]bool{"SWEEPERWALLET": true}
+	if result, _ := w.ProcessCandidate(context.Background(), candidate); result != ProcessConfirmed {
+		t.Fatalf("expected base58 addresses to match case-sensitively, got %s", result)
+	}
+}